@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+func exportCommand(args []string) int {
+	fs := newFlagSet("export", "<history file>")
+	parser := fs.String("parser", "", "parser of the history, default is the parser of the workload in the history header")
+	output := fs.String("o", "", "write the edn to the file, default is stdout")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var w io.Writer = os.Stdout
+	if len(*output) != 0 {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := history.ExportEDN(fs.Arg(0), *parser, w); err != nil {
+		log.Printf("export history %s failed %v", fs.Arg(0), err)
+		return 1
+	}
+	return 0
+}
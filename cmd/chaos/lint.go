@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+func lintCommand(args []string) int {
+	fs := newFlagSet("lint", "<history file>...")
+	dbName := fs.String("db", "", "database of the client test case, default is the one in the history header")
+	clientCase := fs.String("case", "", "client test case, its parser is used, default is the one in the history header")
+	parserName := fs.String("parser", "", "parser of the history, it overrides the parser of the case")
+	asJSON := fs.Bool("json", false, "print the issues in json")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	code := 0
+	all := make(map[string][]history.Issue)
+	for _, historyFile := range fs.Args() {
+		var p history.RecordParser
+		if len(*parserName) != 0 {
+			if p = history.GetParser(*parserName); p == nil {
+				log.Printf("parser %s is not registered", *parserName)
+				return 2
+			}
+		} else {
+			var err error
+			if _, p, err = historyWorkload(historyFile, *dbName, *clientCase); err != nil {
+				log.Printf("read history %s failed %v, use -parser to choose the parser", historyFile, err)
+				return 2
+			}
+		}
+
+		issues, err := history.Lint(historyFile, p)
+		if err != nil {
+			log.Printf("lint history %s failed %v", historyFile, err)
+			return 2
+		}
+		if len(issues) != 0 {
+			code = 1
+		}
+		if *asJSON {
+			all[historyFile] = append([]history.Issue{}, issues...)
+			continue
+		}
+		for _, i := range issues {
+			fmt.Printf("%s:%d: %s: %s\n", historyFile, i.Line, i.Kind, i.Message)
+		}
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(all, "", "  ")
+		if err != nil {
+			log.Print(err)
+			return 2
+		}
+		os.Stdout.Write(append(data, '\n'))
+	}
+	return code
+}
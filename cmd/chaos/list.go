@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+	"github.com/pingcap/chaos/pkg/nemesis"
+)
+
+func listCommand(args []string) int {
+	fs := newFlagSet("list", "")
+	dbName := fs.String("db", "", "only list the workloads of the database")
+	fs.Parse(args)
+
+	fmt.Printf("databases: %s\n", strings.Join(core.DBNames(), ", "))
+	fmt.Println("workloads:")
+	for _, w := range core.Workloads(*dbName) {
+		fmt.Printf("  %s/%s: model=%s parser=%s checkers=%s\n",
+			w.DB, w.Name, w.Model, w.Parser, strings.Join(w.Checkers, ","))
+	}
+	fmt.Printf("nemeses: %s\n", strings.Join(core.NemesisNames(), ", "))
+	fmt.Printf("nemesis generators: %s\n", strings.Join(nemesis.GeneratorNames(), ", "))
+	fmt.Printf("models: %s\n", strings.Join(core.ModelNames(), ", "))
+	fmt.Printf("parsers: %s\n", strings.Join(history.ParserNames(), ", "))
+	fmt.Printf("edn codecs: %s\n", strings.Join(history.EDNCodecNames(), ", "))
+	fmt.Printf("checkers: %s\n", strings.Join(core.CheckerNames(), ", "))
+	fmt.Printf("online checkers: %s\n", strings.Join(core.OnlineCheckerNames(), ", "))
+	return 0
+}
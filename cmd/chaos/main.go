@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	// register databases, workloads, models, parsers and checkers
+	_ "github.com/pingcap/chaos/db/rawkv"
+	_ "github.com/pingcap/chaos/db/tidb"
+	_ "github.com/pingcap/chaos/db/txnkv"
+)
+
+// command is a subcommand of chaos.
+type command struct {
+	name  string
+	usage string
+	// run runs the command with the arguments after the command name and
+	// returns the exit code.
+	run func(args []string) int
+}
+
+var commands = []command{
+	{"run", "drive a chaos test", runCommand},
+	{"verify", "verify histories again", verifyCommand},
+	{"list", "list the registered databases, workloads, nemeses, models, parsers and checkers", listCommand},
+	{"report", "summarise the results of a test", reportCommand},
+	{"export", "export a history to Jepsen edn", exportCommand},
+	{"timeline", "render a history as an HTML timeline", timelineCommand},
+	{"stats", "show the throughput, latency and outcomes of histories", statsCommand},
+	{"lint", "check histories are well-formed", lintCommand},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags] [args]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of a command.\n", os.Args[0])
+}
+
+// newFlagSet creates the flag set of the command.
+func newFlagSet(name string, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s [flags] %s\n", os.Args[0], name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	for _, c := range commands {
+		if c.name == name {
+			os.Exit(c.run(os.Args[2:]))
+		}
+	}
+
+	switch name {
+	case "-h", "-help", "--help", "help":
+		usage()
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %s\n\n", name)
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/history"
+	"github.com/pingcap/chaos/pkg/stats"
+	"github.com/pingcap/chaos/pkg/verify"
+)
+
+func reportCommand(args []string) int {
+	fs := newFlagSet("report", "[run directory or summary file]...")
+	plot := fs.Bool("plot", false, "write the latency and throughput plots in svg next to every history")
+	fs.Parse(args)
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"./var/latest"}
+	}
+
+	var results []verify.Result
+	for _, p := range paths {
+		files, err := summaryFiles(p)
+		if err != nil {
+			log.Print(err)
+			return 2
+		}
+		for _, name := range files {
+			s, err := verify.ReadSummary(name)
+			if err != nil {
+				log.Printf("read summary %s failed %v", name, err)
+				return 2
+			}
+			for _, r := range s.Results {
+				// The run directory may be moved after the run.
+				if !fileExists(r.HistoryFile) {
+					if h := filepath.Join(filepath.Dir(name), filepath.Base(r.HistoryFile)); fileExists(h) {
+						r.HistoryFile = h
+					}
+				}
+				results = append(results, r)
+			}
+		}
+	}
+
+	if len(results) == 0 {
+		log.Printf("no summary found in %v", paths)
+		return 2
+	}
+
+	summary := verify.Summarize(results)
+	printResults(summary.Results)
+	printNemeses(summary.Results)
+	if *plot {
+		writePlots(summary.Results)
+	}
+	fmt.Printf("\noutcome: %s\n", summary.Outcome)
+
+	if summary.Outcome != verify.Valid {
+		return 1
+	}
+	return 0
+}
+
+// summaryFiles returns the summary files in the run directory, or the file itself.
+func summaryFiles(p string) ([]string, error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{p}, nil
+	}
+
+	// summary.json is in a run directory, and history.log.summary.json
+	// is next to the histories written without a run directory.
+	if name := filepath.Join(p, "summary.json"); fileExists(name) {
+		return []string{name}, nil
+	}
+	return filepath.Glob(filepath.Join(p, "*.summary.json"))
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+func printResults(results []verify.Result) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "HISTORY\tCHECKER\tMODEL\tOUTCOME\tDURATION\tERROR")
+	for _, r := range results {
+		checker := r.Checker
+		if r.Online {
+			checker += " (online)"
+		}
+		outcome := string(r.Outcome)
+		if r.TimedOut {
+			outcome += " (timed out)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.HistoryFile, checker, r.Model, outcome, r.Duration.Round(time.Millisecond), r.Err)
+	}
+	w.Flush()
+
+	truncated := make(map[string]bool)
+	for _, r := range results {
+		if r.Truncated && !truncated[r.HistoryFile] {
+			truncated[r.HistoryFile] = true
+			fmt.Printf("history %s is truncated, its partial last record is skipped\n", r.HistoryFile)
+		}
+		if len(r.Partitions) != 0 {
+			fmt.Printf("keys of %s which are not linearizable: %s\n", r.HistoryFile, strings.Join(r.Partitions, ", "))
+		}
+		if len(r.Timeline) != 0 {
+			fmt.Printf("timeline of %s: %s\n", r.HistoryFile, r.Timeline)
+		}
+		if len(r.Window) != 0 {
+			fmt.Printf("window of the violation of %s: %s\n", r.HistoryFile, r.Window)
+		}
+		if len(r.Shrunk) != 0 {
+			fmt.Printf("shrunk history of %s: %s\n", r.HistoryFile, r.Shrunk)
+		}
+	}
+}
+
+// printNemeses prints how many nemesis activities every history has.
+func printNemeses(results []verify.Result) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "HISTORY\tNEMESIS\tFAILED")
+	seen := make(map[string]bool)
+	for _, r := range results {
+		if seen[r.HistoryFile] {
+			continue
+		}
+		seen[r.HistoryFile] = true
+
+		records, err := history.ReadNemesisHistory(r.HistoryFile)
+		if err != nil {
+			fmt.Fprintf(w, "%s\t-\t-\n", r.HistoryFile)
+			continue
+		}
+		failed := 0
+		for _, record := range records {
+			if len(record.Err) != 0 {
+				failed++
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\n", r.HistoryFile, len(records), failed)
+	}
+	w.Flush()
+}
+
+// writePlots writes the plots of every history.
+func writePlots(results []verify.Result) {
+	fmt.Println()
+	seen := make(map[string]bool)
+	for _, r := range results {
+		if seen[r.HistoryFile] {
+			continue
+		}
+		seen[r.HistoryFile] = true
+
+		w, p, err := historyWorkload(r.HistoryFile, "", "")
+		if err != nil {
+			log.Printf("plot history %s failed %v", r.HistoryFile, err)
+			continue
+		}
+		s, err := stats.Read(r.HistoryFile, p, history.GetEDNCodec(w.Parser), 0)
+		if err != nil {
+			log.Printf("plot history %s failed %v", r.HistoryFile, err)
+			continue
+		}
+		names, err := s.WritePlots()
+		if err != nil {
+			log.Printf("plot history %s failed %v", r.HistoryFile, err)
+			continue
+		}
+		fmt.Printf("plots of %s: %s\n", r.HistoryFile, strings.Join(names, ", "))
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	_ "net/http/pprof"
+	"time"
+
+	"github.com/pingcap/chaos/cmd/util"
+)
+
+func runCommand(args []string) int {
+	fs := newFlagSet("run", "")
+	specFlags := util.RegisterSpecFlags(fs, util.Spec{
+		DB:           "tidb",
+		Executor:     "ssh",
+		Round:        3,
+		RunTime:      util.Duration{Duration: 10 * time.Minute},
+		RequestCount: 500,
+		Concurrency:  1,
+		Workload:     util.Workload{Name: "bank"},
+		OutputDir:    "./var",
+	})
+	pprofAddr := fs.String("pprof", "", "Pprof address, like 0.0.0.0:8080")
+	fs.Parse(args)
+
+	spec, err := specFlags.Spec()
+	if err != nil {
+		log.Print(err)
+		return 2
+	}
+
+	if len(*pprofAddr) != 0 {
+		go func() {
+			http.ListenAndServe(*pprofAddr, nil)
+		}()
+	}
+
+	suit, err := util.NewSuit(spec)
+	if err != nil {
+		log.Print(err)
+		return 2
+	}
+	suit.Run(context.Background(), spec.Nodes)
+	return 0
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/pingcap/chaos/pkg/history"
+	"github.com/pingcap/chaos/pkg/stats"
+)
+
+func statsCommand(args []string) int {
+	fs := newFlagSet("stats", "<history file>...")
+	dbName := fs.String("db", "", "database of the client test case, default is the one in the history header")
+	clientCase := fs.String("case", "", "client test case, its parser is used, default is the one in the history header")
+	window := fs.Duration("window", stats.DefaultWindow, "window of the throughput and latency time series")
+	asJSON := fs.Bool("json", false, "print the stats in json")
+	plot := fs.Bool("plot", false, "write the latency and throughput plots in svg next to every history")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	for _, historyFile := range fs.Args() {
+		w, p, err := historyWorkload(historyFile, *dbName, *clientCase)
+		if err != nil {
+			log.Printf("read history %s failed %v", historyFile, err)
+			return 2
+		}
+
+		s, err := stats.Read(historyFile, p, history.GetEDNCodec(w.Parser), *window)
+		if err != nil {
+			log.Printf("read history %s failed %v", historyFile, err)
+			return 1
+		}
+		if *asJSON {
+			err = s.WriteJSON(os.Stdout)
+		} else {
+			err = s.WriteText(os.Stdout)
+			os.Stdout.WriteString("\n")
+		}
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+
+		if *plot {
+			names, err := s.WritePlots()
+			if err != nil {
+				log.Printf("plot history %s failed %v", historyFile, err)
+				return 1
+			}
+			log.Printf("plots of %s: %s", historyFile, strings.Join(names, ", "))
+		}
+	}
+	return 0
+}
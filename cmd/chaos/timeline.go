@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+	"github.com/pingcap/chaos/pkg/timeline"
+)
+
+func timelineCommand(args []string) int {
+	fs := newFlagSet("timeline", "<history file>")
+	dbName := fs.String("db", "", "database of the client test case, default is the one in the history header")
+	clientCase := fs.String("case", "", "client test case, its model and parser are used, default is the one in the history header")
+	output := fs.String("o", "", "HTML file to write, default is the history file with .timeline.html")
+	explain := fs.Bool("explain", true, "highlight the longest linearizable prefix with the model of the case")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	historyFile := fs.Arg(0)
+	name := *output
+	if len(name) == 0 {
+		name = historyFile + ".timeline.html"
+	}
+
+	w, p, err := historyWorkload(historyFile, *dbName, *clientCase)
+	if err != nil {
+		log.Printf("read history %s failed %v", historyFile, err)
+		return 2
+	}
+	ops, state, err := history.ReadHistory(historyFile, p)
+	if err != nil {
+		log.Printf("read history %s failed %v", historyFile, err)
+		return 1
+	}
+	if ops, err = history.CompleteOperations(ops, p); err != nil {
+		log.Printf("read history %s failed %v", historyFile, err)
+		return 1
+	}
+
+	var m core.Model
+	if *explain && len(w.Model) != 0 {
+		if m = core.NewModel(w.Model); m == nil {
+			log.Printf("model %s is not registered", w.Model)
+			return 2
+		}
+		m.Prepare(state)
+	}
+	t, err := timeline.Build(context.Background(), historyFile, ops, history.GetEDNCodec(w.Parser), m)
+	if err != nil {
+		log.Printf("build timeline of %s failed %v", historyFile, err)
+		return 1
+	}
+	if err = t.WriteFile(name); err != nil {
+		log.Printf("write timeline to %s failed %v", name, err)
+		return 1
+	}
+	log.Printf("timeline of history %s is written to %s", historyFile, name)
+	return 0
+}
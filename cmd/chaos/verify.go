@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log"
+
+	"github.com/pingcap/chaos/cmd/util"
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/verify"
+)
+
+func verifyCommand(args []string) int {
+	fs := newFlagSet("verify", "<history file>...")
+	dbName := fs.String("db", "", "database of the client test case, default is the one in the history header")
+	clientCase := fs.String("case", "", "client test case, its model, parser and checkers are used, default is the one in the history header")
+	checkers := fs.String("checker", "", "checkers, seperated by comma, default is the checkers of the case")
+	summaryFile := fs.String("summary", "", "write the summary of the results to the file in json")
+	fs.DurationVar(&verify.CheckTimeout, "check-timeout", verify.CheckTimeout, "how long a checker checks a history before it is unknown, 0 means no limit")
+	fs.DurationVar(&verify.ShrinkTimeout, "shrink-timeout", verify.ShrinkTimeout, "how long to shrink an invalid history, e.g, 5m, 0 disables shrinking")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	checkerNames := util.SplitNames(*checkers)
+	var suits []verify.Suit
+	if len(*clientCase) != 0 {
+		w, ok := core.GetWorkload(*dbName, *clientCase)
+		if !ok {
+			log.Printf("invalid client test case %s of db %s", *clientCase, *dbName)
+			return 2
+		}
+		var err error
+		if suits, err = verify.WorkloadSuits(w, checkerNames); err != nil {
+			log.Print(err)
+			return 2
+		}
+	}
+
+	var results []verify.Result
+	for _, historyFile := range fs.Args() {
+		historySuits := suits
+		if len(*clientCase) == 0 {
+			var err error
+			// Choose the suits by the workload in the history header.
+			if historySuits, err = verify.HistorySuits(historyFile, checkerNames); err != nil {
+				log.Printf("verify history %s failed %v", historyFile, err)
+				results = append(results, verify.Result{
+					Outcome:     verify.Unknown,
+					HistoryFile: historyFile,
+					Err:         err.Error(),
+				})
+				continue
+			}
+		}
+		for _, s := range historySuits {
+			results = append(results, s.Verify(historyFile))
+		}
+	}
+
+	summary := verify.Summarize(results)
+	if len(*summaryFile) != 0 {
+		if err := verify.WriteSummary(*summaryFile, summary); err != nil {
+			log.Printf("write summary to %s failed %v", *summaryFile, err)
+		}
+	}
+	printResults(summary.Results)
+
+	if summary.Outcome != verify.Valid {
+		log.Printf("verify finished with outcome %s", summary.Outcome)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+// historyWorkload returns the workload of the db, or the one in the history
+// header if the name is empty, and its parser.
+func historyWorkload(historyFile string, db string, name string) (core.Workload, history.RecordParser, error) {
+	if len(name) == 0 {
+		h, err := history.ReadHeader(historyFile)
+		if err != nil {
+			return core.Workload{}, nil, err
+		}
+		db, name = h.DB, h.Workload
+	}
+	w, ok := core.GetWorkload(db, name)
+	if !ok {
+		return w, nil, fmt.Errorf("invalid client test case %s of db %s", name, db)
+	}
+	p := history.GetParser(w.Parser)
+	if p == nil {
+		return w, nil, fmt.Errorf("parser %s is not registered", w.Parser)
+	}
+	return w, p, nil
+}
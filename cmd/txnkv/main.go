@@ -7,52 +7,31 @@ import (
 	"time"
 
 	"github.com/pingcap/chaos/cmd/util"
-	"github.com/pingcap/chaos/db/txnkv"
-	"github.com/pingcap/chaos/pkg/check/porcupine"
-	"github.com/pingcap/chaos/pkg/control"
-	"github.com/pingcap/chaos/pkg/core"
-	"github.com/pingcap/chaos/pkg/model"
-	"github.com/pingcap/chaos/pkg/verify"
+	_ "github.com/pingcap/chaos/db/txnkv"
 )
 
-var (
-	requestCount = flag.Int("request-count", 500, "client test request count")
-	round        = flag.Int("round", 3, "client test request count")
-	runTime      = flag.Duration("run-time", 10*time.Minute, "client test run time")
-	clientCase   = flag.String("case", "register", "client test case, like register")
-	historyFile  = flag.String("history", "./history.log", "history file")
-	nemesises    = flag.String("nemesis", "", "nemesis, seperated by name, like random_kill,all_kill")
-)
+var specFlags = util.RegisterSpecFlags(flag.CommandLine, util.Spec{
+	DB:           "txnkv",
+	Executor:     "ssh",
+	Round:        3,
+	RunTime:      util.Duration{Duration: 10 * time.Minute},
+	RequestCount: 500,
+	Concurrency:  1,
+	Workload:     util.Workload{Name: "register"},
+	OutputDir:    "./var",
+})
 
 func main() {
 	flag.Parse()
 
-	cfg := control.Config{
-		DB:           "txnkv",
-		RequestCount: *requestCount,
-		RunRound:     *round,
-		RunTime:      *runTime,
-		History:      *historyFile,
-	}
-
-	var creator core.ClientCreator
-	switch *clientCase {
-	case "register":
-		creator = txnkv.RegisterClientCreator{}
-	default:
-		log.Fatalf("invalid client test case %s", *clientCase)
+	spec, err := specFlags.Spec()
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	verifySuit := verify.Suit{
-		Model:   model.RegisterModel(),
-		Checker: porcupine.Checker{},
-		Parser:  model.RegisterParser(),
-	}
-	suit := util.Suit{
-		Config:        &cfg,
-		ClientCreator: creator,
-		Nemesises:     *nemesises,
-		VerifySuit:    verifySuit,
+	suit, err := util.NewSuit(spec)
+	if err != nil {
+		log.Fatal(err)
 	}
-	suit.Run(context.Background(), []string{})
+	suit.Run(context.Background(), spec.Nodes)
 }
@@ -0,0 +1,295 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/pingcap/chaos/pkg/control"
+)
+
+// Spec is a declarative chaos test specification, loaded from a toml or yaml file.
+//
+// A toml spec looks like:
+//
+//	db = "tidb"
+//	nodes = ["n1", "n2", "n3", "n4", "n5"]
+//	round = 3
+//	run_time = "10m"
+//	request_count = 500
+//	nemeses = ["random_kill", "random_drop"]
+//	checkers = ["porcupine", "tidb_bank_tso"]
+//	output_dir = "./output/bank"
+//
+//	[workload]
+//	name = "long_fork"
+//	[workload.params]
+//	table_count = 7
+type Spec struct {
+	// DB is the database to test.
+	DB string `toml:"db" yaml:"db"`
+	// Nodes are the nodes of the cluster, default is n1 - n5.
+	Nodes []string `toml:"nodes" yaml:"nodes"`
+	// Executor is how to run commands on nodes, see control.Config.
+	Executor string `toml:"executor" yaml:"executor"`
+
+	Round        int      `toml:"round" yaml:"round"`
+	RunTime      Duration `toml:"run_time" yaml:"run_time"`
+	RequestCount int      `toml:"request_count" yaml:"request_count"`
+	Concurrency  int      `toml:"concurrency" yaml:"concurrency"`
+	// Seed is the seed of the nemesis random source, 0 means a time based seed.
+	Seed int64 `toml:"seed" yaml:"seed"`
+
+	Workload Workload `toml:"workload" yaml:"workload"`
+	// Nemeses are the nemesis generators, run one by one in order.
+	Nemeses []string `toml:"nemeses" yaml:"nemeses"`
+	// Checkers verify the history of every round, default is the checkers of the workload.
+	Checkers []string `toml:"checkers" yaml:"checkers"`
+	// OnlineCheckers check the operations while the test runs, see control.Config.
+	OnlineCheckers []string `toml:"online_checkers" yaml:"online_checkers"`
+	// CheckTimeout limits how long a checker checks a history, see verify.Suit.
+	CheckTimeout Duration `toml:"check_timeout" yaml:"check_timeout"`
+
+	// OutputDir is where the run directories are created, see control.Config.
+	OutputDir string `toml:"output_dir" yaml:"output_dir"`
+	// History is the path prefix of the history files, default is
+	// history.log in the run directory.
+	History string `toml:"history" yaml:"history"`
+	// HistoryCompression compresses the history files with gzip or zstd.
+	HistoryCompression string `toml:"history_compression" yaml:"history_compression"`
+	// HistorySync writes the history records by none (default), flush or fsync, see control.Config.
+	HistorySync string `toml:"history_sync" yaml:"history_sync"`
+}
+
+// Workload is the client test case and its parameters.
+type Workload struct {
+	Name   string                 `toml:"name" yaml:"name"`
+	Params map[string]interface{} `toml:"params" yaml:"params"`
+}
+
+// Decode decodes the parameters into v, which is usually a client creator.
+// The fields of v are matched by their json tags, an unknown parameter is an error.
+func (w Workload) Decode(v interface{}) error {
+	if len(w.Params) == 0 {
+		return nil
+	}
+
+	params := make(map[string]interface{}, len(w.Params))
+	for k, v := range w.Params {
+		params[k] = v
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("invalid params of workload %s: %v", w.Name, err)
+	}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err = d.Decode(v); err != nil {
+		return fmt.Errorf("invalid params of workload %s: %v", w.Name, err)
+	}
+	return nil
+}
+
+// Duration is a time.Duration written like "10m" in a spec file.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// WriteSpec writes the spec to the file in toml.
+func (s *Spec) WriteSpec(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return toml.NewEncoder(f).Encode(s)
+}
+
+// LoadSpec loads the spec file on top of the spec. The file is
+// decoded as yaml if it ends with .yaml or .yml, otherwise as toml.
+func (s *Spec) LoadSpec(name string) error {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	switch path.Ext(name) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, s)
+	default:
+		var meta toml.MetaData
+		meta, err = toml.Decode(string(data), s)
+		if err == nil && len(meta.Undecoded()) > 0 {
+			err = fmt.Errorf("unknown fields %v", meta.Undecoded())
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("load spec %s failed: %v", name, err)
+	}
+	return nil
+}
+
+// Config returns the controller configuration of the spec.
+func (s *Spec) Config() *control.Config {
+	return &control.Config{
+		DB:                 s.DB,
+		Workload:           s.Workload.Name,
+		Nodes:              s.Nodes,
+		Executor:           s.Executor,
+		RunRound:           s.Round,
+		RunTime:            s.RunTime.Duration,
+		RequestCount:       s.RequestCount,
+		Concurrency:        s.Concurrency,
+		Seed:               s.Seed,
+		OutputDir:          s.OutputDir,
+		History:            s.History,
+		HistoryCompression: s.HistoryCompression,
+		HistorySync:        s.HistorySync,
+		OnlineCheckers:     s.OnlineCheckers,
+	}
+}
+
+// SpecFlags are the flags shared by the chaos binaries. A flag given in the
+// command line overrides the value in the spec file.
+type SpecFlags struct {
+	fs       *flag.FlagSet
+	defaults Spec
+
+	spec         *string
+	db           *string
+	nodes        *string
+	executor     *string
+	round        *int
+	runTime      *time.Duration
+	requestCount *int
+	concurrency  *int
+	seed         *int64
+	workload     *string
+	nemeses      *string
+	checkers     *string
+	online       *string
+	checkTimeout *time.Duration
+	outputDir    *string
+	history      *string
+	compression  *string
+	syncPolicy   *string
+}
+
+// RegisterSpecFlags registers the spec flags to the flag set, the defaults
+// are used when neither the spec file nor the command line sets a value.
+func RegisterSpecFlags(fs *flag.FlagSet, defaults Spec) *SpecFlags {
+	return &SpecFlags{
+		fs:       fs,
+		defaults: defaults,
+
+		spec:         fs.String("config", "", "test spec file in toml or yaml, flags override values in it"),
+		db:           fs.String("db", defaults.DB, "database to test"),
+		nodes:        fs.String("nodes", strings.Join(defaults.Nodes, ","), "nodes, seperated by comma, default is n1 - n5"),
+		executor:     fs.String("executor", defaults.Executor, "executor to run commands on nodes, like ssh, native-ssh:inventory.toml, local, docker"),
+		round:        fs.Int("round", defaults.Round, "client test round count"),
+		runTime:      fs.Duration("run-time", defaults.RunTime.Duration, "client test run time"),
+		requestCount: fs.Int("request-count", defaults.RequestCount, "client test request count"),
+		concurrency:  fs.Int("concurrency", defaults.Concurrency, "client count on one node"),
+		seed:         fs.Int64("seed", defaults.Seed, "seed of the nemesis random source, 0 means a time based seed"),
+		workload:     fs.String("case", defaults.Workload.Name, "client test case"),
+		nemeses:      fs.String("nemesis", strings.Join(defaults.Nemeses, ","), "nemesis, seperated by comma, like random_kill,all_kill"),
+		checkers:     fs.String("checker", strings.Join(defaults.Checkers, ","), "checkers, seperated by comma, default is the checkers of the case"),
+		online:       fs.String("online-checker", strings.Join(defaults.OnlineCheckers, ","), "online checkers, seperated by comma, which abort the test on the first violation"),
+		checkTimeout: fs.Duration("check-timeout", defaults.CheckTimeout.Duration, "how long a checker checks a history before it is unknown, default is verify.CheckTimeout"),
+		outputDir:    fs.String("output-dir", defaults.OutputDir, "output directory, every run creates a directory in it"),
+		history:      fs.String("history", defaults.History, "history file prefix, default is history.log in the run directory"),
+		compression:  fs.String("history-compression", defaults.HistoryCompression, "compress the history files with gzip or zstd"),
+		syncPolicy:   fs.String("history-sync", defaults.HistorySync, "buffer the history records with none (default), or write every record to the file with flush or fsync, so an aborted run keeps its history"),
+	}
+}
+
+// Spec returns the spec from the defaults, the spec file and the command line
+// flags, the later one overrides the former one. It must be called after the
+// flag set is parsed.
+func (f *SpecFlags) Spec() (*Spec, error) {
+	s := f.defaults
+	if len(*f.spec) != 0 {
+		if err := s.LoadSpec(*f.spec); err != nil {
+			return nil, err
+		}
+	}
+
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "db":
+			s.DB = *f.db
+		case "nodes":
+			s.Nodes = SplitNames(*f.nodes)
+		case "executor":
+			s.Executor = *f.executor
+		case "round":
+			s.Round = *f.round
+		case "run-time":
+			s.RunTime.Duration = *f.runTime
+		case "request-count":
+			s.RequestCount = *f.requestCount
+		case "concurrency":
+			s.Concurrency = *f.concurrency
+		case "seed":
+			s.Seed = *f.seed
+		case "case":
+			// Parameters are for the workload in the spec file.
+			if s.Workload.Name != *f.workload {
+				s.Workload = Workload{Name: *f.workload}
+			}
+		case "nemesis":
+			s.Nemeses = SplitNames(*f.nemeses)
+		case "checker":
+			s.Checkers = SplitNames(*f.checkers)
+		case "online-checker":
+			s.OnlineCheckers = SplitNames(*f.online)
+		case "check-timeout":
+			s.CheckTimeout.Duration = *f.checkTimeout
+		case "output-dir":
+			s.OutputDir = *f.outputDir
+		case "history":
+			s.History = *f.history
+		case "history-compression":
+			s.HistoryCompression = *f.compression
+		case "history-sync":
+			s.HistorySync = *f.syncPolicy
+		}
+	})
+
+	return &s, nil
+}
+
+// SplitNames splits the comma separated names, empty names are skipped.
+func SplitNames(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadSpec(t *testing.T) {
+	for _, name := range []string{"tidb-bank.toml", "rawkv-register.yaml"} {
+		var s Spec
+		if err := s.LoadSpec(path.Join("../../examples", name)); err != nil {
+			t.Fatal(err)
+		}
+		if len(s.DB) == 0 || len(s.Nodes) != 5 || s.RunTime.Duration != 10*time.Minute || len(s.Workload.Name) == 0 {
+			t.Fatalf("unexpected spec %s: %+v", name, s)
+		}
+	}
+}
+
+func TestSpecFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := path.Join(dir, "spec.toml")
+	data := `
+round = 5
+run_time = "1m"
+nemeses = ["random_kill"]
+output_dir = "/tmp/out"
+
+[workload]
+name = "long_fork"
+[workload.params]
+table_count = 3
+`
+	if err = ioutil.WriteFile(name, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := RegisterSpecFlags(fs, Spec{DB: "tidb", Round: 3, RequestCount: 500, Workload: Workload{Name: "bank"}})
+	if err = fs.Parse([]string{"-config", name, "-round", "7", "-nemesis", "all_kill, random_drop"}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := f.Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.DB != "tidb" || s.RequestCount != 500 {
+		t.Fatalf("defaults must be kept: %+v", s)
+	}
+	if s.Round != 7 || !reflect.DeepEqual(s.Nemeses, []string{"all_kill", "random_drop"}) {
+		t.Fatalf("flags must override the spec file: %+v", s)
+	}
+	if s.RunTime.Duration != time.Minute || s.Workload.Name != "long_fork" {
+		t.Fatalf("spec file must override defaults: %+v", s)
+	}
+	if cfg := s.Config(); cfg.OutputDir != "/tmp/out" || cfg.RunRound != 7 {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	var params struct {
+		TableCount int `json:"table_count"`
+	}
+	if err = s.Workload.Decode(&params); err != nil || params.TableCount != 3 {
+		t.Fatalf("decode params failed %v, %+v", err, params)
+	}
+	var unknown struct{}
+	if err = s.Workload.Decode(&unknown); err == nil {
+		t.Fatal("unknown params must fail")
+	}
+
+	if err = ioutil.WriteFile(name, []byte("rounds = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.Spec(); err == nil {
+		t.Fatal("unknown fields must fail")
+	}
+}
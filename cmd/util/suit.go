@@ -6,7 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
-	"strings"
+	"path"
 	"syscall"
 
 	"github.com/pingcap/chaos/pkg/control"
@@ -19,29 +19,51 @@ import (
 type Suit struct {
 	*control.Config
 	core.ClientCreator
-	// nemesis, seperated by comma.
-	Nemesises string
+	Nemesises []string
 
-	VerifySuit verify.Suit
+	VerifySuits []verify.Suit
+
+	// Spec is written to the run directory if it is not nil.
+	Spec *Spec
 }
 
-// Run runs the suit.
+// NewSuit creates the suit of the spec with the registered workload and checkers.
+func NewSuit(spec *Spec) (*Suit, error) {
+	w, ok := core.GetWorkload(spec.DB, spec.Workload.Name)
+	if !ok {
+		return nil, fmt.Errorf("invalid client test case %s of db %s", spec.Workload.Name, spec.DB)
+	}
+
+	creator := w.NewClientCreator()
+	if err := spec.Workload.Decode(creator); err != nil {
+		return nil, err
+	}
+
+	verifySuits, err := verify.WorkloadSuits(w, spec.Checkers)
+	if err != nil {
+		return nil, err
+	}
+	for i := range verifySuits {
+		verifySuits[i].Timeout = spec.CheckTimeout.Duration
+	}
+
+	return &Suit{
+		Config:        spec.Config(),
+		ClientCreator: creator,
+		Nemesises:     spec.Nemeses,
+		VerifySuits:   verifySuits,
+		Spec:          spec,
+	}, nil
+}
+
+// Run runs the suit. It exits with a non-zero code if any history
+// is not valid after the whole test finishes.
 func (suit *Suit) Run(ctx context.Context, nodes []string) {
 	var nemesisGens []core.NemesisGenerator
-	for _, name := range strings.Split(suit.Nemesises, ",") {
-		var g core.NemesisGenerator
-		name := strings.TrimSpace(name)
-		if len(name) == 0 {
-			continue
-		}
-
-		switch name {
-		case "random_kill", "all_kill", "minor_kill", "major_kill":
-			g = nemesis.NewKillGenerator(suit.Config.DB, name)
-		case "random_drop", "all_drop", "minor_drop", "major_drop":
-			g = nemesis.NewDropGenerator(name)
-		default:
-			log.Fatalf("invalid nemesis generator %s", name)
+	for _, name := range suit.Nemesises {
+		g, err := nemesis.NewGenerator(suit.Config.DB, name)
+		if err != nil {
+			log.Fatal(err)
 		}
 
 		nemesisGens = append(nemesisGens, g)
@@ -64,9 +86,20 @@ func (suit *Suit) Run(ctx context.Context, nodes []string) {
 		suit.Config,
 		suit.ClientCreator,
 		nemesisGens,
-		suit.VerifySuit,
+		suit.VerifySuits,
 	)
 
+	if runDir := c.RunDir(); len(runDir) != 0 && suit.Spec != nil {
+		// Record the effective spec, so the run can be repeated.
+		spec := *suit.Spec
+		spec.Nodes = suit.Config.Nodes
+		spec.Seed = suit.Config.Seed
+		name := path.Join(runDir, "config.toml")
+		if err := spec.WriteSpec(name); err != nil {
+			log.Printf("write spec to %s failed %v", name, err)
+		}
+	}
+
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 
@@ -76,5 +109,11 @@ func (suit *Suit) Run(ctx context.Context, nodes []string) {
 		cancel()
 	}()
 
-	c.Run()
+	summary := c.Run()
+	cancel()
+
+	if summary.Outcome != verify.Valid {
+		log.Printf("test finished with outcome %s", summary.Outcome)
+		os.Exit(1)
+	}
 }
@@ -3,29 +3,62 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 
-	"github.com/pingcap/chaos/db/tidb"
-	"github.com/pingcap/chaos/pkg/check/porcupine"
-	"github.com/pingcap/chaos/pkg/model"
+	"github.com/pingcap/chaos/cmd/util"
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
 	"github.com/pingcap/chaos/pkg/verify"
+
+	// register workloads, models, parsers and checkers
+	_ "github.com/pingcap/chaos/db/rawkv"
+	_ "github.com/pingcap/chaos/db/tidb"
+	_ "github.com/pingcap/chaos/db/txnkv"
 )
 
 var (
 	historyFile = flag.String("history", "./history.log", "history file")
-	names       = flag.String("names", "", "model names, seperate by comma")
+	dbName      = flag.String("db", "", "database of the client test case, default is the one in the history header")
+	clientCase  = flag.String("case", "", "client test case, its model, parser and checkers are used, default is the one in the history header")
+	checkers    = flag.String("checker", "", "checkers, seperated by comma, default is the checkers of the case")
+	list        = flag.Bool("list", false, "list the registered cases, models, parsers and checkers")
 	pprofAddr   = flag.String("pprof", "0.0.0.0:6060", "Pprof address")
 )
 
 func main() {
 	flag.Parse()
 
+	if *list {
+		printRegistered()
+		return
+	}
+
+	var suits []verify.Suit
+	if len(*clientCase) != 0 {
+		w, ok := core.GetWorkload(*dbName, *clientCase)
+		if !ok {
+			log.Fatalf("invalid client test case %s of db %s", *clientCase, *dbName)
+		}
+		var err error
+		if suits, err = verify.WorkloadSuits(w, util.SplitNames(*checkers)); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		var err error
+		// Choose the suits by the workload in the history header.
+		if suits, err = verify.HistorySuits(*historyFile, util.SplitNames(*checkers)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
@@ -41,30 +74,43 @@ func main() {
 
 	childCtx, cancel := context.WithCancel(ctx)
 
+	var (
+		resultsMu sync.Mutex
+		results   []verify.Result
+	)
 	go func() {
-		for _, name := range strings.Split(*names, ",") {
-			s := verify.Suit{}
-			switch name {
-			case "tidb_bank":
-				s.Model, s.Parser, s.Checker = tidb.BankModel(), tidb.BankParser(), porcupine.Checker{}
-			case "tidb_bank_tso":
-				// Actually we can omit BankModel, since BankTsoChecker does not require a Model.
-				s.Model, s.Parser, s.Checker = tidb.BankModel(), tidb.BankParser(), tidb.BankTsoChecker()
-			case "sequential":
-				s.Parser, s.Checker = tidb.NewSequentialParser(), tidb.NewSequentialChecker()
-			case "register":
-				s.Model, s.Parser, s.Checker = model.RegisterModel(), model.RegisterParser(), porcupine.Checker{}
-			case "":
-				continue
-			default:
-				log.Printf("%s is not supported", name)
-				continue
-			}
-			s.Verify(*historyFile)
+		for _, s := range suits {
+			r := s.Verify(*historyFile)
+			resultsMu.Lock()
+			results = append(results, r)
+			resultsMu.Unlock()
 		}
 
 		cancel()
 	}()
 
 	<-childCtx.Done()
+
+	resultsMu.Lock()
+	defer resultsMu.Unlock()
+	if len(results) < len(suits) {
+		// The verification is interrupted, so the outcome is unknown.
+		log.Printf("verify interrupted after %d of %d checkers", len(results), len(suits))
+		os.Exit(1)
+	}
+	if summary := verify.Summarize(results); summary.Outcome != verify.Valid {
+		log.Printf("verify finished with outcome %s", summary.Outcome)
+		os.Exit(1)
+	}
+}
+
+func printRegistered() {
+	fmt.Println("cases:")
+	for _, w := range core.Workloads("") {
+		fmt.Printf("  %s/%s: model=%s parser=%s checkers=%s\n",
+			w.DB, w.Name, w.Model, w.Parser, strings.Join(w.Checkers, ","))
+	}
+	fmt.Printf("models: %s\n", strings.Join(core.ModelNames(), ", "))
+	fmt.Printf("parsers: %s\n", strings.Join(history.ParserNames(), ", "))
+	fmt.Printf("checkers: %s\n", strings.Join(core.CheckerNames(), ", "))
 }
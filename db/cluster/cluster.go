@@ -11,7 +11,7 @@ import (
 	"time"
 
 	"github.com/pingcap/chaos/pkg/util"
-	"github.com/pingcap/chaos/pkg/util/ssh"
+	"github.com/pingcap/chaos/pkg/util/executor"
 )
 
 const (
@@ -48,10 +48,10 @@ type Cluster struct {
 func (cluster *Cluster) SetUp(ctx context.Context, nodes []string, node string) error {
 	// Try kill all old servers
 	if cluster.IncludeTidb {
-		ssh.Exec(ctx, node, "killall", "-9", "tidb-server")
+		executor.Exec(ctx, node, "killall", "-9", "tidb-server")
 	}
-	ssh.Exec(ctx, node, "killall", "-9", "tikv-server")
-	ssh.Exec(ctx, node, "killall", "-9", "pd-server")
+	executor.Exec(ctx, node, "killall", "-9", "tikv-server")
+	executor.Exec(ctx, node, "killall", "-9", "pd-server")
 
 	cluster.once.Do(func() {
 		cluster.nodes = nodes
@@ -176,7 +176,7 @@ WAIT:
 			// Member API works when PD cluster is ready.
 			memberAPI := fmt.Sprintf("%s/pd/api/v1/members", ep)
 			// `--fail`, non-zero exit code on server errors.
-			_, err := ssh.CombinedOutput(ctx, node, "curl", "--fail", memberAPI)
+			_, err := executor.CombinedOutput(ctx, node, "curl", "--fail", memberAPI)
 			if err == nil {
 				log.Println("PD cluster is ready")
 				break WAIT
@@ -224,7 +224,7 @@ WAIT:
 		var err error
 		if inSetUp {
 			for i := 0; i < 12; i++ {
-				if err = ssh.Exec(ctx, node, "curl", fmt.Sprintf("http://%s:10080/status", node)); err == nil {
+				if err = executor.Exec(ctx, node, "curl", fmt.Sprintf("http://%s:10080/status", node)); err == nil {
 					break
 				}
 				log.Printf("try to wait tidb run on %s", node)
@@ -282,6 +282,36 @@ func (cluster *Cluster) IsRunning(ctx context.Context, node string) bool {
 	return util.IsDaemonRunning(ctx, node, tidbBinary, path.Join(deployDir, "tikv.pid"))
 }
 
+// Version returns the version of TiDB, or TiKV if TiDB is not included.
+func (cluster *Cluster) Version(ctx context.Context, node string) (string, error) {
+	binary := tikvBinary
+	if cluster.IncludeTidb {
+		binary = tidbBinary
+	}
+	output, err := executor.CombinedOutput(ctx, node, binary, "-V")
+	if err != nil {
+		return "", err
+	}
+
+	// The version output is several lines of "key: value", join them into one line.
+	var lines []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); len(line) != 0 {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "; "), nil
+}
+
+// LogFiles returns the log files of the database on the node.
+func (cluster *Cluster) LogFiles(node string) []string {
+	files := []string{pdLog, tikvLog}
+	if cluster.IncludeTidb {
+		files = append(files, tidbLog)
+	}
+	return files
+}
+
 // Name returns the unique name for the database
 func (cluster *Cluster) Name() string {
 	return "cluster"
@@ -0,0 +1,126 @@
+package rawkv
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/model"
+	"github.com/pingcap/tidb/config"
+	"github.com/pingcap/tidb/store/tikv"
+)
+
+var (
+	// RawKV can't add to a value atomically, so every add puts a key of the
+	// prefix, and a read sums all the keys.
+	counterPrefix = []byte("counter_")
+	// counterEnd is the end of the prefix, '`' is the next byte of '_'.
+	counterEnd = []byte("counter`")
+	// counterKey is the last key id of the adds, the ids are unique in a test.
+	counterKey int64
+)
+
+type counterClient struct {
+	db       *tikv.RawKVClient
+	r        *rand.Rand
+	maxDelta int
+}
+
+func (c *counterClient) SetUp(ctx context.Context, nodes []string, node string) error {
+	c.r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	db, err := tikv.NewRawKVClient([]string{fmt.Sprintf("%s:2379", node)}, config.Security{})
+	if err != nil {
+		return err
+	}
+
+	c.db = db
+
+	// Do SetUp in the first node
+	if node != nodes[0] {
+		return nil
+	}
+
+	log.Printf("begin to clear counter on node %s", node)
+
+	return db.DeleteRange(counterPrefix, counterEnd)
+}
+
+func (c *counterClient) TearDown(ctx context.Context, nodes []string, node string) error {
+	return c.db.Close()
+}
+
+// sum scans all the keys of the counter, the scan is not atomic, so it may
+// miss the adds running concurrently.
+func (c *counterClient) sum() (int, error) {
+	sum := 0
+	start := counterPrefix
+	for {
+		keys, values, err := c.db.Scan(start, counterEnd, tikv.MaxRawKVScanLimit)
+		if err != nil {
+			return 0, err
+		}
+		for _, val := range values {
+			v, err := strconv.Atoi(string(val))
+			if err != nil {
+				panic(fmt.Sprintf("invalid value: %s", val))
+			}
+			sum += v
+		}
+		if len(keys) < tikv.MaxRawKVScanLimit {
+			return sum, nil
+		}
+		// Scan from the next key of the last one.
+		start = append(append([]byte(nil), keys[len(keys)-1]...), 0)
+	}
+}
+
+func (c *counterClient) Invoke(ctx context.Context, node string, r interface{}) interface{} {
+	arg := r.(model.CounterRequest)
+	if arg.Op == model.CounterRead {
+		sum, err := c.sum()
+		if err != nil {
+			return model.CounterResponse{Unknown: true}
+		}
+		return model.CounterResponse{Value: sum}
+	}
+
+	key := fmt.Sprintf("%s%d", counterPrefix, atomic.AddInt64(&counterKey, 1))
+	if err := c.db.Put([]byte(key), []byte(strconv.Itoa(arg.Value))); err != nil {
+		return model.CounterResponse{Unknown: true}
+	}
+	return model.CounterResponse{}
+}
+
+func (c *counterClient) NextRequest() interface{} {
+	if c.r.Intn(2) == 0 {
+		return model.CounterRequest{Op: model.CounterRead}
+	}
+	return model.CounterRequest{Op: model.CounterAdd, Value: 1 + c.r.Intn(c.maxDelta)}
+}
+
+// DumpState the database state(also the model's state)
+func (c *counterClient) DumpState(ctx context.Context) (interface{}, error) {
+	return c.sum()
+}
+
+// CounterClientCreator creates a counter test client for rawkv.
+type CounterClientCreator struct {
+	// MaxDelta is the max value an add adds to the counter, default is 5.
+	MaxDelta int `json:"max_delta"`
+}
+
+// Create creates a client.
+func (c CounterClientCreator) Create(node string) core.Client {
+	maxDelta := c.MaxDelta
+	if maxDelta == 0 {
+		maxDelta = 5
+	}
+	return &counterClient{
+		maxDelta: maxDelta,
+	}
+}
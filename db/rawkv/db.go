@@ -3,6 +3,10 @@ package rawkv
 import (
 	"github.com/pingcap/chaos/db/cluster"
 	"github.com/pingcap/chaos/pkg/core"
+
+	// register porcupine checker, register model and parser
+	_ "github.com/pingcap/chaos/pkg/check/porcupine"
+	_ "github.com/pingcap/chaos/pkg/model"
 )
 
 // db is the TiDB database.
@@ -20,4 +24,21 @@ func init() {
 		// RawKV does not use TiDB.
 		cluster.Cluster{IncludeTidb: false},
 	})
+
+	core.RegisterWorkload(core.Workload{
+		DB:               "rawkv",
+		Name:             "register",
+		NewClientCreator: func() core.ClientCreator { return &RegisterClientCreator{} },
+		Model:            "register",
+		Parser:           "register",
+		Checkers:         []string{"porcupine"},
+	})
+	core.RegisterWorkload(core.Workload{
+		DB:               "rawkv",
+		Name:             "counter",
+		NewClientCreator: func() core.ClientCreator { return &CounterClientCreator{} },
+		Model:            "counter",
+		Parser:           "counter",
+		Checkers:         []string{"counter_bounds"},
+	})
 }
@@ -100,7 +100,7 @@ func (c *registerClient) DumpState(ctx context.Context) (interface{}, error) {
 	return v, nil
 }
 
-func newRegisterEvent(v interface{}, id uint) porcupine.Event {
+func newRegisterEvent(v interface{}, id int) porcupine.Event {
 	if _, ok := v.(model.RegisterRequest); ok {
 		return porcupine.Event{Kind: porcupine.CallEvent, Value: v, Id: id}
 	}
@@ -0,0 +1,158 @@
+package tidb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/check/elle"
+	"github.com/pingcap/chaos/pkg/core"
+)
+
+// appendValue is the last value appended, the values are unique in a test.
+var appendValue int64
+
+// appendClient runs list-append transactions, every key is a row whose
+// value is the comma separated list.
+type appendClient struct {
+	db        *sql.DB
+	r         *rand.Rand
+	keyCount  int
+	txnLength int
+}
+
+func (c *appendClient) SetUp(ctx context.Context, nodes []string, node string) error {
+	c.r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	db, err := sql.Open("mysql", fmt.Sprintf("root@tcp(%s:4000)/test", node))
+	if err != nil {
+		return err
+	}
+	c.db = db
+
+	db.SetMaxIdleConns(1)
+
+	// Do SetUp in the first node
+	if node != nodes[0] {
+		return nil
+	}
+
+	log.Printf("begin to create table txn_append on node %s", node)
+	if _, err = db.ExecContext(ctx, "drop table if exists txn_append"); err != nil {
+		return err
+	}
+	if _, err = db.ExecContext(ctx, "create table if not exists txn_append (id int not null primary key, val text not null)"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *appendClient) TearDown(ctx context.Context, nodes []string, node string) error {
+	return c.db.Close()
+}
+
+func (c *appendClient) Invoke(ctx context.Context, node string, r interface{}) interface{} {
+	req := r.(elle.TxnRequest)
+	txn, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return elle.TxnResponse{Ok: false}
+	}
+	defer txn.Rollback()
+
+	mops := make([]elle.Mop, 0, len(req.Mops))
+	for _, m := range req.Mops {
+		switch m.F {
+		case elle.MopRead:
+			var val string
+			err = txn.QueryRowContext(ctx, "select val from txn_append where id = ?", m.Key).Scan(&val)
+			if err == sql.ErrNoRows {
+				err = nil
+			}
+			if err != nil {
+				return elle.TxnResponse{Ok: false}
+			}
+			m.List, err = parseAppendList(val)
+			if err != nil {
+				log.Printf("invalid list %q of key %s: %v", val, m.Key, err)
+				return elle.TxnResponse{Ok: false}
+			}
+		case elle.MopAppend:
+			v := strconv.Itoa(m.Value)
+			if _, err = txn.ExecContext(ctx, "insert into txn_append (id, val) values (?, ?) on duplicate key update val = concat(val, ',', ?)", m.Key, v, v); err != nil {
+				return elle.TxnResponse{Ok: false}
+			}
+		default:
+			panic(fmt.Sprintf("unknown micro operation %v", m))
+		}
+		mops = append(mops, m)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return elle.TxnResponse{Unknown: true}
+	}
+	return elle.TxnResponse{Ok: true, Mops: mops}
+}
+
+func parseAppendList(val string) ([]int, error) {
+	list := []int{}
+	if len(val) == 0 {
+		return list, nil
+	}
+	for _, s := range strings.Split(val, ",") {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func (c *appendClient) NextRequest() interface{} {
+	n := 1 + c.r.Intn(c.txnLength)
+	mops := make([]elle.Mop, 0, n)
+	for i := 0; i < n; i++ {
+		key := strconv.Itoa(c.r.Intn(c.keyCount))
+		if c.r.Intn(2) == 0 {
+			mops = append(mops, elle.Mop{F: elle.MopRead, Key: key})
+		} else {
+			v := atomic.AddInt64(&appendValue, 1)
+			mops = append(mops, elle.Mop{F: elle.MopAppend, Key: key, Value: int(v)})
+		}
+	}
+	return elle.TxnRequest{Mops: mops}
+}
+
+func (c *appendClient) DumpState(ctx context.Context) (interface{}, error) {
+	return nil, nil
+}
+
+// AppendClientCreator creates list-append test clients for tidb.
+type AppendClientCreator struct {
+	// KeyCount is how many keys the transactions run on, default is 10.
+	KeyCount int `json:"key_count"`
+	// TxnLength is the max count of micro operations of a transaction,
+	// default is 4.
+	TxnLength int `json:"txn_length"`
+}
+
+// Create creates a new appendClient.
+func (c AppendClientCreator) Create(node string) core.Client {
+	keyCount := c.KeyCount
+	if keyCount == 0 {
+		keyCount = 10
+	}
+	txnLength := c.TxnLength
+	if txnLength == 0 {
+		txnLength = 4
+	}
+	return &appendClient{
+		keyCount:  keyCount,
+		txnLength: txnLength,
+	}
+}
@@ -0,0 +1,48 @@
+package tidb
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/pingcap/chaos/pkg/check/elle"
+)
+
+func TestParseAppendList(t *testing.T) {
+	for _, c := range []struct {
+		val  string
+		list []int
+	}{
+		{"", []int{}},
+		{"3", []int{3}},
+		{"3,1,20", []int{3, 1, 20}},
+	} {
+		list, err := parseAppendList(c.val)
+		if err != nil || !reflect.DeepEqual(list, c.list) {
+			t.Fatalf("expected %v, got %v, err %v", c.list, list, err)
+		}
+	}
+	if _, err := parseAppendList("1,,2"); err == nil {
+		t.Fatal("an empty value must be invalid")
+	}
+}
+
+func TestAppendNextRequest(t *testing.T) {
+	c := AppendClientCreator{KeyCount: 3, TxnLength: 5}.Create("n1").(*appendClient)
+	c.r = rand.New(rand.NewSource(1))
+	seen := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		req := c.NextRequest().(elle.TxnRequest)
+		if len(req.Mops) == 0 || len(req.Mops) > 5 {
+			t.Fatalf("unexpected length of %v", req)
+		}
+		for _, m := range req.Mops {
+			if m.F == elle.MopAppend {
+				if seen[m.Value] {
+					t.Fatalf("value %d is appended twice", m.Value)
+				}
+				seen[m.Value] = true
+			}
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/anishathalye/porcupine"
 	pchecker "github.com/pingcap/chaos/pkg/check/porcupine"
+	"github.com/pingcap/chaos/pkg/check/timestamp"
 	"github.com/pingcap/chaos/pkg/core"
 	"github.com/pingcap/chaos/pkg/history"
 
@@ -55,7 +56,7 @@ func (c *bankClient) SetUp(ctx context.Context, nodes []string, node string) err
 	}
 
 	for i := 0; i < c.accountNum; i++ {
-		if _, err = db.ExecContext(ctx, "insert into accounts values (?, ?)", i, initBalance); err != nil {
+		if _, err = db.ExecContext(ctx, "insert ignore into accounts values (?, ?)", i, initBalance); err != nil {
 			return err
 		}
 	}
@@ -104,7 +105,14 @@ func (c *bankClient) Invoke(ctx context.Context, node string, r interface{}) int
 		return c.invokeRead(ctx, arg)
 	}
 
-	txn, err := c.db.Begin()
+	// Use a connection to query the commit timestamp of the transaction.
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return bankResponse{Ok: false}
+	}
+	defer conn.Close()
+
+	txn, err := conn.BeginTx(ctx, nil)
 
 	if err != nil {
 		return bankResponse{Ok: false}
@@ -145,7 +153,7 @@ func (c *bankClient) Invoke(ctx context.Context, node string, r interface{}) int
 		return bankResponse{Unknown: true, Tso: tso, FromBalance: fromBalance, ToBalance: toBalance}
 	}
 
-	return bankResponse{Ok: true, Tso: tso, FromBalance: fromBalance, ToBalance: toBalance}
+	return bankResponse{Ok: true, Tso: tso, CommitTso: lastCommitTso(ctx, conn), FromBalance: fromBalance, ToBalance: toBalance}
 }
 
 func (c *bankClient) NextRequest() interface{} {
@@ -215,6 +223,8 @@ type bankRequest struct {
 type bankResponse struct {
 	// Transaction start timestamp
 	Tso uint64
+	// Transaction commit timestamp, 0 if unknown
+	CommitTso uint64
 	// read result
 	Balances []int64
 	// transfer ok or not
@@ -234,6 +244,37 @@ func (r bankResponse) IsUnknown() bool {
 	return r.Unknown
 }
 
+var _ timestamp.Response = (*bankResponse)(nil)
+
+// Timestamps implements timestamp.Response. An unknown transfer has no
+// timestamps, as it may commit at any time after it starts, and the
+// timestamps of a transfer are lost if its commit timestamp can't be read.
+func (r bankResponse) Timestamps() (uint64, uint64, bool) {
+	if r.Unknown {
+		return 0, 0, true
+	}
+	if r.Ok && r.CommitTso == 0 {
+		return 0, 0, false
+	}
+	return r.Tso, r.CommitTso, true
+}
+
+// lastCommitTso returns the commit timestamp of the last transaction on the
+// connection, 0 if tidb doesn't support it.
+func lastCommitTso(ctx context.Context, conn *sql.Conn) uint64 {
+	var info string
+	if err := conn.QueryRowContext(ctx, "select @@tidb_last_txn_info").Scan(&info); err != nil {
+		return 0
+	}
+	var txnInfo struct {
+		CommitTs uint64 `json:"commit_ts"`
+	}
+	if err := json.Unmarshal([]byte(info), &txnInfo); err != nil {
+		return 0
+	}
+	return txnInfo.CommitTs
+}
+
 func balancesEqual(a, b []int64) bool {
 	if len(a) != len(b) {
 		return false
@@ -303,6 +344,10 @@ func (*bank) Name() string {
 	return "tidb_bank"
 }
 
+func (*bank) ReadOnly(input interface{}) bool {
+	return input.(bankRequest).Op == 0
+}
+
 // BankModel is the model of bank in TiDB
 func BankModel() core.Model {
 	return &bank{
@@ -310,6 +355,48 @@ func BankModel() core.Model {
 	}
 }
 
+// bankTotalChecker checks the total balance of every read online, the
+// transfers must never change it.
+type bankTotalChecker struct {
+	total int64
+}
+
+func (c *bankTotalChecker) Prepare(state interface{}) {
+	balances, ok := state.([]int64)
+	if !ok {
+		return
+	}
+	c.total = 0
+	for _, b := range balances {
+		c.total += b
+	}
+}
+
+func (c *bankTotalChecker) Step(req interface{}, resp interface{}) error {
+	if req.(bankRequest).Op != 0 || resp == nil {
+		return nil
+	}
+
+	var total int64
+	balances := resp.(bankResponse).Balances
+	for _, b := range balances {
+		total += b
+	}
+	if total != c.total {
+		return fmt.Errorf("read balances %v, the total is %d, but expect %d", balances, total, c.total)
+	}
+	return nil
+}
+
+func (*bankTotalChecker) Name() string {
+	return "tidb_bank_total"
+}
+
+// BankTotalChecker checks the total balance of the bank test while it runs.
+func BankTotalChecker() core.OnlineChecker {
+	return &bankTotalChecker{total: accountNum * initBalance}
+}
+
 type bankParser struct{}
 
 // OnRequest impls history.RecordParser.OnRequest
@@ -348,6 +435,93 @@ func BankParser() history.RecordParser {
 	return bankParser{}
 }
 
+type bankCodec struct{}
+
+func (bankCodec) EncodeRequest(req interface{}) (history.Keyword, interface{}, error) {
+	r := req.(bankRequest)
+	if r.Op == 0 {
+		return "read", nil, nil
+	}
+	return "transfer", history.EDNMap{
+		{Key: history.Keyword("from"), Value: int64(r.From)},
+		{Key: history.Keyword("to"), Value: int64(r.To)},
+		{Key: history.Keyword("amount"), Value: r.Amount},
+	}, nil
+}
+
+func (c bankCodec) EncodeResponse(req interface{}, resp interface{}) (history.Keyword, interface{}, error) {
+	r := resp.(bankResponse)
+	if req.(bankRequest).Op == 0 {
+		balances := make(history.EDNMap, 0, len(r.Balances))
+		for i, balance := range r.Balances {
+			balances = append(balances, history.EDNEntry{Key: int64(i), Value: balance})
+		}
+		return history.EDNOk, balances, nil
+	}
+
+	_, value, err := c.EncodeRequest(req)
+	if !r.Ok {
+		return history.EDNFail, value, err
+	}
+	return history.EDNOk, value, err
+}
+
+func (bankCodec) DecodeRequest(f history.Keyword, value interface{}) (interface{}, error) {
+	switch f {
+	case "read":
+		return bankRequest{Op: 0}, nil
+	case "transfer":
+		m, ok := value.(history.EDNMap)
+		if !ok {
+			return nil, fmt.Errorf("transfer value %v is not a map", value)
+		}
+		var args [3]int64
+		for i, name := range []history.Keyword{"from", "to", "amount"} {
+			arg, err := history.EDNInt(m.Get(name))
+			if err != nil {
+				return nil, fmt.Errorf("invalid transfer %s: %v", name, err)
+			}
+			args[i] = arg
+		}
+		return bankRequest{Op: 1, From: int(args[0]), To: int(args[1]), Amount: args[2]}, nil
+	default:
+		return nil, fmt.Errorf("unknown bank operation %s", f)
+	}
+}
+
+func (bankCodec) DecodeResponse(typ history.Keyword, req interface{}, value interface{}) (interface{}, error) {
+	if req.(bankRequest).Op == 1 {
+		return bankResponse{Ok: typ == history.EDNOk}, nil
+	}
+	if typ != history.EDNOk {
+		// A failed read reads nothing.
+		return bankResponse{Unknown: true}, nil
+	}
+
+	m, ok := value.(history.EDNMap)
+	if !ok {
+		return nil, fmt.Errorf("read value %v is not a map", value)
+	}
+	balances := make([]int64, len(m))
+	for _, e := range m {
+		account, err := history.EDNInt(e.Key)
+		if err != nil || account < 0 || account >= int64(len(m)) {
+			return nil, fmt.Errorf("invalid account %v", e.Key)
+		}
+		if balances[account], err = history.EDNInt(e.Value); err != nil {
+			return nil, err
+		}
+	}
+	return bankResponse{Balances: balances}, nil
+}
+
+// BankEDNCodec converts a history of bank operations to and from Jepsen EDN,
+// like the Jepsen bank test, a read is {:f :read, :value {0 1000, 1 1000}},
+// and a transfer is {:f :transfer, :value {:from 0, :to 1, :amount 5}}.
+func BankEDNCodec() history.EDNCodec {
+	return bankCodec{}
+}
+
 type tsoEvent struct {
 	Tso uint64
 	Op  int
@@ -390,7 +564,7 @@ func (s tsoEvents) Less(i, j int) bool { return s[i].Tso < s[j].Tso }
 func generateTsoEvents(events []porcupine.Event) tsoEvents {
 	tEvents := make(tsoEvents, 0, len(events))
 
-	mapEvents := make(map[uint]porcupine.Event, len(events))
+	mapEvents := make(map[int]porcupine.Event, len(events))
 	for _, event := range events {
 		if event.Kind == porcupine.CallEvent {
 			mapEvents[event.Id] = event
@@ -589,7 +763,7 @@ type bankTsoChecker struct {
 }
 
 // Check checks the bank history.
-func (bankTsoChecker) Check(_ core.Model, ops []core.Operation) (bool, error) {
+func (bankTsoChecker) Check(_ context.Context, _ core.Model, ops []core.Operation) (bool, error) {
 	events, err := pchecker.ConvertOperationsToEvents(ops)
 	if err != nil {
 		return false, err
@@ -603,6 +777,12 @@ func (bankTsoChecker) Name() string {
 	return "tidb_bank_tso_checker"
 }
 
+// BankTimestampChecker replays the bank history by the timestamps, it
+// completes the history with the bank parser.
+func BankTimestampChecker() core.Checker {
+	return timestamp.Checker{Parser: BankParser()}
+}
+
 // BankTsoChecker checks the bank history with the help of tso.
 func BankTsoChecker() core.Checker {
 	return bankTsoChecker{}
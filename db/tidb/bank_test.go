@@ -1,9 +1,18 @@
 package tidb
 
 import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
 	"testing"
 
 	"github.com/anishathalye/porcupine"
+	"github.com/pingcap/chaos/pkg/check/timestamp"
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
 )
 
 func checkTsoEvents(evnets []porcupine.Event) bool {
@@ -22,7 +31,7 @@ func getBankModel(accountNum int) porcupine.Model {
 	}
 }
 
-func newBankEvent(v interface{}, id uint) porcupine.Event {
+func newBankEvent(v interface{}, id int) porcupine.Event {
 	if _, ok := v.(bankRequest); ok {
 		return porcupine.Event{Kind: porcupine.CallEvent, Value: v, Id: id}
 	}
@@ -134,3 +143,137 @@ func TestBankVerifyNoLinerizable2(t *testing.T) {
 		t.Fatal("must be not linearizable")
 	}
 }
+
+func TestBankEDN(t *testing.T) {
+	ops := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 0, Data: bankRequest{Op: 1, From: 0, To: 1, Amount: 5}},
+		{Action: core.InvokeOperation, Proc: 1, Data: bankRequest{Op: 1, From: 1, To: 0, Amount: 2000}},
+		{Action: core.ReturnOperation, Proc: 0, Data: bankResponse{Ok: true}},
+		{Action: core.ReturnOperation, Proc: 1, Data: bankResponse{Ok: false}},
+		{Action: core.InvokeOperation, Proc: 2, Data: bankRequest{Op: 0}},
+		{Action: core.ReturnOperation, Proc: 2, Data: bankResponse{Balances: []int64{995, 1005}}},
+	}
+
+	var buf bytes.Buffer
+	if err := history.WriteEDN(&buf, ops, BankEDNCodec()); err != nil {
+		t.Fatalf("write edn failed %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("{:type :ok, :f :read, :value {0 995, 1 1005}")) {
+		t.Fatalf("expect a read of balances, but got %s", buf.String())
+	}
+
+	readOps, err := history.ReadEDN(&buf, BankEDNCodec())
+	if err != nil {
+		t.Fatalf("read edn failed %v", err)
+	}
+	if !reflect.DeepEqual(readOps, ops) {
+		t.Fatalf("expect %v, but got %v", ops, readOps)
+	}
+}
+
+func TestBankTotalChecker(t *testing.T) {
+	c := BankTotalChecker()
+	c.Prepare([]int64{100, 200})
+	transfer := bankRequest{Op: 1, From: 0, To: 1, Amount: 5}
+	if err := c.Step(transfer, bankResponse{Ok: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Step(bankRequest{Op: 0}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Step(bankRequest{Op: 0}, bankResponse{Balances: []int64{95, 205}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Step(bankRequest{Op: 0}, bankResponse{Balances: []int64{95, 200}}); err == nil {
+		t.Fatal("a changed total must fail")
+	}
+}
+
+func TestBankTimestampChecker(t *testing.T) {
+	transfer := bankRequest{Op: 1, From: 0, To: 1, Amount: 500}
+	ops := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 1, Data: transfer},
+		// The read starts before the transfer commits, so it doesn't see it.
+		{Action: core.InvokeOperation, Proc: 2, Data: bankRequest{Op: 0}},
+		{Action: core.ReturnOperation, Proc: 1, Data: bankResponse{Ok: true, Tso: 2, CommitTso: 4}},
+		{Action: core.ReturnOperation, Proc: 2, Data: bankResponse{Balances: []int64{1000, 1000}, Tso: 3}},
+		{Action: core.InvokeOperation, Proc: 2, Data: bankRequest{Op: 0}},
+		{Action: core.ReturnOperation, Proc: 2, Data: bankResponse{Balances: []int64{500, 1500}, Tso: 5}},
+	}
+	m := &bank{accountNum: 2}
+	ok, err := timestamp.Checker{}.Check(context.Background(), m, ops)
+	if err != nil || !ok {
+		t.Fatalf("must be valid by timestamp, err %v", err)
+	}
+
+	ops[3].Data = bankResponse{Balances: []int64{500, 1500}, Tso: 3}
+	ok, err = timestamp.Checker{}.Check(context.Background(), m, ops)
+	if err != nil || ok {
+		t.Fatalf("a read before the commit must not see the transfer, err %v", err)
+	}
+
+	// The commit timestamp of the transfer can't be read, so it is not
+	// replayed at its start timestamp.
+	ops[2].Data = bankResponse{Ok: true, Tso: 2}
+	ops[3].Data = bankResponse{Balances: []int64{1000, 1000}, Tso: 3}
+	ok, err = timestamp.Checker{}.Check(context.Background(), m, ops)
+	if err != nil || !ok {
+		t.Fatalf("a transfer without the commit timestamp must be unknown, err %v", err)
+	}
+}
+
+func TestBankTimestampCheckerHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := path.Join(dir, "history.log")
+	r, err := history.NewRecorder(name, history.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.RecordState([]int64{1000, 1000})
+	// The transfer times out, it is recorded as unknown.
+	r.RecordRequest(1, "n1", 0, bankRequest{Op: 1, From: 0, To: 1, Amount: 500})
+	r.RecordResponse(1, "n1", 0, bankResponse{Unknown: true, Tso: 2})
+	r.RecordRequest(2, "n1", 0, bankRequest{Op: 0})
+	r.RecordResponse(2, "n1", 0, bankResponse{Balances: []int64{500, 1500}, Tso: 5})
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ops, state, err := history.ReadHistory(name, BankParser())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := BankModel()
+	m.Prepare(state)
+	ok, err := BankTimestampChecker().Check(context.Background(), m, ops)
+	if err != nil || !ok {
+		t.Fatalf("the unknown transfer must take effect, err %v", err)
+	}
+
+	// The balances read are impossible whether the transfer takes effect.
+	r, err = history.NewRecorder(name, history.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.RecordState([]int64{1000, 1000})
+	r.RecordRequest(1, "n1", 0, bankRequest{Op: 1, From: 0, To: 1, Amount: 500})
+	r.RecordResponse(1, "n1", 0, bankResponse{Unknown: true, Tso: 2})
+	r.RecordRequest(2, "n1", 0, bankRequest{Op: 0})
+	r.RecordResponse(2, "n1", 0, bankResponse{Balances: []int64{600, 1400}, Tso: 5})
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ops, state, err = history.ReadHistory(name, BankParser()); err != nil {
+		t.Fatal(err)
+	}
+	m.Prepare(state)
+	ok, err = BankTimestampChecker().Check(context.Background(), m, ops)
+	if err != nil || ok {
+		t.Fatalf("the read must not be explained, err %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package tidb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/model"
+)
+
+// counterClient adds to and reads a counter, which is a row of the table
+// counter.
+type counterClient struct {
+	db       *sql.DB
+	r        *rand.Rand
+	maxDelta int
+}
+
+func (c *counterClient) SetUp(ctx context.Context, nodes []string, node string) error {
+	c.r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	db, err := sql.Open("mysql", fmt.Sprintf("root@tcp(%s:4000)/test", node))
+	if err != nil {
+		return err
+	}
+	c.db = db
+
+	db.SetMaxIdleConns(1)
+
+	// Do SetUp in the first node
+	if node != nodes[0] {
+		return nil
+	}
+
+	log.Printf("begin to create table counter on node %s", node)
+	if _, err = db.ExecContext(ctx, "drop table if exists counter"); err != nil {
+		return err
+	}
+	if _, err = db.ExecContext(ctx, "create table if not exists counter (id int not null primary key, val bigint not null)"); err != nil {
+		return err
+	}
+	if _, err = db.ExecContext(ctx, "insert into counter values (0, 0)"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *counterClient) TearDown(ctx context.Context, nodes []string, node string) error {
+	return c.db.Close()
+}
+
+func (c *counterClient) Invoke(ctx context.Context, node string, r interface{}) interface{} {
+	arg := r.(model.CounterRequest)
+	if arg.Op == model.CounterRead {
+		var val int
+		if err := c.db.QueryRowContext(ctx, "select val from counter where id = 0").Scan(&val); err != nil {
+			return model.CounterResponse{Unknown: true}
+		}
+		return model.CounterResponse{Value: val}
+	}
+
+	// The statement commits on its own, so an error may be a failed commit.
+	if _, err := c.db.ExecContext(ctx, "update counter set val = val + ? where id = 0", arg.Value); err != nil {
+		return model.CounterResponse{Unknown: true}
+	}
+	return model.CounterResponse{}
+}
+
+func (c *counterClient) NextRequest() interface{} {
+	if c.r.Intn(2) == 0 {
+		return model.CounterRequest{Op: model.CounterRead}
+	}
+	return model.CounterRequest{Op: model.CounterAdd, Value: 1 + c.r.Intn(c.maxDelta)}
+}
+
+func (c *counterClient) DumpState(ctx context.Context) (interface{}, error) {
+	var val int
+	if err := c.db.QueryRowContext(ctx, "select val from counter where id = 0").Scan(&val); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// CounterClientCreator creates counter test clients for tidb.
+type CounterClientCreator struct {
+	// MaxDelta is the max value an add adds to the counter, default is 5.
+	MaxDelta int `json:"max_delta"`
+}
+
+// Create creates a new counterClient.
+func (c CounterClientCreator) Create(node string) core.Client {
+	maxDelta := c.MaxDelta
+	if maxDelta == 0 {
+		maxDelta = 5
+	}
+	return &counterClient{
+		maxDelta: maxDelta,
+	}
+}
@@ -0,0 +1,19 @@
+package tidb
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/pingcap/chaos/pkg/model"
+)
+
+func TestCounterNextRequest(t *testing.T) {
+	c := CounterClientCreator{MaxDelta: 3}.Create("n1").(*counterClient)
+	c.r = rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		req := c.NextRequest().(model.CounterRequest)
+		if req.Op == model.CounterAdd && (req.Value < 1 || req.Value > 3) {
+			t.Fatalf("unexpected delta of %v", req)
+		}
+	}
+}
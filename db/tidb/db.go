@@ -3,6 +3,10 @@ package tidb
 import (
 	"github.com/pingcap/chaos/db/cluster"
 	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+
+	// register porcupine checker
+	_ "github.com/pingcap/chaos/pkg/check/porcupine"
 )
 
 // db is the TiDB database.
@@ -19,4 +23,64 @@ func init() {
 	core.RegisterDB(&db{
 		cluster.Cluster{IncludeTidb: true},
 	})
+
+	core.RegisterModel("tidb_bank", BankModel)
+	history.RegisterParser("tidb_bank", BankParser())
+	history.RegisterParser("tidb_long_fork", LongForkParser())
+	history.RegisterParser("tidb_sequential", NewSequentialParser())
+	history.RegisterEDNCodec("tidb_bank", BankEDNCodec())
+	history.RegisterEDNCodec("tidb_long_fork", LongForkEDNCodec())
+	core.RegisterChecker("tidb_bank_tso", BankTsoChecker)
+	core.RegisterChecker("tidb_bank_timestamp", BankTimestampChecker)
+	core.RegisterChecker("long_fork_checker", LongForkChecker)
+	core.RegisterChecker("sequential_checker", NewSequentialChecker)
+	core.RegisterOnlineChecker("tidb_bank_total", BankTotalChecker)
+	core.RegisterOnlineChecker("long_fork_checker", LongForkOnlineChecker)
+	core.RegisterOnlineChecker("sequential_checker", NewSequentialOnlineChecker)
+
+	core.RegisterWorkload(core.Workload{
+		DB:               "tidb",
+		Name:             "bank",
+		NewClientCreator: func() core.ClientCreator { return &BankClientCreator{} },
+		Model:            "tidb_bank",
+		Parser:           "tidb_bank",
+		Checkers:         []string{"porcupine"},
+	})
+	core.RegisterWorkload(core.Workload{
+		DB:               "tidb",
+		Name:             "multi_bank",
+		NewClientCreator: func() core.ClientCreator { return &MultiBankClientCreator{} },
+		Model:            "tidb_bank",
+		Parser:           "tidb_bank",
+		Checkers:         []string{"porcupine"},
+	})
+	core.RegisterWorkload(core.Workload{
+		DB:               "tidb",
+		Name:             "long_fork",
+		NewClientCreator: func() core.ClientCreator { return &LongForkClientCreator{} },
+		Parser:           "tidb_long_fork",
+		Checkers:         []string{"long_fork_checker"},
+	})
+	core.RegisterWorkload(core.Workload{
+		DB:               "tidb",
+		Name:             "append",
+		NewClientCreator: func() core.ClientCreator { return &AppendClientCreator{} },
+		Parser:           "elle_txn",
+		Checkers:         []string{"elle_list_append_si"},
+	})
+	core.RegisterWorkload(core.Workload{
+		DB:               "tidb",
+		Name:             "counter",
+		NewClientCreator: func() core.ClientCreator { return &CounterClientCreator{} },
+		Model:            "counter",
+		Parser:           "counter",
+		Checkers:         []string{"counter_bounds"},
+	})
+	core.RegisterWorkload(core.Workload{
+		DB:               "tidb",
+		Name:             "sequential",
+		NewClientCreator: func() core.ClientCreator { return &SequentialClientCreator{} },
+		Parser:           "tidb_sequential",
+		Checkers:         []string{"sequential_checker"},
+	})
 }
@@ -196,12 +196,18 @@ func makeKeysInGroup(r *rand.Rand, groupSize uint64, key uint64) []uint64 {
 
 // LongForkClientCreator creates long fork test clients for tidb.
 type LongForkClientCreator struct {
+	// TableCount is how many tables the keys are spread in, default is 7.
+	TableCount int `json:"table_count"`
 }
 
 // Create creates a new longForkClient.
-func (LongForkClientCreator) Create(node string) core.Client {
+func (c LongForkClientCreator) Create(node string) core.Client {
+	tableCount := c.TableCount
+	if tableCount == 0 {
+		tableCount = 7
+	}
 	return &longForkClient{
-		tableCount: 7,
+		tableCount: tableCount,
 		node:       node,
 	}
 }
@@ -237,9 +243,160 @@ func LongForkParser() history.RecordParser {
 	return lfParser{}
 }
 
+type lfCodec struct{}
+
+// microOps returns the micro operations of a long fork transaction, the
+// values are nil if unknown.
+func (lfCodec) microOps(req lfRequest, values []sql.NullInt64) []interface{} {
+	txn := make([]interface{}, 0, len(req.Keys))
+	for i, key := range req.Keys {
+		if req.Kind == lfWrite {
+			txn = append(txn, []interface{}{history.Keyword("w"), key, int64(1)})
+			continue
+		}
+		var value interface{}
+		if i < len(values) && values[i].Valid {
+			value = values[i].Int64
+		}
+		txn = append(txn, []interface{}{history.Keyword("r"), key, value})
+	}
+	return txn
+}
+
+func (c lfCodec) EncodeRequest(req interface{}) (history.Keyword, interface{}, error) {
+	return "txn", c.microOps(req.(lfRequest), nil), nil
+}
+
+func (c lfCodec) EncodeResponse(req interface{}, resp interface{}) (history.Keyword, interface{}, error) {
+	r := resp.(lfResponse)
+	value := c.microOps(req.(lfRequest), r.Values)
+	if !r.Ok {
+		return history.EDNFail, value, nil
+	}
+	return history.EDNOk, value, nil
+}
+
+// parseMicroOps parses the micro operations to the kind, keys and values.
+func (lfCodec) parseMicroOps(value interface{}) (string, []uint64, []sql.NullInt64, error) {
+	txn, ok := value.([]interface{})
+	if !ok {
+		return "", nil, nil, fmt.Errorf("txn %v is not a vector", value)
+	}
+	kind := lfRead
+	keys := make([]uint64, 0, len(txn))
+	values := make([]sql.NullInt64, 0, len(txn))
+	for _, v := range txn {
+		mop, ok := v.([]interface{})
+		if !ok || len(mop) != 3 {
+			return "", nil, nil, fmt.Errorf("micro operation %v is not [f key value]", v)
+		}
+		if mop[0] == history.Keyword("w") {
+			kind = lfWrite
+		}
+		key, err := history.EDNInt(mop[1])
+		if err != nil {
+			return "", nil, nil, err
+		}
+		keys = append(keys, uint64(key))
+		if val, ok := mop[2].(int64); ok {
+			values = append(values, sql.NullInt64{Int64: val, Valid: true})
+		} else {
+			values = append(values, sql.NullInt64{})
+		}
+	}
+	if kind == lfWrite && len(keys) != 1 {
+		return "", nil, nil, fmt.Errorf("a long fork write should write one key, but it has %d", len(keys))
+	}
+	return kind, keys, values, nil
+}
+
+func (c lfCodec) DecodeRequest(f history.Keyword, value interface{}) (interface{}, error) {
+	kind, keys, _, err := c.parseMicroOps(value)
+	if err != nil {
+		return nil, err
+	}
+	return lfRequest{Kind: kind, Keys: keys}, nil
+}
+
+func (c lfCodec) DecodeResponse(typ history.Keyword, req interface{}, value interface{}) (interface{}, error) {
+	if typ != history.EDNOk {
+		return lfResponse{Ok: false}, nil
+	}
+	kind, keys, values, err := c.parseMicroOps(value)
+	if err != nil {
+		return nil, err
+	}
+	if kind == lfWrite {
+		// The checker tells a write by its empty values.
+		values = []sql.NullInt64{}
+	}
+	return lfResponse{Ok: true, Keys: keys, Values: values}, nil
+}
+
+// LongForkEDNCodec converts a history of long fork test to and from Jepsen
+// EDN, every operation is a :txn of [:r key value] or [:w key 1] micro
+// operations, a missing value is read as nil.
+func LongForkEDNCodec() history.EDNCodec {
+	return lfCodec{}
+}
+
 type lfChecker struct{}
 
-func ensureNoLongForks(ops []core.Operation, groupSize int) (bool, error) {
+// sortedRead returns the keys and values of a read sorted by key.
+func sortedRead(res lfResponse, groupSize int) ([]uint64, []sql.NullInt64, error) {
+	if len(res.Keys) != groupSize || len(res.Values) != groupSize {
+		return nil, nil, fmt.Errorf("The read respond should have %v keys and %v values, but it has %v keys and %v values",
+			groupSize, groupSize, len(res.Keys), len(res.Values))
+	}
+	type pair struct {
+		key   uint64
+		value sql.NullInt64
+	}
+	//sort key
+	pairs := make([]pair, groupSize)
+	for i := 0; i < groupSize; i++ {
+		pairs[i] = pair{key: res.Keys[i], value: res.Values[i]}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].key < pairs[j].key })
+	keys := make([]uint64, groupSize)
+	values := make([]sql.NullInt64, groupSize)
+	for i := 0; i < groupSize; i++ {
+		keys[i] = pairs[i].key
+		values[i] = pairs[i].value
+	}
+	return keys, values, nil
+}
+
+// isForked tells whether two reads of the keys see the writes in different
+// orders, each sees a write which the other does not.
+func isForked(keys []uint64, values1 []sql.NullInt64, values2 []sql.NullInt64) (bool, error) {
+	var result int
+	for i := range keys {
+		present1 := values1[i].Valid
+		present2 := values2[i].Valid
+		if present1 && !present2 {
+			if result > 0 {
+				return true, nil
+			}
+			result = -1
+		}
+		if !present1 && present2 {
+			if result < 0 {
+				return true, nil
+			}
+			result = 1
+		}
+		if present1 && present2 {
+			if values1[i] != values2[i] {
+				return false, fmt.Errorf("The key %v was write twice since it had two different values %v and %v",
+					keys[i], values1[i], values2[i])
+			}
+		}
+	}
+	return false, nil
+}
+
+func ensureNoLongForks(ctx context.Context, ops []core.Operation, groupSize int) (bool, error) {
 	// why we cannot have something like map<vec<T>,T> in golang?
 	keyset := make(map[string][]uint64)
 	groups := make(map[string][][]sql.NullInt64)
@@ -256,25 +413,9 @@ func ensureNoLongForks(ops []core.Operation, groupSize int) (bool, error) {
 		if !res.Ok || res.Unknown {
 			continue
 		}
-		if len(res.Keys) != groupSize || len(res.Values) != groupSize {
-			return false, fmt.Errorf("The read respond should have %v keys and %v values, but it has %v keys and %v values",
-				groupSize, groupSize, len(res.Keys), len(res.Values))
-		}
-		type pair struct {
-			key   uint64
-			value sql.NullInt64
-		}
-		//sort key
-		pairs := make([]pair, groupSize)
-		for i := 0; i < groupSize; i++ {
-			pairs[i] = pair{key: res.Keys[i], value: res.Values[i]}
-		}
-		sort.Slice(pairs, func(i, j int) bool { return pairs[i].key < pairs[j].key })
-		keys := make([]uint64, groupSize)
-		values := make([]sql.NullInt64, groupSize)
-		for i := 0; i < groupSize; i++ {
-			keys[i] = pairs[i].key
-			values[i] = pairs[i].value
+		keys, values, err := sortedRead(res, groupSize)
+		if err != nil {
+			return false, err
 		}
 		str := fmt.Sprintf("%v", keys)
 		groups[str] = append(groups[str], values)
@@ -284,34 +425,20 @@ func ensureNoLongForks(ops []core.Operation, groupSize int) (bool, error) {
 		keys := keyset[str]
 		count := len(results)
 		for p := 0; p < count; p++ {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
 			for q := p + 1; q < count; q++ {
 				values1 := results[p]
 				values2 := results[q]
 				//compare!
-				var result int
-				for i := 0; i < groupSize; i++ {
-					present1 := values1[i].Valid
-					present2 := values2[i].Valid
-					if present1 && !present2 {
-						if result > 0 {
-							log.Printf("Detected fork in history, read to %v returns %v and %v", keys, values1, values2)
-							return false, nil
-						}
-						result = -1
-					}
-					if !present1 && present2 {
-						if result < 0 {
-							log.Printf("Detected fork in history, read to %v returns %v and %v", keys, values1, values2)
-							return false, nil
-						}
-						result = 1
-					}
-					if present1 && present2 {
-						if values1[i] != values2[i] {
-							return false, fmt.Errorf("The key %v was write twice since it had two different values %v and %v",
-								keys[i], values1[i], values2[i])
-						}
-					}
+				forked, err := isForked(keys, values1, values2)
+				if err != nil {
+					return false, err
+				}
+				if forked {
+					log.Printf("Detected fork in history, read to %v returns %v and %v", keys, values1, values2)
+					return false, nil
 				}
 			}
 		}
@@ -339,13 +466,13 @@ func ensureNoMultipleWritesToOneKey(ops []core.Operation) (bool, error) {
 	return true, nil
 }
 
-func (lfChecker) Check(_ core.Model, ops []core.Operation) (bool, error) {
+func (lfChecker) Check(ctx context.Context, _ core.Model, ops []core.Operation) (bool, error) {
 	if ok, err := ensureNoMultipleWritesToOneKey(ops); err != nil {
 		return false, err
 	} else if !ok {
 		return false, nil
 	}
-	if ok, err := ensureNoLongForks(ops, lfGroupSize); err != nil {
+	if ok, err := ensureNoLongForks(ctx, ops, lfGroupSize); err != nil {
 		return false, err
 	} else if !ok {
 		return false, nil
@@ -361,3 +488,61 @@ func (lfChecker) Name() string {
 func LongForkChecker() core.Checker {
 	return lfChecker{}
 }
+
+// lfOnlineChecker checks every completed operation of the long fork test,
+// a read is compared with the earlier reads of the same keys.
+type lfOnlineChecker struct {
+	written map[uint64]bool
+	reads   map[string][][]sql.NullInt64
+}
+
+func (c *lfOnlineChecker) Prepare(_ interface{}) {}
+
+func (c *lfOnlineChecker) Step(req interface{}, resp interface{}) error {
+	r := req.(lfRequest)
+	if r.Kind == lfWrite {
+		for _, key := range r.Keys {
+			if c.written[key] {
+				return fmt.Errorf("The key %v was written twice", key)
+			}
+			c.written[key] = true
+		}
+		return nil
+	}
+
+	if resp == nil {
+		return nil
+	}
+	res := resp.(lfResponse)
+	if !res.Ok {
+		return nil
+	}
+	keys, values, err := sortedRead(res, lfGroupSize)
+	if err != nil {
+		return err
+	}
+	str := fmt.Sprintf("%v", keys)
+	for _, earlier := range c.reads[str] {
+		forked, err := isForked(keys, earlier, values)
+		if err != nil {
+			return err
+		}
+		if forked {
+			return fmt.Errorf("read to %v returns %v and %v", keys, earlier, values)
+		}
+	}
+	c.reads[str] = append(c.reads[str], values)
+	return nil
+}
+
+func (c *lfOnlineChecker) Name() string {
+	return "tidb_long_fork_checker"
+}
+
+// LongForkOnlineChecker checks the long fork test while it runs.
+func LongForkOnlineChecker() core.OnlineChecker {
+	return &lfOnlineChecker{
+		written: make(map[uint64]bool),
+		reads:   make(map[string][][]sql.NullInt64),
+	}
+}
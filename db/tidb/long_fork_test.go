@@ -1,10 +1,14 @@
 package tidb
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"reflect"
 	"testing"
 
 	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
 )
 
 func TestCheckLongFork(t *testing.T) {
@@ -33,12 +37,74 @@ func TestCheckLongFork(t *testing.T) {
 		core.Operation{Action: core.ReturnOperation, Proc: 1, Data: lfResponse{Ok: true, Keys: []uint64{0, 1, 2}, Values: []sql.NullInt64{sql.NullInt64{Valid: false}, sql.NullInt64{Valid: false}, sql.NullInt64{Valid: true, Int64: 1}}}},
 		core.Operation{Action: core.ReturnOperation, Proc: 0, Data: lfResponse{Ok: true, Keys: []uint64{1, 2, 0}, Values: []sql.NullInt64{sql.NullInt64{Valid: true, Int64: 1}, sql.NullInt64{Valid: false}, sql.NullInt64{Valid: false}}}},
 	}
-	ok, err := ensureNoLongForks(good, 3)
+	ok, err := ensureNoLongForks(context.Background(), good, 3)
 	if !ok || err != nil {
 		t.Fatalf("good must pass check")
 	}
-	ok, err = ensureNoLongForks(bad, 3)
+	ok, err = ensureNoLongForks(context.Background(), bad, 3)
 	if ok {
 		t.Fatalf("bad must fail check")
 	}
 }
+
+func TestLongForkEDN(t *testing.T) {
+	ops := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 0, Data: lfRequest{Kind: lfWrite, Keys: []uint64{1}}},
+		{Action: core.ReturnOperation, Proc: 0, Data: lfResponse{Ok: true, Keys: []uint64{1}, Values: []sql.NullInt64{}}},
+		{Action: core.InvokeOperation, Proc: 1, Data: lfRequest{Kind: lfRead, Keys: []uint64{1, 2}}},
+		{Action: core.ReturnOperation, Proc: 1, Data: lfResponse{Ok: true, Keys: []uint64{1, 2}, Values: []sql.NullInt64{{Int64: 1, Valid: true}, {}}}},
+	}
+
+	var buf bytes.Buffer
+	if err := history.WriteEDN(&buf, ops, LongForkEDNCodec()); err != nil {
+		t.Fatalf("write edn failed %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("{:type :ok, :f :txn, :value [[:r 1 1] [:r 2 nil]]")) {
+		t.Fatalf("expect a read txn, but got %s", buf.String())
+	}
+
+	readOps, err := history.ReadEDN(&buf, LongForkEDNCodec())
+	if err != nil {
+		t.Fatalf("read edn failed %v", err)
+	}
+	if !reflect.DeepEqual(readOps, ops) {
+		t.Fatalf("expect %v, but got %v", ops, readOps)
+	}
+}
+
+func TestLongForkOnlineChecker(t *testing.T) {
+	keys := []uint64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	read := func(present ...int) lfResponse {
+		values := make([]sql.NullInt64, len(keys))
+		for _, i := range present {
+			values[i] = sql.NullInt64{Int64: 1, Valid: true}
+		}
+		return lfResponse{Ok: true, Keys: keys, Values: values}
+	}
+
+	c := LongForkOnlineChecker()
+	steps := []struct {
+		req  lfRequest
+		resp interface{}
+	}{
+		{lfRequest{Kind: lfWrite, Keys: []uint64{0}}, lfResponse{Ok: true}},
+		{lfRequest{Kind: lfRead, Keys: keys}, read()},
+		{lfRequest{Kind: lfRead, Keys: keys}, read(0)},
+		{lfRequest{Kind: lfWrite, Keys: []uint64{1}}, nil},
+		{lfRequest{Kind: lfRead, Keys: keys}, read(0, 1)},
+	}
+	for i, s := range steps {
+		if err := c.Step(s.req, s.resp); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+	if err := c.Step(lfRequest{Kind: lfRead, Keys: keys}, read(1)); err == nil {
+		t.Fatal("a forked read must fail")
+	}
+
+	c = LongForkOnlineChecker()
+	c.Step(lfRequest{Kind: lfWrite, Keys: []uint64{0}}, nil)
+	if err := c.Step(lfRequest{Kind: lfWrite, Keys: []uint64{0}}, nil); err == nil {
+		t.Fatal("writing a key twice must fail")
+	}
+}
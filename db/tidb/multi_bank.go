@@ -43,7 +43,7 @@ func (c *multiBankClient) SetUp(ctx context.Context, nodes []string, node string
 			return err
 		}
 
-		sql = fmt.Sprintf("insert into accounts_%d values (?, ?)", i)
+		sql = fmt.Sprintf("insert ignore into accounts_%d values (?, ?)", i)
 		if _, err = db.ExecContext(ctx, sql, i, initBalance); err != nil {
 			return err
 		}
@@ -89,7 +89,14 @@ func (c *multiBankClient) Invoke(ctx context.Context, node string, r interface{}
 		return c.invokeRead(ctx, arg)
 	}
 
-	txn, err := c.db.Begin()
+	// Use a connection to query the commit timestamp of the transaction.
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return bankResponse{Ok: false}
+	}
+	defer conn.Close()
+
+	txn, err := conn.BeginTx(ctx, nil)
 
 	if err != nil {
 		return bankResponse{Ok: false}
@@ -130,7 +137,7 @@ func (c *multiBankClient) Invoke(ctx context.Context, node string, r interface{}
 		return bankResponse{Unknown: true, Tso: tso, FromBalance: fromBalance, ToBalance: toBalance}
 	}
 
-	return bankResponse{Ok: true, Tso: tso, FromBalance: fromBalance, ToBalance: toBalance}
+	return bankResponse{Ok: true, Tso: tso, CommitTso: lastCommitTso(ctx, conn), FromBalance: fromBalance, ToBalance: toBalance}
 }
 
 func (c *multiBankClient) NextRequest() interface{} {
@@ -64,6 +64,10 @@ type sequentialClient struct {
 
 // SequentialClientCreator creates a bank test client for tidb.
 type SequentialClientCreator struct {
+	// TableCount is how many tables the keys are spread in, default is 3.
+	TableCount int `json:"table_count"`
+	// KeyCount is how many sub keys a request accesses, default is 5.
+	KeyCount int `json:"key_count"`
 }
 
 func genRequest() interface{} {
@@ -101,10 +105,17 @@ WRITE:
 }
 
 // Create creates a new SequentialClient.
-func (SequentialClientCreator) Create(node string) core.Client {
+func (c SequentialClientCreator) Create(node string) core.Client {
+	tableCount, keyCount := c.TableCount, c.KeyCount
+	if tableCount == 0 {
+		tableCount = 3
+	}
+	if keyCount == 0 {
+		keyCount = 5
+	}
 	return &sequentialClient{
-		tableCount: 3,
-		keyCount:   5,
+		tableCount: tableCount,
+		keyCount:   keyCount,
 		gen:        generator.Stagger(time.Millisecond*10, genRequest),
 	}
 }
@@ -233,31 +244,58 @@ func NewSequentialChecker() core.Checker {
 type sequentialChecker struct{}
 
 // Check checks the sequential history.
-func (sequentialChecker) Check(_ core.Model, ops []core.Operation) (bool, error) {
+func (sequentialChecker) Check(_ context.Context, _ core.Model, ops []core.Operation) (bool, error) {
 	for _, op := range ops {
 		if op.Action == core.ReturnOperation {
 			resp := op.Data.(seqResponse)
-			if !resp.Unknown && resp.Ok {
-				foundNoneEmpty := false
-				for _, s := range resp.V {
-					if !foundNoneEmpty {
-						foundNoneEmpty = s != ""
-					} else if s == "" {
-						log.Printf("Find no sequential op %+v", resp)
-						return false, nil
-					}
-				}
+			if !resp.Unknown && resp.Ok && !isSequential(resp) {
+				log.Printf("Find no sequential op %+v", resp)
+				return false, nil
 			}
 		}
 	}
 	return true, nil
 }
 
+// isSequential tells whether the read sees the sub keys in the order they
+// are written, the sub keys are read in the reverse order, so once a sub key
+// is seen, all the sub keys read after it must be seen.
+func isSequential(resp seqResponse) bool {
+	foundNoneEmpty := false
+	for _, s := range resp.V {
+		if !foundNoneEmpty {
+			foundNoneEmpty = s != ""
+		} else if s == "" {
+			return false
+		}
+	}
+	return true
+}
+
 // Name returns the name of the verifier.
 func (sequentialChecker) Name() string {
 	return "sequential_checker"
 }
 
+// NewSequentialOnlineChecker returns a new online sequentialChecker.
+func NewSequentialOnlineChecker() core.OnlineChecker {
+	return sequentialChecker{}
+}
+
+// Prepare implements core.OnlineChecker.
+func (sequentialChecker) Prepare(_ interface{}) {}
+
+// Step checks the sequential read online.
+func (sequentialChecker) Step(_ interface{}, resp interface{}) error {
+	if resp == nil {
+		return nil
+	}
+	if r := resp.(seqResponse); r.Ok && !isSequential(r) {
+		return fmt.Errorf("no sequential op %+v", r)
+	}
+	return nil
+}
+
 type parser struct{}
 
 // OnRequest impls history.RecordParser.OnRequest
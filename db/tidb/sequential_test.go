@@ -1,6 +1,7 @@
 package tidb
 
 import (
+	"context"
 	"testing"
 
 	"github.com/pingcap/chaos/pkg/core"
@@ -34,12 +35,25 @@ func TestCheckSequential(t *testing.T) {
 		core.Operation{Action: core.ReturnOperation, Proc: 2, Data: seqResponse{Ok: true, K: 2, V: []string{"2", "2", "2"}}},
 	}
 	checker := NewSequentialChecker()
-	ok, err := checker.Check(nil, good)
+	ok, err := checker.Check(context.Background(), nil, good)
 	if !ok || err != nil {
 		t.Fatalf("good must pass check")
 	}
-	ok, err = checker.Check(nil, bad)
+	ok, err = checker.Check(context.Background(), nil, bad)
 	if ok {
 		t.Fatalf("bad must fail check")
 	}
 }
+
+func TestSequentialOnlineChecker(t *testing.T) {
+	c := NewSequentialOnlineChecker()
+	if err := c.Step(seqRequest{}, seqResponse{Ok: true, K: 1, V: []string{"", "1", "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Step(seqRequest{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Step(seqRequest{}, seqResponse{Ok: true, K: 0, V: []string{"0", "", "0"}}); err == nil {
+		t.Fatal("a read out of order must fail")
+	}
+}
@@ -3,6 +3,10 @@ package txnkv
 import (
 	"github.com/pingcap/chaos/db/cluster"
 	"github.com/pingcap/chaos/pkg/core"
+
+	// register porcupine checker, register model and parser
+	_ "github.com/pingcap/chaos/pkg/check/porcupine"
+	_ "github.com/pingcap/chaos/pkg/model"
 )
 
 // db is the transactional KV database.
@@ -20,4 +24,13 @@ func init() {
 		// TxnKV does not use TiDB.
 		cluster.Cluster{IncludeTidb: false},
 	})
+
+	core.RegisterWorkload(core.Workload{
+		DB:               "txnkv",
+		Name:             "register",
+		NewClientCreator: func() core.ClientCreator { return &RegisterClientCreator{} },
+		Model:            "register",
+		Parser:           "register",
+		Checkers:         []string{"porcupine"},
+	})
 }
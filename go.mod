@@ -3,11 +3,13 @@ module github.com/pingcap/chaos
 go 1.12
 
 require (
-	github.com/anishathalye/porcupine v0.0.0-20190205033716-f6fec466e840
+	github.com/BurntSushi/toml v0.3.1
+	github.com/anishathalye/porcupine v0.1.4
 	github.com/go-sql-driver/mysql v1.4.1
 	github.com/golang/snappy v0.0.1 // indirect
 	github.com/google/btree v1.0.0 // indirect
 	github.com/juju/errors v0.0.0-20190207033735-e65537c515d7 // indirect
+	github.com/klauspost/compress v1.10.3
 	github.com/opentracing/opentracing-go v1.1.0 // indirect
 	github.com/pingcap/tidb v0.0.0-20190710093938-f409f0b4cfae
 	github.com/pingcap/tipb v0.0.0-20190708032835-0c0ce040d91b // indirect
@@ -16,7 +18,9 @@ require (
 	github.com/spaolacci/murmur3 v1.1.0 // indirect
 	github.com/uber/jaeger-client-go v2.16.0+incompatible // indirect
 	github.com/uber/jaeger-lib v2.0.0+incompatible // indirect
+	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2
 	golang.org/x/net v0.0.0-20190628185345-da137c7871d7 // indirect
 	golang.org/x/text v0.3.2 // indirect
 	google.golang.org/grpc v1.22.0 // indirect
+	gopkg.in/yaml.v2 v2.2.2
 )
@@ -0,0 +1,551 @@
+// Package elle checks transactional histories like Elle: the dependencies
+// between the transactions are inferred from the values they read, and the
+// anomalies of Adya's consistency models are reported with the transactions
+// as evidence.
+//
+// A list-append transaction appends unique values to lists and reads the
+// whole lists, so the order of the versions of every key is known from the
+// longest read. A rw-register transaction writes unique values to registers
+// and reads them, the order of the versions is only known when a transaction
+// reads a register and then writes it.
+package elle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+// Types of anomalies.
+const (
+	// G0 is a cycle of ww dependencies, a dirty write.
+	G0 = "G0"
+	// G1a is a read of a version written by an aborted transaction.
+	G1a = "G1a"
+	// G1b is a read of a version which is overwritten by the same
+	// transaction later, an intermediate read.
+	G1b = "G1b"
+	// G1c is a cycle of ww and wr dependencies, a circular information flow.
+	G1c = "G1c"
+	// GSingle is a cycle with exactly one rw dependency, a read skew.
+	GSingle = "G-single"
+	// G2 is a cycle with more than one rw dependencies, a write skew.
+	G2 = "G2"
+	// IncompatibleOrder is a read of a list which is not a prefix of the
+	// longest read of the list, so the lists have no order of versions.
+	IncompatibleOrder = "incompatible-order"
+)
+
+// Consistency is a consistency model, which prohibits some anomalies.
+type Consistency string
+
+// Consistency models.
+const (
+	Serializable      Consistency = "serializable"
+	SnapshotIsolation Consistency = "snapshot_isolation"
+	ReadCommitted     Consistency = "read_committed"
+)
+
+// prohibited are the anomalies prohibited by the consistency models.
+var prohibited = map[Consistency][]string{
+	Serializable:      {G0, G1a, G1b, G1c, GSingle, G2, IncompatibleOrder},
+	SnapshotIsolation: {G0, G1a, G1b, G1c, GSingle, IncompatibleOrder},
+	ReadCommitted:     {G0, G1a, G1b, G1c, IncompatibleOrder},
+}
+
+// Statuses of transactions.
+const (
+	TxnOk   = "ok"
+	TxnFail = "fail"
+	// TxnInfo means the transaction may or may not commit.
+	TxnInfo = "info"
+)
+
+// Txn is a transaction of the history, it is numbered by its invoke.
+type Txn struct {
+	ID     int    `json:"id"`
+	Proc   int64  `json:"proc"`
+	Status string `json:"status"`
+	// Mops are the micro operations of the response if the transaction
+	// commits, otherwise the ones of the request.
+	Mops []Mop `json:"mops"`
+}
+
+func (t Txn) String() string {
+	mops := make([]string, 0, len(t.Mops))
+	for _, m := range t.Mops {
+		mops = append(mops, m.String())
+	}
+	return fmt.Sprintf("T%d (proc %d, %s) [%s]", t.ID, t.Proc, t.Status, strings.Join(mops, ", "))
+}
+
+// Anomaly is an anomaly found in the history.
+type Anomaly struct {
+	Type string `json:"type"`
+	// Cycle is the dependencies of the cycle of transactions, for G0, G1c,
+	// G-single and G2.
+	Cycle []Dep `json:"cycle,omitempty"`
+	// Txns are the transactions involved.
+	Txns    []Txn  `json:"txns"`
+	Message string `json:"message"`
+}
+
+func (a Anomaly) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", a.Type, a.Message)
+	for _, t := range a.Txns {
+		fmt.Fprintf(&b, "\n  %s", t)
+	}
+	return b.String()
+}
+
+// Analysis is the transactions and the anomalies of a history.
+type Analysis struct {
+	Txns      []Txn
+	Anomalies []Anomaly
+}
+
+func (a *Analysis) add(typ string, txns []int, cycle []Dep, format string, args ...interface{}) {
+	an := Anomaly{
+		Type:    typ,
+		Cycle:   cycle,
+		Message: fmt.Sprintf(format, args...),
+	}
+	for _, id := range txns {
+		an.Txns = append(an.Txns, a.Txns[id])
+	}
+	a.Anomalies = append(a.Anomalies, an)
+}
+
+// committed tells whether the transaction commits, a transaction with an
+// unknown result commits if any of its writes is read.
+func (a *Analysis) committed(id int, observed map[int]bool) bool {
+	t := a.Txns[id]
+	return t.Status == TxnOk || (t.Status == TxnInfo && observed[id])
+}
+
+// buildTxns pairs the invokes and returns of the completed operations.
+func buildTxns(ops []core.Operation) ([]Txn, error) {
+	var txns []Txn
+	pending := make(map[int64]int)
+	for _, op := range ops {
+		if op.Action == core.InvokeOperation {
+			req, ok := op.Data.(TxnRequest)
+			if !ok {
+				return nil, fmt.Errorf("%v is not a transaction", op.Data)
+			}
+			pending[op.Proc] = len(txns)
+			txns = append(txns, Txn{ID: len(txns), Proc: op.Proc, Status: TxnInfo, Mops: req.Mops})
+			continue
+		}
+
+		id, ok := pending[op.Proc]
+		if !ok {
+			return nil, fmt.Errorf("missing invoke, op: %v", op)
+		}
+		delete(pending, op.Proc)
+		resp, ok := op.Data.(TxnResponse)
+		if !ok || resp.Unknown {
+			continue
+		}
+		if resp.Ok {
+			txns[id].Status = TxnOk
+			txns[id].Mops = resp.Mops
+		} else {
+			txns[id].Status = TxnFail
+		}
+	}
+	return txns, nil
+}
+
+// version is a value of a key.
+type version struct {
+	key   string
+	value int
+}
+
+// txnKey is a key written by a transaction.
+type txnKey struct {
+	id  int
+	key string
+}
+
+// AnalyzeListAppend finds the anomalies of the list-append history.
+func AnalyzeListAppend(ctx context.Context, ops []core.Operation) (*Analysis, error) {
+	txns, err := buildTxns(ops)
+	if err != nil {
+		return nil, err
+	}
+	a := &Analysis{Txns: txns}
+
+	writer := make(map[version]int)
+	final := make(map[txnKey]int)
+	for _, t := range txns {
+		for _, m := range t.Mops {
+			if m.F == MopAppend {
+				writer[version{m.Key, m.Value}] = t.ID
+				final[txnKey{t.ID, m.Key}] = m.Value
+			}
+		}
+	}
+
+	// The longest read of every key is the order of its versions.
+	orders := make(map[string][]int)
+	forEachRead(txns, func(t Txn, m Mop) {
+		if len(m.List) > len(orders[m.Key]) {
+			orders[m.Key] = m.List
+		}
+	})
+
+	incompatible := make(map[string]bool)
+	forEachRead(txns, func(t Txn, m Mop) {
+		order := orders[m.Key]
+		if !incompatible[m.Key] && !isPrefix(m.List, order) {
+			incompatible[m.Key] = true
+			a.add(IncompatibleOrder, []int{t.ID}, nil, "T%d reads %s %v, which is not a prefix of %v", t.ID, m.Key, m.List, order)
+		}
+		for _, v := range m.List {
+			if w, ok := writer[version{m.Key, v}]; ok && txns[w].Status == TxnFail {
+				a.add(G1a, []int{w, t.ID}, nil, "T%d reads %s %d appended by the aborted T%d", t.ID, m.Key, v, w)
+			}
+		}
+		if len(m.List) == 0 {
+			return
+		}
+		last := m.List[len(m.List)-1]
+		if w, ok := writer[version{m.Key, last}]; ok && w != t.ID && final[txnKey{w, m.Key}] != last {
+			a.add(G1b, []int{w, t.ID}, nil, "T%d reads %s %d, which is not the last append of T%d to it", t.ID, m.Key, last, w)
+		}
+	})
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	observed := make(map[int]bool)
+	for key, order := range orders {
+		for _, v := range order {
+			if w, ok := writer[version{key, v}]; ok {
+				observed[w] = true
+			}
+		}
+	}
+	// writerOf returns the committed writer of the version.
+	writerOf := func(key string, v int) (int, bool) {
+		w, ok := writer[version{key, v}]
+		return w, ok && a.committed(w, observed)
+	}
+
+	g := newGraph()
+	for _, key := range sortedKeys(orders) {
+		order := orders[key]
+		for i := 0; i+1 < len(order); i++ {
+			w1, ok1 := writerOf(key, order[i])
+			w2, ok2 := writerOf(key, order[i+1])
+			if ok1 && ok2 {
+				g.add(w1, w2, WW, key)
+			}
+		}
+	}
+	forEachRead(txns, func(t Txn, m Mop) {
+		n := len(m.List)
+		if n != 0 {
+			if w, ok := writerOf(m.Key, m.List[n-1]); ok {
+				g.add(w, t.ID, WR, m.Key)
+			}
+		}
+		// The next version overwrites the read one.
+		if order := orders[m.Key]; n < len(order) {
+			if w, ok := writerOf(m.Key, order[n]); ok {
+				g.add(t.ID, w, RW, m.Key)
+			}
+		}
+	})
+
+	if err = a.findCycles(ctx, g); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// AnalyzeRWRegister finds the anomalies of the rw-register history.
+func AnalyzeRWRegister(ctx context.Context, ops []core.Operation) (*Analysis, error) {
+	txns, err := buildTxns(ops)
+	if err != nil {
+		return nil, err
+	}
+	a := &Analysis{Txns: txns}
+
+	writer := make(map[version]int)
+	final := make(map[txnKey]int)
+	for _, t := range txns {
+		for _, m := range t.Mops {
+			if m.F == MopWrite {
+				writer[version{m.Key, m.Value}] = t.ID
+				final[txnKey{t.ID, m.Key}] = m.Value
+			}
+		}
+	}
+
+	// The reads of a transaction before it writes the key, the reads after
+	// its own writes tell nothing of the other transactions.
+	readers := make(map[version][]int)
+	// A transaction which reads a version and then writes the key installs
+	// the next version.
+	next := make(map[version][]int)
+	for _, t := range txns {
+		if t.Status != TxnOk {
+			continue
+		}
+		read := make(map[string]int)
+		var readKeys []string
+		written := make(map[string]bool)
+		for _, m := range t.Mops {
+			if m.F == MopWrite {
+				written[m.Key] = true
+				continue
+			}
+			if m.F != MopRead || written[m.Key] {
+				continue
+			}
+			if _, ok := read[m.Key]; ok {
+				continue
+			}
+			read[m.Key] = m.Value
+			readKeys = append(readKeys, m.Key)
+			v := version{m.Key, m.Value}
+			readers[v] = append(readers[v], t.ID)
+			if m.Value == 0 {
+				continue
+			}
+			w, ok := writer[v]
+			if !ok {
+				continue
+			}
+			if txns[w].Status == TxnFail {
+				a.add(G1a, []int{w, t.ID}, nil, "T%d reads %s %d written by the aborted T%d", t.ID, m.Key, m.Value, w)
+			} else if w != t.ID && final[txnKey{w, m.Key}] != m.Value {
+				a.add(G1b, []int{w, t.ID}, nil, "T%d reads %s %d, which is not the last write of T%d to it", t.ID, m.Key, m.Value, w)
+			}
+		}
+		for _, key := range readKeys {
+			if written[key] {
+				v := version{key, read[key]}
+				next[v] = append(next[v], final[txnKey{t.ID, key}])
+			}
+		}
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	observed := make(map[int]bool)
+	for v := range readers {
+		if w, ok := writer[v]; ok {
+			observed[w] = true
+		}
+	}
+	writerOf := func(v version) (int, bool) {
+		w, ok := writer[v]
+		return w, ok && a.committed(w, observed)
+	}
+	// The committed writers of every key.
+	writers := make(map[string][]int)
+	for k, v := range final {
+		if writer[version{k.key, v}] == k.id && a.committed(k.id, observed) {
+			writers[k.key] = append(writers[k.key], k.id)
+		}
+	}
+	for _, ids := range writers {
+		sort.Ints(ids)
+	}
+
+	g := newGraph()
+	for _, v := range sortedVersions(readers) {
+		if w, ok := writerOf(v); ok {
+			for _, r := range readers[v] {
+				g.add(w, r, WR, v.key)
+			}
+		}
+		// Every version is after the initial one.
+		if v.value == 0 {
+			for _, r := range readers[v] {
+				for _, w := range writers[v.key] {
+					g.add(r, w, RW, v.key)
+				}
+			}
+		}
+	}
+	for _, v := range sortedVersions(next) {
+		w1, ok1 := writerOf(v)
+		for _, value := range next[v] {
+			w2, ok2 := writerOf(version{v.key, value})
+			if !ok2 {
+				continue
+			}
+			if ok1 {
+				g.add(w1, w2, WW, v.key)
+			}
+			for _, r := range readers[v] {
+				g.add(r, w2, RW, v.key)
+			}
+		}
+	}
+
+	if err = a.findCycles(ctx, g); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// findCycles finds a cycle of every anomaly in every strongly connected
+// component of the dependency graph.
+func (a *Analysis) findCycles(ctx context.Context, g *graph) error {
+	addCycle := func(typ string, cycle []Dep) {
+		ids := make([]int, 0, len(cycle))
+		for _, d := range cycle {
+			ids = append(ids, d.From)
+		}
+		a.add(typ, ids, cycle, "%s", formatCycle(cycle))
+	}
+
+	for _, scc := range g.sccs(WW) {
+		if cycle := g.findCycle(scc, WW, WW); cycle != nil {
+			addCycle(G0, cycle)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, scc := range g.sccs(WW | WR) {
+		if cycle := g.findCycle(scc, WR, WW|WR); cycle != nil {
+			addCycle(G1c, cycle)
+		}
+	}
+	for _, scc := range g.sccs(WW | WR | RW) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if cycle := g.findCycle(scc, RW, WW|WR); cycle != nil {
+			addCycle(GSingle, cycle)
+		} else if cycle := g.findCycle(scc, RW, WW|WR|RW); cycle != nil {
+			addCycle(G2, cycle)
+		}
+	}
+	return nil
+}
+
+// forEachRead calls f with every read of the committed transactions.
+func forEachRead(txns []Txn, f func(t Txn, m Mop)) {
+	for _, t := range txns {
+		if t.Status != TxnOk {
+			continue
+		}
+		for _, m := range t.Mops {
+			if m.F == MopRead {
+				f(t, m)
+			}
+		}
+	}
+}
+
+func isPrefix(list []int, of []int) bool {
+	if len(list) > len(of) {
+		return false
+	}
+	for i, v := range list {
+		if of[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string][]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedVersions(m map[version][]int) []version {
+	versions := make([]version, 0, len(m))
+	for v := range m {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if versions[i].key != versions[j].key {
+			return versions[i].key < versions[j].key
+		}
+		return versions[i].value < versions[j].value
+	})
+	return versions
+}
+
+// Checker checks a transactional history and fails if any of the prohibited
+// anomalies is found.
+type Checker struct {
+	name       string
+	analyze    func(ctx context.Context, ops []core.Operation) (*Analysis, error)
+	prohibited map[string]bool
+}
+
+// Check implements core.Checker, the model is not used.
+func (c Checker) Check(ctx context.Context, _ core.Model, ops []core.Operation) (bool, error) {
+	a, err := c.analyze(ctx, ops)
+	if err != nil {
+		return false, err
+	}
+	ok := true
+	for _, an := range a.Anomalies {
+		if c.prohibited[an.Type] {
+			log.Printf("%s finds %s", c.name, an)
+			ok = false
+		}
+	}
+	return ok, nil
+}
+
+// Name implements core.Checker.
+func (c Checker) Name() string {
+	return c.name
+}
+
+func newChecker(name string, analyze func(context.Context, []core.Operation) (*Analysis, error), c Consistency) Checker {
+	checker := Checker{
+		name:       name,
+		analyze:    analyze,
+		prohibited: make(map[string]bool),
+	}
+	for _, typ := range prohibited[c] {
+		checker.prohibited[typ] = true
+	}
+	return checker
+}
+
+// ListAppendChecker checks list-append histories, and fails on the anomalies
+// prohibited by the consistency model.
+func ListAppendChecker(c Consistency) core.Checker {
+	return newChecker(fmt.Sprintf("elle_list_append_%s_checker", c), AnalyzeListAppend, c)
+}
+
+// RWRegisterChecker checks rw-register histories, and fails on the anomalies
+// prohibited by the consistency model.
+func RWRegisterChecker(c Consistency) core.Checker {
+	return newChecker(fmt.Sprintf("elle_rw_register_%s_checker", c), AnalyzeRWRegister, c)
+}
+
+func init() {
+	core.RegisterChecker("elle_list_append", func() core.Checker { return ListAppendChecker(Serializable) })
+	core.RegisterChecker("elle_list_append_si", func() core.Checker { return ListAppendChecker(SnapshotIsolation) })
+	core.RegisterChecker("elle_rw_register", func() core.Checker { return RWRegisterChecker(Serializable) })
+	core.RegisterChecker("elle_rw_register_si", func() core.Checker { return RWRegisterChecker(SnapshotIsolation) })
+	history.RegisterParser("elle_txn", TxnParser())
+	history.RegisterEDNCodec("elle_txn", TxnEDNCodec())
+}
@@ -0,0 +1,218 @@
+package elle
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+func appendMop(key string, v int) Mop {
+	return Mop{F: MopAppend, Key: key, Value: v}
+}
+
+func readList(key string, list ...int) Mop {
+	return Mop{F: MopRead, Key: key, List: list}
+}
+
+func writeMop(key string, v int) Mop {
+	return Mop{F: MopWrite, Key: key, Value: v}
+}
+
+func readValue(key string, v int) Mop {
+	return Mop{F: MopRead, Key: key, Value: v}
+}
+
+// txnHistory builds a history with a process for every transaction, the
+// transactions are run one by one, and every one commits unless it is in
+// failed.
+func txnHistory(txns [][]Mop, failed ...int) []core.Operation {
+	var ops []core.Operation
+	for i, mops := range txns {
+		req := make([]Mop, len(mops))
+		for j, m := range mops {
+			req[j] = Mop{F: m.F, Key: m.Key}
+			if m.F != MopRead {
+				req[j].Value = m.Value
+			}
+		}
+		resp := TxnResponse{Ok: true, Mops: mops}
+		for _, f := range failed {
+			if f == i {
+				resp = TxnResponse{}
+			}
+		}
+		ops = append(ops,
+			core.Operation{Action: core.InvokeOperation, Proc: int64(i), Data: TxnRequest{Mops: req}},
+			core.Operation{Action: core.ReturnOperation, Proc: int64(i), Data: resp},
+		)
+	}
+	return ops
+}
+
+func anomalyTypes(a *Analysis) []string {
+	var types []string
+	for _, an := range a.Anomalies {
+		types = append(types, an.Type)
+	}
+	return types
+}
+
+func TestListAppend(t *testing.T) {
+	cases := []struct {
+		name     string
+		txns     [][]Mop
+		failed   []int
+		expected []string
+	}{
+		{"valid", [][]Mop{
+			{appendMop("x", 1)},
+			{readList("x", 1), appendMop("x", 2)},
+			{readList("x", 1, 2)},
+		}, nil, nil},
+		{"G1a", [][]Mop{
+			{appendMop("x", 1)},
+			{readList("x", 1)},
+		}, []int{0}, []string{G1a}},
+		{"G1b", [][]Mop{
+			{appendMop("x", 1), appendMop("x", 2)},
+			{readList("x", 1)},
+			{readList("x", 1, 2)},
+		}, nil, []string{G1b, GSingle}},
+		{"G0", [][]Mop{
+			{appendMop("x", 1), appendMop("y", 2)},
+			{appendMop("x", 3), appendMop("y", 4)},
+			{readList("x", 1, 3), readList("y", 4, 2)},
+		}, nil, []string{G0}},
+		{"G1c", [][]Mop{
+			{appendMop("x", 1), readList("y", 2)},
+			{appendMop("y", 2), readList("x", 1)},
+		}, nil, []string{G1c}},
+		{"G-single", [][]Mop{
+			{appendMop("x", 1), appendMop("y", 1)},
+			{readList("x"), readList("y", 1)},
+			{readList("x", 1)},
+		}, nil, []string{GSingle}},
+		{"G2", [][]Mop{
+			{readList("x"), appendMop("y", 1)},
+			{readList("y"), appendMop("x", 2)},
+			{readList("x", 2), readList("y", 1)},
+		}, nil, []string{G2}},
+		{"incompatible-order", [][]Mop{
+			{appendMop("x", 1)},
+			{appendMop("x", 2)},
+			{readList("x", 1, 2)},
+			{readList("x", 2)},
+		}, nil, []string{IncompatibleOrder, GSingle}},
+	}
+
+	for _, c := range cases {
+		a, err := AnalyzeListAppend(context.Background(), txnHistory(c.txns, c.failed...))
+		if err != nil {
+			t.Fatalf("%s: analyze failed %v", c.name, err)
+		}
+		if types := anomalyTypes(a); !reflect.DeepEqual(types, c.expected) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.expected, a.Anomalies)
+		}
+	}
+}
+
+func TestListAppendCycle(t *testing.T) {
+	ops := txnHistory([][]Mop{
+		{readList("x"), appendMop("y", 1)},
+		{readList("y"), appendMop("x", 2)},
+		{readList("x", 2), readList("y", 1)},
+	})
+	a, err := AnalyzeListAppend(context.Background(), ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Dep{{From: 0, To: 1, Kind: RW, Key: "x"}, {From: 1, To: 0, Kind: RW, Key: "y"}}
+	if len(a.Anomalies) != 1 || !reflect.DeepEqual(a.Anomalies[0].Cycle, expected) {
+		t.Fatalf("expected cycle %v, got %v", expected, a.Anomalies)
+	}
+	if len(a.Anomalies[0].Txns) != 2 || a.Anomalies[0].Message != "T0 -rw(x)-> T1 -rw(y)-> T0" {
+		t.Fatalf("unexpected anomaly %s", a.Anomalies[0])
+	}
+
+	// Write skew is allowed by snapshot isolation.
+	ok, err := ListAppendChecker(SnapshotIsolation).Check(context.Background(), nil, ops)
+	if err != nil || !ok {
+		t.Fatalf("write skew must be allowed by snapshot isolation, err %v", err)
+	}
+	ok, err = ListAppendChecker(Serializable).Check(context.Background(), nil, ops)
+	if err != nil || ok {
+		t.Fatalf("write skew must not be serializable, err %v", err)
+	}
+}
+
+func TestRWRegister(t *testing.T) {
+	cases := []struct {
+		name     string
+		txns     [][]Mop
+		failed   []int
+		expected []string
+	}{
+		{"valid", [][]Mop{
+			{writeMop("x", 1)},
+			{readValue("x", 1), writeMop("x", 2)},
+			{readValue("x", 2)},
+		}, nil, nil},
+		{"G1a", [][]Mop{
+			{writeMop("x", 1)},
+			{readValue("x", 1)},
+		}, []int{0}, []string{G1a}},
+		{"G1b", [][]Mop{
+			{writeMop("x", 1), writeMop("x", 2)},
+			{readValue("x", 1)},
+		}, nil, []string{G1b}},
+		{"G1c", [][]Mop{
+			{writeMop("x", 1), readValue("y", 2)},
+			{writeMop("y", 2), readValue("x", 1)},
+		}, nil, []string{G1c}},
+		{"G-single", [][]Mop{
+			{writeMop("x", 1), writeMop("y", 1)},
+			{readValue("x", 0), readValue("y", 1)},
+		}, nil, []string{GSingle}},
+		{"G2", [][]Mop{
+			{readValue("x", 0), writeMop("y", 1)},
+			{readValue("y", 0), writeMop("x", 2)},
+		}, nil, []string{G2}},
+	}
+
+	for _, c := range cases {
+		a, err := AnalyzeRWRegister(context.Background(), txnHistory(c.txns, c.failed...))
+		if err != nil {
+			t.Fatalf("%s: analyze failed %v", c.name, err)
+		}
+		if types := anomalyTypes(a); !reflect.DeepEqual(types, c.expected) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.expected, a.Anomalies)
+		}
+	}
+}
+
+func TestTxnEDN(t *testing.T) {
+	ops := txnHistory([][]Mop{
+		{appendMop("x", 1), readList("y", 1, 2)},
+		{writeMop("z", 3), readValue("z", 3)},
+	}, 1)
+
+	var buf bytes.Buffer
+	if err := history.WriteEDN(&buf, ops, TxnEDNCodec()); err != nil {
+		t.Fatalf("write edn failed %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`{:type :ok, :f :txn, :value [[:append "x" 1] [:r "y" [1 2]]], :process 0`)) {
+		t.Fatalf("expect a transaction, but got %s", buf.String())
+	}
+
+	readOps, err := history.ReadEDN(&buf, TxnEDNCodec())
+	if err != nil {
+		t.Fatalf("read edn failed %v", err)
+	}
+	if !reflect.DeepEqual(readOps, ops) {
+		t.Fatalf("expect %v, but got %v", ops, readOps)
+	}
+}
@@ -0,0 +1,250 @@
+package elle
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DepKind is the kind of a dependency between transactions.
+type DepKind uint8
+
+// Kinds of dependencies, as Adya defines them.
+const (
+	// WW means the second transaction overwrites a version written by the
+	// first one.
+	WW DepKind = 1 << iota
+	// WR means the second transaction reads a version written by the
+	// first one.
+	WR
+	// RW means the second transaction overwrites a version read by the
+	// first one, which is an anti-dependency.
+	RW
+)
+
+func (k DepKind) String() string {
+	switch k {
+	case WW:
+		return "ww"
+	case WR:
+		return "wr"
+	case RW:
+		return "rw"
+	default:
+		return fmt.Sprintf("DepKind(%d)", uint8(k))
+	}
+}
+
+// Dep is a dependency from a transaction to another one on a key.
+type Dep struct {
+	From int     `json:"from"`
+	To   int     `json:"to"`
+	Kind DepKind `json:"kind"`
+	Key  string  `json:"key"`
+}
+
+func (d Dep) String() string {
+	return fmt.Sprintf("T%d -%s(%s)-> T%d", d.From, d.Kind, d.Key, d.To)
+}
+
+// graph is the dependency graph of the transactions, the first dependency of
+// every kind between two transactions is kept as the evidence.
+type graph struct {
+	// out is the successors of every transaction, with the kinds of the
+	// dependencies to them.
+	out  map[int]map[int]DepKind
+	deps map[[2]int][]Dep
+}
+
+func newGraph() *graph {
+	return &graph{
+		out:  make(map[int]map[int]DepKind),
+		deps: make(map[[2]int][]Dep),
+	}
+}
+
+func (g *graph) add(from int, to int, kind DepKind, key string) {
+	if from == to {
+		return
+	}
+	succ, ok := g.out[from]
+	if !ok {
+		succ = make(map[int]DepKind)
+		g.out[from] = succ
+	}
+	if succ[to]&kind != 0 {
+		return
+	}
+	succ[to] |= kind
+	edge := [2]int{from, to}
+	g.deps[edge] = append(g.deps[edge], Dep{From: from, To: to, Kind: kind, Key: key})
+}
+
+// dep returns the evidence of the dependency from a transaction to another
+// one of the kinds, the kinds are preferred in the order ww, wr and rw.
+func (g *graph) dep(from int, to int, kinds DepKind) Dep {
+	var found Dep
+	for _, d := range g.deps[[2]int{from, to}] {
+		if d.Kind&kinds != 0 && (found.Kind == 0 || d.Kind < found.Kind) {
+			found = d
+		}
+	}
+	return found
+}
+
+// nodes returns the sorted transactions which have a dependency.
+func (g *graph) nodes() []int {
+	seen := make(map[int]bool)
+	for from, succ := range g.out {
+		seen[from] = true
+		for to := range succ {
+			seen[to] = true
+		}
+	}
+	nodes := make([]int, 0, len(seen))
+	for n := range seen {
+		nodes = append(nodes, n)
+	}
+	sort.Ints(nodes)
+	return nodes
+}
+
+// successors returns the sorted successors by the dependencies of the kinds.
+func (g *graph) successors(n int, kinds DepKind) []int {
+	var succ []int
+	for to, k := range g.out[n] {
+		if k&kinds != 0 {
+			succ = append(succ, to)
+		}
+	}
+	sort.Ints(succ)
+	return succ
+}
+
+// sccs returns the strongly connected components of more than one
+// transaction by the dependencies of the kinds, with Tarjan's algorithm.
+func (g *graph) sccs(kinds DepKind) [][]int {
+	var (
+		index   = make(map[int]int)
+		low     = make(map[int]int)
+		onStack = make(map[int]bool)
+		stack   []int
+		sccs    [][]int
+		next    int
+	)
+
+	var connect func(n int)
+	connect = func(n int) {
+		index[n] = next
+		low[n] = next
+		next++
+		stack = append(stack, n)
+		onStack[n] = true
+
+		for _, s := range g.successors(n, kinds) {
+			if _, ok := index[s]; !ok {
+				connect(s)
+				if low[s] < low[n] {
+					low[n] = low[s]
+				}
+			} else if onStack[s] && index[s] < low[n] {
+				low[n] = index[s]
+			}
+		}
+
+		if low[n] != index[n] {
+			return
+		}
+		var scc []int
+		for {
+			s := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[s] = false
+			scc = append(scc, s)
+			if s == n {
+				break
+			}
+		}
+		if len(scc) > 1 {
+			sort.Ints(scc)
+			sccs = append(sccs, scc)
+		}
+	}
+
+	for _, n := range g.nodes() {
+		if _, ok := index[n]; !ok {
+			connect(n)
+		}
+	}
+	return sccs
+}
+
+// path returns the shortest path from a transaction to another one by the
+// dependencies of the kinds in the component, or nil if there is none.
+func (g *graph) path(from int, to int, kinds DepKind, scc map[int]bool) []int {
+	prev := map[int]int{from: from}
+	queue := []int{from}
+	for len(queue) != 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if n == to {
+			path := []int{n}
+			for n != from {
+				n = prev[n]
+				path = append(path, n)
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path
+		}
+		for _, s := range g.successors(n, kinds) {
+			if _, ok := prev[s]; !ok && scc[s] {
+				prev[s] = n
+				queue = append(queue, s)
+			}
+		}
+	}
+	return nil
+}
+
+// cycle returns the dependencies of the cycle which starts with the
+// dependency and goes back by the path.
+func (g *graph) cycle(first Dep, path []int, kinds DepKind) []Dep {
+	deps := []Dep{first}
+	for i := 0; i+1 < len(path); i++ {
+		deps = append(deps, g.dep(path[i], path[i+1], kinds))
+	}
+	return deps
+}
+
+// findCycle finds a cycle in the component which has a dependency of the
+// first kinds, followed by the dependencies of the rest kinds.
+func (g *graph) findCycle(scc []int, first DepKind, rest DepKind) []Dep {
+	in := make(map[int]bool, len(scc))
+	for _, n := range scc {
+		in[n] = true
+	}
+	for _, n := range scc {
+		for _, s := range g.successors(n, first) {
+			if !in[s] {
+				continue
+			}
+			if path := g.path(s, n, rest, in); path != nil {
+				return g.cycle(g.dep(n, s, first), path, rest)
+			}
+		}
+	}
+	return nil
+}
+
+func formatCycle(cycle []Dep) string {
+	var b strings.Builder
+	for i, d := range cycle {
+		if i == 0 {
+			fmt.Fprintf(&b, "T%d", d.From)
+		}
+		fmt.Fprintf(&b, " -%s(%s)-> T%d", d.Kind, d.Key, d.To)
+	}
+	return b.String()
+}
@@ -0,0 +1,194 @@
+package elle
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+// Functions of micro operations.
+const (
+	// MopAppend appends the value to the list of the key.
+	MopAppend = "append"
+	// MopWrite writes the value to the register of the key.
+	MopWrite = "w"
+	// MopRead reads the list or the register of the key.
+	MopRead = "r"
+)
+
+// Mop is a micro operation of a transaction.
+type Mop struct {
+	F   string `json:"f"`
+	Key string `json:"k"`
+	// Value is the value to append or write, or the value read from a
+	// register, 0 means the register is never written. The values of a
+	// key must be unique and not 0.
+	Value int `json:"v,omitempty"`
+	// List is the list read by a list-append transaction.
+	List []int `json:"l,omitempty"`
+}
+
+func (m Mop) String() string {
+	switch {
+	case m.F != MopRead:
+		return fmt.Sprintf("%s %s %d", m.F, m.Key, m.Value)
+	case m.List != nil:
+		return fmt.Sprintf("r %s %v", m.Key, m.List)
+	case m.Value != 0:
+		return fmt.Sprintf("r %s %d", m.Key, m.Value)
+	default:
+		return fmt.Sprintf("r %s nil", m.Key)
+	}
+}
+
+// TxnRequest is a transaction of micro operations, the reads have no values.
+type TxnRequest struct {
+	Mops []Mop
+}
+
+// TxnResponse is the result of a transaction. Mops are the micro operations
+// of the request with the values read if the transaction commits.
+type TxnResponse struct {
+	Ok      bool
+	Unknown bool
+	Mops    []Mop
+}
+
+var _ core.UnknownResponse = (*TxnResponse)(nil)
+
+// IsUnknown implements UnknownResponse interface
+func (r TxnResponse) IsUnknown() bool {
+	return r.Unknown
+}
+
+type txnParser struct{}
+
+func (txnParser) OnRequest(data json.RawMessage) (interface{}, error) {
+	r := TxnRequest{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+func (txnParser) OnResponse(data json.RawMessage) (interface{}, error) {
+	r := TxnResponse{}
+	err := json.Unmarshal(data, &r)
+	if r.Unknown {
+		return nil, err
+	}
+	return r, err
+}
+
+func (txnParser) OnNoopResponse() interface{} {
+	return TxnResponse{Unknown: true}
+}
+
+func (txnParser) OnState(data json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+// TxnParser parses the histories of list-append and rw-register transactions.
+func TxnParser() history.RecordParser {
+	return txnParser{}
+}
+
+type txnCodec struct{}
+
+// encodeMops encodes the micro operations like [[:append "x" 1] [:r "x" [1]]].
+func encodeMops(mops []Mop) []interface{} {
+	value := make([]interface{}, 0, len(mops))
+	for _, m := range mops {
+		var v interface{}
+		switch {
+		case m.F != MopRead || m.Value != 0:
+			v = int64(m.Value)
+		case m.List != nil:
+			list := make([]interface{}, 0, len(m.List))
+			for _, e := range m.List {
+				list = append(list, int64(e))
+			}
+			v = list
+		}
+		value = append(value, []interface{}{history.Keyword(m.F), m.Key, v})
+	}
+	return value
+}
+
+func decodeMops(value interface{}) ([]Mop, error) {
+	seq, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("transaction %v is not a vector", value)
+	}
+	mops := make([]Mop, 0, len(seq))
+	for _, e := range seq {
+		mop, ok := e.([]interface{})
+		if !ok || len(mop) != 3 {
+			return nil, fmt.Errorf("micro operation %v is not [f key value]", e)
+		}
+		f, ok := mop[0].(history.Keyword)
+		if !ok {
+			return nil, fmt.Errorf("function %v is not a keyword", mop[0])
+		}
+		key, ok := mop[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("key %v is not a string", mop[1])
+		}
+
+		m := Mop{F: string(f), Key: key}
+		switch v := mop[2].(type) {
+		case nil:
+		case []interface{}:
+			m.List = make([]int, 0, len(v))
+			for _, x := range v {
+				n, err := history.EDNInt(x)
+				if err != nil {
+					return nil, err
+				}
+				m.List = append(m.List, int(n))
+			}
+		default:
+			n, err := history.EDNInt(v)
+			if err != nil {
+				return nil, err
+			}
+			m.Value = int(n)
+		}
+		mops = append(mops, m)
+	}
+	return mops, nil
+}
+
+func (txnCodec) EncodeRequest(req interface{}) (history.Keyword, interface{}, error) {
+	return "txn", encodeMops(req.(TxnRequest).Mops), nil
+}
+
+func (txnCodec) EncodeResponse(req interface{}, resp interface{}) (history.Keyword, interface{}, error) {
+	r := resp.(TxnResponse)
+	if !r.Ok {
+		return history.EDNFail, encodeMops(req.(TxnRequest).Mops), nil
+	}
+	return history.EDNOk, encodeMops(r.Mops), nil
+}
+
+func (txnCodec) DecodeRequest(f history.Keyword, value interface{}) (interface{}, error) {
+	if f != "txn" {
+		return nil, fmt.Errorf("unknown transaction operation %s", f)
+	}
+	mops, err := decodeMops(value)
+	return TxnRequest{Mops: mops}, err
+}
+
+func (txnCodec) DecodeResponse(typ history.Keyword, req interface{}, value interface{}) (interface{}, error) {
+	if typ != history.EDNOk {
+		return TxnResponse{}, nil
+	}
+	mops, err := decodeMops(value)
+	return TxnResponse{Ok: true, Mops: mops}, err
+}
+
+// TxnEDNCodec converts the transaction histories to and from Jepsen EDN like
+// Elle reads, a transaction is {:f :txn, :value [[:append "x" 1] [:r "y" [1 2]]]}.
+func TxnEDNCodec() history.EDNCodec {
+	return txnCodec{}
+}
@@ -0,0 +1,186 @@
+package porcupine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pingcap/chaos/pkg/core"
+)
+
+// DefaultExplainSteps limits the steps of Explain, the search is exponential
+// in the worst case.
+const DefaultExplainSteps = 1000000
+
+// Explanation tells why a history is not linearizable.
+type Explanation struct {
+	// Linearized are the indexes of the invoke operations in the longest
+	// linearizable prefix found, in the linearization order.
+	Linearized []int
+	// Blocked is the index of the invoke operation which could not be placed
+	// after the prefix, or -1 if the history is linearizable.
+	Blocked int
+	// Complete is true if the whole history is linearizable.
+	Complete bool
+	// Exhausted is true if the search stops at the step limit or the context
+	// is done, so a longer prefix may exist.
+	Exhausted bool
+}
+
+// explainOp is an operation from its invoke to its return.
+type explainOp struct {
+	// index is the index of the invoke operation.
+	index  int
+	call   int
+	ret    int
+	input  interface{}
+	output interface{}
+}
+
+type explainer struct {
+	ctx      context.Context
+	m        core.Model
+	ops      []explainOp
+	maxSteps int
+	steps    int
+	// done is true if the context is done.
+	done bool
+
+	linearized []bool
+	seq        []int
+	cache      map[string][]interface{}
+	best       Explanation
+}
+
+// Explain searches the longest linearizable prefix of the history with the
+// model, like Check the history must be complete. It stops after maxSteps
+// search steps, DefaultExplainSteps is used if maxSteps is not positive, or
+// when the context is done.
+func Explain(ctx context.Context, m core.Model, ops []core.Operation, maxSteps int) (Explanation, error) {
+	if maxSteps <= 0 {
+		maxSteps = DefaultExplainSteps
+	}
+
+	e := &explainer{
+		ctx:      ctx,
+		m:        m,
+		maxSteps: maxSteps,
+		cache:    make(map[string][]interface{}),
+		best:     Explanation{Blocked: -1},
+	}
+	pending := make(map[int64]int)
+	for i, op := range ops {
+		if op.Action == core.InvokeOperation {
+			pending[op.Proc] = len(e.ops)
+			e.ops = append(e.ops, explainOp{index: i, call: i, input: op.Data})
+			continue
+		}
+		if op.Data == nil {
+			continue
+		}
+		j, ok := pending[op.Proc]
+		if !ok {
+			return e.best, fmt.Errorf("missing invoke, op: %v", op)
+		}
+		delete(pending, op.Proc)
+		e.ops[j].ret = i
+		e.ops[j].output = op.Data
+	}
+	if len(pending) != 0 {
+		return e.best, fmt.Errorf("history is not complete")
+	}
+
+	e.linearized = make([]bool, len(e.ops))
+	e.best.Complete = e.search(m.Init())
+	e.best.Exhausted = !e.best.Complete && (e.steps >= e.maxSteps || e.done)
+	if e.best.Complete {
+		e.best.Blocked = -1
+	}
+	return e.best, nil
+}
+
+// search linearizes the rest operations from the state, and returns true
+// if all the operations are linearized.
+func (e *explainer) search(state interface{}) bool {
+	e.steps++
+
+	// The operation returns first must be linearized before any operation
+	// invoked after it returns.
+	first := -1
+	for i, op := range e.ops {
+		if !e.linearized[i] && (first == -1 || op.ret < e.ops[first].ret) {
+			first = i
+		}
+	}
+
+	if e.steps == 1 || len(e.seq) > len(e.best.Linearized) {
+		e.best.Linearized = e.best.Linearized[:0]
+		for _, i := range e.seq {
+			e.best.Linearized = append(e.best.Linearized, e.ops[i].index)
+		}
+		if first != -1 {
+			e.best.Blocked = e.ops[first].index
+		}
+	}
+	if first == -1 {
+		return true
+	}
+	if e.stopped() {
+		return false
+	}
+
+	for i, op := range e.ops {
+		if e.linearized[i] || op.call > e.ops[first].ret {
+			continue
+		}
+		ok, next := e.m.Step(state, op.input, op.output)
+		if !ok {
+			continue
+		}
+
+		e.linearized[i] = true
+		if e.visited(next) {
+			e.linearized[i] = false
+			continue
+		}
+		e.seq = append(e.seq, i)
+		if e.search(next) {
+			return true
+		}
+		e.seq = e.seq[:len(e.seq)-1]
+		e.linearized[i] = false
+
+		if e.stopped() {
+			return false
+		}
+	}
+	return false
+}
+
+// stopped tells whether the search should stop, at the step limit or when
+// the context is done.
+func (e *explainer) stopped() bool {
+	if !e.done && e.ctx.Err() != nil {
+		e.done = true
+	}
+	return e.done || e.steps >= e.maxSteps
+}
+
+// visited tells whether the linearized operations with the state are
+// searched, and marks them searched.
+func (e *explainer) visited(state interface{}) bool {
+	key := make([]byte, (len(e.linearized)+7)/8)
+	for i, ok := range e.linearized {
+		if ok {
+			key[i/8] |= 1 << uint(i%8)
+		}
+	}
+
+	states := e.cache[string(key)]
+	for _, s := range states {
+		if e.m.Equal(s, state) {
+			return true
+		}
+	}
+	e.cache[string(key)] = append(states, state)
+	return false
+}
@@ -0,0 +1,53 @@
+package porcupine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/pingcap/chaos/pkg/core"
+)
+
+func TestExplain(t *testing.T) {
+	ops := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 1, Data: noopRequest{Op: 0}},
+		{Action: core.InvokeOperation, Proc: 2, Data: noopRequest{Op: 1, Value: 15}},
+		{Action: core.ReturnOperation, Proc: 1, Data: noopResponse{Value: 15}},
+		{Action: core.ReturnOperation, Proc: 2, Data: noopResponse{Ok: true}},
+		{Action: core.InvokeOperation, Proc: 3, Data: noopRequest{Op: 0}},
+		{Action: core.ReturnOperation, Proc: 3, Data: noopResponse{Value: 15}},
+	}
+	e, err := Explain(context.Background(), noop{}, ops, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Complete || e.Blocked != -1 || !reflect.DeepEqual(e.Linearized, []int{1, 0, 4}) {
+		t.Fatalf("expect the history is linearizable, but got %+v", e)
+	}
+
+	// The read of proc 3 is invoked after the write returns, but reads
+	// the initial value.
+	ops[5].Data = noopResponse{Value: 10}
+	e, err = Explain(context.Background(), noop{}, ops, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Complete || e.Exhausted || e.Blocked != 4 || !reflect.DeepEqual(e.Linearized, []int{1, 0}) {
+		t.Fatalf("expect the read of proc 3 is blocked, but got %+v", e)
+	}
+
+	// The search stops at once with a done context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	e, err = Explain(ctx, noop{}, ops, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Complete || !e.Exhausted {
+		t.Fatalf("expect the search is exhausted, but got %+v", e)
+	}
+
+	if _, err = Explain(context.Background(), noop{}, ops[:5], 0); err == nil {
+		t.Fatal("expect an error for incomplete history")
+	}
+}
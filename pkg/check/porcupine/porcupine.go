@@ -1,8 +1,13 @@
 package porcupine
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/anishathalye/porcupine"
 	"github.com/pingcap/chaos/pkg/core"
@@ -12,8 +17,19 @@ import (
 type Checker struct{}
 
 // Check checks the history of operations meets liearizability or not with model.
-// False means the history is not linearizable.
-func (Checker) Check(m core.Model, ops []core.Operation) (bool, error) {
+// False means the history is not linearizable. Porcupine stops at the deadline
+// of the context, then the history is unknown and context.DeadlineExceeded is
+// returned. The history of a core.PartitionModel is checked by key, see
+// CheckPartitions.
+func (Checker) Check(ctx context.Context, m core.Model, ops []core.Operation) (bool, error) {
+	if pm, ok := m.(core.PartitionModel); ok {
+		invalid, err := CheckPartitions(ctx, pm, ops)
+		if len(invalid) != 0 {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
 	pModel := porcupine.Model{
 		Init:  m.Init,
 		Step:  m.Step,
@@ -24,7 +40,135 @@ func (Checker) Check(m core.Model, ops []core.Operation) (bool, error) {
 		return false, err
 	}
 	log.Printf("begin to verify %d events", len(events))
-	return porcupine.CheckEvents(pModel, events), nil
+	return checkEvents(ctx, pModel, events)
+}
+
+func checkEvents(ctx context.Context, m porcupine.Model, events []porcupine.Event) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	// 0 means no timeout for porcupine.
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		if timeout = time.Until(deadline); timeout <= 0 {
+			return false, context.DeadlineExceeded
+		}
+	}
+	switch porcupine.CheckEventsTimeout(m, events, timeout) {
+	case porcupine.Ok:
+		return true, nil
+	case porcupine.Illegal:
+		return false, nil
+	default:
+		return false, context.DeadlineExceeded
+	}
+}
+
+// Partition is the operations of a key of a core.PartitionModel.
+type Partition struct {
+	Key string
+	Ops []core.Operation
+}
+
+// PartitionOperations splits the operations by the key of their requests,
+// the partitions are sorted by key.
+func PartitionOperations(m core.PartitionModel, ops []core.Operation) ([]Partition, error) {
+	index := make(map[string]int)
+	// The partition of the pending invoke of every process.
+	pending := make(map[int64]int)
+	var partitions []Partition
+	for _, op := range ops {
+		var i int
+		if op.Action == core.InvokeOperation {
+			key := m.Partition(op.Data)
+			var ok bool
+			if i, ok = index[key]; !ok {
+				i = len(partitions)
+				index[key] = i
+				partitions = append(partitions, Partition{Key: key})
+			}
+			pending[op.Proc] = i
+		} else {
+			var ok bool
+			if i, ok = pending[op.Proc]; !ok {
+				return nil, fmt.Errorf("missing invoke, op: %v", op)
+			}
+			delete(pending, op.Proc)
+		}
+		partitions[i].Ops = append(partitions[i].Ops, op)
+	}
+
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].Key < partitions[j].Key })
+	return partitions, nil
+}
+
+// CheckPartitions checks the operations of every key alone, the partitions
+// are checked in parallel by all the CPUs. It returns the partitions which
+// are not linearizable, sorted by key. If no partition is found invalid but
+// some can't be checked before the deadline of the context, the error is
+// context.DeadlineExceeded.
+func CheckPartitions(ctx context.Context, m core.PartitionModel, ops []core.Operation) ([]Partition, error) {
+	partitions, err := PartitionOperations(m, ops)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("begin to verify %d operations in %d partitions", len(ops), len(partitions))
+
+	var (
+		oks  = make([]bool, len(partitions))
+		errs = make([]error, len(partitions))
+		next = make(chan int)
+		wg   sync.WaitGroup
+	)
+	workers := runtime.NumCPU()
+	if workers > len(partitions) {
+		workers = len(partitions)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				key := partitions[i].Key
+				pModel := porcupine.Model{
+					Init:  func() interface{} { return m.PartitionInit(key) },
+					Step:  m.Step,
+					Equal: m.Equal,
+				}
+				events, err := ConvertOperationsToEvents(partitions[i].Ops)
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				oks[i], errs[i] = checkEvents(ctx, pModel, events)
+			}
+		}()
+	}
+	for i := range partitions {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var invalid []Partition
+	err = nil
+	for i, p := range partitions {
+		if errs[i] != nil {
+			if err == nil || err == context.DeadlineExceeded {
+				err = errs[i]
+			}
+			continue
+		}
+		if !oks[i] {
+			log.Printf("partition %s is not linearizable", p.Key)
+			invalid = append(invalid, p)
+		}
+	}
+	if len(invalid) != 0 {
+		return invalid, nil
+	}
+	return nil, err
 }
 
 // Name is the name of porcupine checker
@@ -32,14 +176,18 @@ func (Checker) Name() string {
 	return "porcupine_checker"
 }
 
+func init() {
+	core.RegisterChecker("porcupine", func() core.Checker { return Checker{} })
+}
+
 // ConvertOperationsToEvents converts core.Operations to porcupine.Event.
 func ConvertOperationsToEvents(ops []core.Operation) ([]porcupine.Event, error) {
 	if len(ops)%2 != 0 {
 		return nil, fmt.Errorf("history is not complete")
 	}
 
-	procID := map[int64]uint{}
-	id := uint(0)
+	procID := map[int64]int{}
+	id := 0
 	events := make([]porcupine.Event, 0, len(ops))
 	for _, op := range ops {
 		if op.Action == core.InvokeOperation {
@@ -1,6 +1,8 @@
 package porcupine
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
 	"github.com/pingcap/chaos/pkg/core"
@@ -55,20 +57,85 @@ func (noop) Name() string {
 
 func TestPorcupineChecker(t *testing.T) {
 	ops := []core.Operation{
-		{core.InvokeOperation, 1, noopRequest{Op: 0}},
-		{core.ReturnOperation, 1, noopResponse{Value: 10}},
-		{core.InvokeOperation, 2, noopRequest{Op: 1, Value: 15}},
-		{core.ReturnOperation, 2, noopResponse{Unknown: true}},
-		{core.InvokeOperation, 3, noopRequest{Op: 0}},
-		{core.ReturnOperation, 3, noopResponse{Value: 15}},
+		{Action: core.InvokeOperation, Proc: 1, Data: noopRequest{Op: 0}},
+		{Action: core.ReturnOperation, Proc: 1, Data: noopResponse{Value: 10}},
+		{Action: core.InvokeOperation, Proc: 2, Data: noopRequest{Op: 1, Value: 15}},
+		{Action: core.ReturnOperation, Proc: 2, Data: noopResponse{Unknown: true}},
+		{Action: core.InvokeOperation, Proc: 3, Data: noopRequest{Op: 0}},
+		{Action: core.ReturnOperation, Proc: 3, Data: noopResponse{Value: 15}},
 	}
 
 	var checker Checker
-	ok, err := checker.Check(noop{}, ops)
+	ok, err := checker.Check(context.Background(), noop{}, ops)
 	if err != nil {
 		t.Fatalf("verify history failed %v", err)
 	}
 	if !ok {
 		t.Fatal("must be linearizable")
 	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = checker.Check(ctx, noop{}, ops); err != context.Canceled {
+		t.Fatalf("a cancelled check must be stopped, got %v", err)
+	}
+}
+
+// kv is a map of registers of the noop model.
+type kv struct {
+	noop
+}
+
+func (kv) Init() interface{} {
+	return 10
+}
+
+func (kv) Partition(input interface{}) string {
+	return fmt.Sprintf("k%d", input.(kvRequest).Key)
+}
+
+func (kv) PartitionInit(key string) interface{} {
+	return 10
+}
+
+func (m kv) Step(state interface{}, input interface{}, output interface{}) (bool, interface{}) {
+	return m.noop.Step(state, input.(kvRequest).noopRequest, output)
+}
+
+type kvRequest struct {
+	noopRequest
+	Key int
+}
+
+func TestPorcupineCheckerPartition(t *testing.T) {
+	var ops []core.Operation
+	for key := 0; key < 20; key++ {
+		proc := int64(key * 2)
+		ops = append(ops,
+			core.Operation{Action: core.InvokeOperation, Proc: proc, Data: kvRequest{noopRequest{Op: 1, Value: key}, key}},
+			core.Operation{Action: core.InvokeOperation, Proc: proc + 1, Data: kvRequest{noopRequest{Op: 0}, key}},
+			core.Operation{Action: core.ReturnOperation, Proc: proc, Data: noopResponse{Ok: true}},
+			core.Operation{Action: core.ReturnOperation, Proc: proc + 1, Data: noopResponse{Value: 10}},
+		)
+	}
+
+	var checker Checker
+	ok, err := checker.Check(context.Background(), kv{}, ops)
+	if err != nil || !ok {
+		t.Fatalf("must be linearizable, err %v", err)
+	}
+
+	// Key 7 reads the old value after the write returns.
+	ops = append(ops,
+		core.Operation{Action: core.InvokeOperation, Proc: 100, Data: kvRequest{noopRequest{Op: 0}, 7}},
+		core.Operation{Action: core.ReturnOperation, Proc: 100, Data: noopResponse{Value: 10}},
+	)
+	ok, err = checker.Check(context.Background(), kv{}, ops)
+	if err != nil || ok {
+		t.Fatalf("must not be linearizable, err %v", err)
+	}
+	invalid, err := CheckPartitions(context.Background(), kv{}, ops)
+	if err != nil || len(invalid) != 1 || invalid[0].Key != "k7" || len(invalid[0].Ops) != 6 {
+		t.Fatalf("unexpected invalid partitions %v, err %v", invalid, err)
+	}
 }
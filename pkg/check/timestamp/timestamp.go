@@ -0,0 +1,292 @@
+// Package timestamp checks a history of transactions by their timestamps:
+// the operations are replayed on the model in the order of the timestamps,
+// and every successful operation must be a valid step of the model. It is
+// much cheaper than checking linearizability, as the order of the operations
+// is known instead of searched.
+package timestamp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+// MaxStates limits how many possible states are kept when the operations
+// with unknown results are replayed, the history is unknown beyond it.
+var MaxStates = 100000
+
+// Response is a response with the timestamps of its transaction, a workload
+// opts in the checker by its responses implementing it.
+type Response interface {
+	// Timestamps returns the start and the commit timestamp of the
+	// transaction, and whether they are known. The commit timestamp is 0 if
+	// the transaction is read-only or it is unknown, then the transaction is
+	// replayed at its start timestamp, so it must write the keys it reads,
+	// e.g, by select for update. Both are 0 if the transaction fails before
+	// it starts. A committed transaction which writes must not fall back to
+	// its start timestamp if its commit timestamp is lost, known is false
+	// then, and it is replayed like an unknown operation without timestamps.
+	Timestamps() (start uint64, commit uint64, known bool)
+}
+
+// op is an operation to replay.
+type op struct {
+	// ts is the timestamp to replay the operation at.
+	ts uint64
+	// index is the index of the invoke in the history.
+	index  int
+	input  interface{}
+	output interface{}
+	// unknown means the operation may or may not take effect.
+	unknown bool
+	// after is the timestamp of the last operation which returns before an
+	// unknown operation without timestamps is invoked, the operation takes
+	// effect after it if it does.
+	after uint64
+	// id is the index of the unknown operation.
+	id int
+}
+
+func (o *op) String() string {
+	return fmt.Sprintf("operation %d at %d: %v -> %v", o.index, o.ts, o.input, o.output)
+}
+
+// timestampOf returns the timestamp to replay the response at, 0 if it has
+// no timestamps, and false if its timestamps are lost.
+func timestampOf(resp interface{}) (uint64, bool) {
+	r, ok := resp.(Response)
+	if !ok {
+		return 0, true
+	}
+	start, commit, known := r.Timestamps()
+	if !known {
+		return 0, false
+	}
+	if commit != 0 {
+		return commit, true
+	}
+	return start, true
+}
+
+func isUnknown(resp interface{}) bool {
+	if resp == nil {
+		return true
+	}
+	u, ok := resp.(core.UnknownResponse)
+	return ok && u.IsUnknown()
+}
+
+// buildOps returns the operations with timestamps sorted by timestamp, and
+// the unknown operations without timestamps sorted by after. The successful
+// operations without timestamps are skipped, they never take effect. The
+// operations whose timestamps are lost are unknown, as their order is.
+func buildOps(ops []core.Operation) ([]*op, []*op, error) {
+	type invoke struct {
+		index int
+		input interface{}
+		after uint64
+	}
+	var (
+		timed    []*op
+		untimed  []*op
+		unknowns int
+		// last is the largest timestamp of the returned operations.
+		last    uint64
+		pending = make(map[int64]invoke)
+	)
+	for i, o := range ops {
+		if o.Action == core.InvokeOperation {
+			pending[o.Proc] = invoke{index: i, input: o.Data, after: last}
+			continue
+		}
+
+		inv, ok := pending[o.Proc]
+		if !ok {
+			return nil, nil, fmt.Errorf("missing invoke, op: %v", o)
+		}
+		delete(pending, o.Proc)
+		if o.Data == nil {
+			return nil, nil, fmt.Errorf("missing response, the history must be completed by history.CompleteOperations, op: %v", o)
+		}
+		ts, known := timestampOf(o.Data)
+		p := &op{
+			ts:      ts,
+			index:   inv.index,
+			input:   inv.input,
+			output:  o.Data,
+			unknown: !known || isUnknown(o.Data),
+			after:   inv.after,
+		}
+		if p.unknown {
+			p.id = unknowns
+			unknowns++
+		}
+		switch {
+		case p.ts != 0:
+			timed = append(timed, p)
+			if !p.unknown && p.ts > last {
+				last = p.ts
+			}
+		case p.unknown:
+			untimed = append(untimed, p)
+		}
+	}
+
+	sort.SliceStable(timed, func(i, j int) bool { return timed[i].ts < timed[j].ts })
+	sort.SliceStable(untimed, func(i, j int) bool { return untimed[i].after < untimed[j].after })
+	return timed, untimed, nil
+}
+
+// config is a possible state, with the unknown operations which take effect
+// to reach it.
+type config struct {
+	state   interface{}
+	applied []bool
+}
+
+func (c config) apply(m core.Model, o *op) (config, bool) {
+	ok, state := m.Step(c.state, o.input, o.output)
+	if !ok {
+		return c, false
+	}
+	applied := append([]bool(nil), c.applied...)
+	if o.unknown {
+		applied[o.id] = true
+	}
+	return config{state: state, applied: applied}, true
+}
+
+func (c config) key() string {
+	var b strings.Builder
+	for _, a := range c.applied {
+		if a {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
+
+// configs are the possible states, deduplicated by the applied unknown
+// operations and the state.
+type configs struct {
+	m       core.Model
+	byKey   map[string][]config
+	configs []config
+}
+
+func newConfigs(m core.Model) *configs {
+	return &configs{m: m, byKey: make(map[string][]config)}
+}
+
+func (cs *configs) add(c config) bool {
+	key := c.key()
+	for _, other := range cs.byKey[key] {
+		if cs.m.Equal(other.state, c.state) {
+			return false
+		}
+	}
+	cs.byKey[key] = append(cs.byKey[key], c)
+	cs.configs = append(cs.configs, c)
+	return true
+}
+
+// Checker replays the operations in the order of their timestamps on the
+// model, see Response.
+type Checker struct {
+	// Parser completes the history if it is not nil, the parsers return nil
+	// for the unknown responses, which can't be replayed on the model.
+	// Otherwise the history must be complete, like in verify.
+	Parser history.RecordParser
+}
+
+// Check implements core.Checker. An operation with an unknown result is
+// replayed at its timestamp if it has one, otherwise at any timestamp after
+// the operations returned before it is invoked, both taking effect and not
+// are tried.
+func (c Checker) Check(ctx context.Context, m core.Model, ops []core.Operation) (bool, error) {
+	if m == nil {
+		return false, fmt.Errorf("timestamp checker requires a model")
+	}
+	var err error
+	if c.Parser != nil {
+		if ops, err = history.CompleteOperations(ops, c.Parser); err != nil {
+			return false, err
+		}
+	}
+	timed, untimed, err := buildOps(ops)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("begin to replay %d operations by timestamp, %d unknown operations have no timestamps", len(timed), len(untimed))
+
+	unknowns := 0
+	for _, o := range timed {
+		if o.unknown {
+			unknowns++
+		}
+	}
+	unknowns += len(untimed)
+
+	cs := newConfigs(m)
+	cs.add(config{state: m.Init(), applied: make([]bool, unknowns)})
+	// open are the unknown operations without timestamps which may take
+	// effect now.
+	var open []*op
+	for _, o := range timed {
+		if err = ctx.Err(); err != nil {
+			return false, err
+		}
+
+		for len(untimed) != 0 && untimed[0].after < o.ts {
+			open = append(open, untimed[0])
+			untimed = untimed[1:]
+		}
+		// Try every open unknown operation which doesn't take effect yet.
+		for i := 0; i < len(cs.configs); i++ {
+			c := cs.configs[i]
+			for _, u := range open {
+				if c.applied[u.id] {
+					continue
+				}
+				if next, ok := c.apply(m, u); ok {
+					cs.add(next)
+				}
+			}
+			if len(cs.configs) > MaxStates {
+				return false, fmt.Errorf("more than %d possible states at %s", MaxStates, o)
+			}
+		}
+
+		next := newConfigs(m)
+		for _, c := range cs.configs {
+			if o.unknown {
+				next.add(c)
+			}
+			if n, ok := c.apply(m, o); ok {
+				next.add(n)
+			}
+		}
+		if len(next.configs) == 0 {
+			log.Printf("%s can't be explained by replaying the history by timestamp", o)
+			return false, nil
+		}
+		cs = next
+	}
+	return true, nil
+}
+
+// Name implements core.Checker.
+func (Checker) Name() string {
+	return "timestamp_checker"
+}
+
+func init() {
+	core.RegisterChecker("timestamp", func() core.Checker { return Checker{} })
+}
@@ -0,0 +1,154 @@
+package timestamp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+type tsRequest struct {
+	Write bool
+	Value int
+}
+
+type tsResponse struct {
+	Start   uint64
+	Commit  uint64
+	Value   int
+	Unknown bool
+	// Lost means the commit timestamp is lost.
+	Lost bool
+}
+
+func (r tsResponse) Timestamps() (uint64, uint64, bool) {
+	return r.Start, r.Commit, !r.Lost
+}
+
+func (r tsResponse) IsUnknown() bool {
+	return r.Unknown
+}
+
+// tsParser completes the history with the unknown tsResponse.
+type tsParser struct {
+	history.NoopParser
+}
+
+func (tsParser) OnNoopResponse() interface{} {
+	return tsResponse{Unknown: true}
+}
+
+// register is a register model of tsRequest.
+type register struct {
+	core.NoopModel
+}
+
+func (*register) Init() interface{} {
+	return 0
+}
+
+func (*register) Step(state interface{}, input interface{}, output interface{}) (bool, interface{}) {
+	req := input.(tsRequest)
+	resp := output.(tsResponse)
+	if req.Write {
+		return true, req.Value
+	}
+	return resp.Unknown || resp.Value == state.(int), state
+}
+
+func (*register) Equal(state1, state2 interface{}) bool {
+	return state1.(int) == state2.(int)
+}
+
+func tsHistory(txns ...interface{}) []core.Operation {
+	var ops []core.Operation
+	for i := 0; i+1 < len(txns); i += 2 {
+		proc := int64(i / 2)
+		ops = append(ops,
+			core.Operation{Action: core.InvokeOperation, Proc: proc, Data: txns[i]},
+			core.Operation{Action: core.ReturnOperation, Proc: proc, Data: txns[i+1]},
+		)
+	}
+	return ops
+}
+
+func TestChecker(t *testing.T) {
+	cases := []struct {
+		name  string
+		ops   []core.Operation
+		valid bool
+	}{
+		{"ordered by timestamp", tsHistory(
+			tsRequest{Write: true, Value: 1}, tsResponse{Start: 5, Commit: 10},
+			tsRequest{}, tsResponse{Start: 15, Value: 1},
+			// It is invoked later, but reads the snapshot before the write.
+			tsRequest{}, tsResponse{Start: 8, Value: 0},
+		), true},
+		{"stale read", tsHistory(
+			tsRequest{Write: true, Value: 1}, tsResponse{Start: 5, Commit: 10},
+			tsRequest{}, tsResponse{Start: 15, Value: 0},
+		), false},
+		{"failed before start", tsHistory(
+			tsRequest{Write: true, Value: 1}, tsResponse{},
+			tsRequest{}, tsResponse{Start: 15, Value: 0},
+		), true},
+		{"unknown write takes effect", tsHistory(
+			tsRequest{Write: true, Value: 1}, tsResponse{Start: 5, Commit: 10},
+			tsRequest{Write: true, Value: 2}, tsResponse{Unknown: true},
+			tsRequest{}, tsResponse{Start: 20, Value: 2},
+			tsRequest{}, tsResponse{Start: 30, Value: 2},
+		), true},
+		{"unknown write doesn't take effect", tsHistory(
+			tsRequest{Write: true, Value: 2}, tsResponse{Unknown: true},
+			tsRequest{}, tsResponse{Start: 20, Value: 0},
+		), true},
+		{"unknown write takes effect before its invoke", tsHistory(
+			tsRequest{}, tsResponse{Start: 10, Value: 0},
+			tsRequest{Write: true, Value: 2}, tsResponse{Unknown: true},
+			tsRequest{}, tsResponse{Start: 5, Value: 2},
+		), false},
+		{"unknown write with timestamp", tsHistory(
+			tsRequest{Write: true, Value: 2}, tsResponse{Start: 5, Commit: 10, Unknown: true},
+			tsRequest{}, tsResponse{Start: 8, Value: 0},
+			tsRequest{}, tsResponse{Start: 12, Value: 2},
+		), true},
+		{"lost commit timestamp", tsHistory(
+			tsRequest{Write: true, Value: 1}, tsResponse{Start: 2, Lost: true},
+			// The write is not replayed at its start timestamp.
+			tsRequest{}, tsResponse{Start: 3, Value: 0},
+			tsRequest{}, tsResponse{Start: 20, Value: 1},
+		), true},
+		{"unknown write is read back and forth", tsHistory(
+			tsRequest{Write: true, Value: 2}, tsResponse{Unknown: true},
+			tsRequest{}, tsResponse{Start: 20, Value: 2},
+			tsRequest{}, tsResponse{Start: 30, Value: 0},
+		), false},
+	}
+
+	for _, c := range cases {
+		ok, err := Checker{}.Check(context.Background(), &register{}, c.ops)
+		if err != nil {
+			t.Fatalf("%s: check failed %v", c.name, err)
+		}
+		if ok != c.valid {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.valid, ok)
+		}
+	}
+}
+
+func TestCheckerUnknownResponse(t *testing.T) {
+	// The parsers return nil for the unknown responses.
+	ops := tsHistory(
+		tsRequest{Write: true, Value: 2}, nil,
+		tsRequest{}, tsResponse{Start: 20, Value: 2},
+	)
+	if _, err := (Checker{}).Check(context.Background(), &register{}, ops); err == nil {
+		t.Fatal("an incomplete history must fail")
+	}
+
+	ok, err := Checker{Parser: tsParser{}}.Check(context.Background(), &register{}, ops)
+	if err != nil || !ok {
+		t.Fatalf("the unknown write must take effect, err %v", err)
+	}
+}
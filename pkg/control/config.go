@@ -8,18 +8,43 @@ import (
 type Config struct {
 	// DB is the name which we want to run.
 	DB string
+	// Workload is the name of the client test case, it is recorded in the
+	// history header.
+	Workload string
 	// Nodes are address of nodes.
 	Nodes []string
+	// Executor is how we run commands on nodes: ssh, native-ssh, native-ssh:<inventory file>,
+	// local, docker or docker:<container prefix>. Default is ssh.
+	Executor string
 
 	// RunRound controls how many round the controller runs tests.
 	RunRound int
 	// RunTime controls how long a round takes.
 	RunTime time.Duration
-	// RequestCount controls how many requests a client sends to the db.
+	// RequestCount controls how many requests all the clients send to the db in a round.
 	RequestCount int
+	// Concurrency controls how many clients run on one node.
+	Concurrency int
+	// Seed is the seed of the global random source used by the nemesis
+	// generators, a time based seed is used if it is 0.
+	Seed int64
 
-	// History file
+	// OutputDir is where the run directories are created. Every run creates a
+	// directory named by its start time in it, with the histories, logs and
+	// results of the run, and links OutputDir/latest to it.
+	OutputDir string
+	// History is the path prefix of the history files, default is
+	// history.log in the run directory.
 	History string
+	// HistoryCompression compresses the history files, it is empty or none,
+	// gzip or zstd.
+	HistoryCompression string
+	// HistorySync is how often the history records are written to the file,
+	// it is empty or none, flush or fsync, see history.SyncPolicy.
+	HistorySync string
+	// OnlineCheckers are the names of the online checkers, which check the
+	// operations while the test runs, and abort it on the first violation.
+	OnlineCheckers []string
 }
 
 func (c *Config) adjust() {
@@ -34,4 +59,16 @@ func (c *Config) adjust() {
 	if c.RunRound == 0 {
 		c.RunRound = 20
 	}
+
+	if c.Concurrency == 0 {
+		c.Concurrency = 1
+	}
+
+	if c.Seed == 0 {
+		c.Seed = time.Now().UnixNano()
+	}
+
+	if len(c.History) == 0 && len(c.OutputDir) == 0 {
+		c.History = historyFileName
+	}
 }
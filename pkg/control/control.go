@@ -3,13 +3,17 @@ package control
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
+	"math/rand"
+	"path"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/pingcap/chaos/pkg/core"
 	"github.com/pingcap/chaos/pkg/history"
+	"github.com/pingcap/chaos/pkg/util/executor"
 	"github.com/pingcap/chaos/pkg/verify"
 
 	// register nemesis
@@ -25,17 +29,40 @@ import (
 type Controller struct {
 	cfg *Config
 
+	// clients are Concurrency clients for every node, the clients
+	// of the ith node are clients[i*Concurrency : (i+1)*Concurrency].
 	clients []core.Client
+	// ready tells whether the client is set up, only the ready clients are
+	// torn down.
+	ready []bool
 
 	nemesisGenerators []core.NemesisGenerator
 
 	ctx    context.Context
 	cancel context.CancelFunc
 
+	executor executor.Executor
+
+	compression history.Compression
+	syncPolicy  history.SyncPolicy
+	// dbVersion is reported by the database after it is set up.
+	dbVersion string
+
 	proc         int64
 	requestCount int64
 
-	suit verify.Suit
+	// recorder is the history recorder of the running round, nemesis activities
+	// are recorded to it. It is nil between rounds.
+	recorderMu sync.Mutex
+	recorder   *history.Recorder
+
+	// suits verify the history of every round.
+	suits   []verify.Suit
+	results []verify.Result
+
+	// runDir is the directory of this run, empty if Config.OutputDir is not set.
+	runDir  string
+	runLogs *runLogs
 }
 
 // NewController creates a controller.
@@ -44,7 +71,7 @@ func NewController(
 	cfg *Config,
 	clientCreator core.ClientCreator,
 	nemesisGenerators []core.NemesisGenerator,
-	verifySuit verify.Suit,
+	verifySuits []verify.Suit,
 ) *Controller {
 	cfg.adjust()
 
@@ -56,14 +83,53 @@ func NewController(
 		log.Fatalf("database %s is not registered", cfg.DB)
 	}
 
+	e, err := executor.New(cfg.Executor)
+	if err != nil {
+		log.Fatalf("create executor failed %v", err)
+	}
+
+	compression, err := history.ParseCompression(cfg.HistoryCompression)
+	if err != nil {
+		log.Fatal(err)
+	}
+	syncPolicy, err := history.ParseSyncPolicy(cfg.HistorySync)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err = verify.NewOnlineCheckers(cfg.OnlineCheckers); err != nil {
+		log.Fatal(err)
+	}
+
+	// The nemesis generators use the global random source.
+	rand.Seed(cfg.Seed)
+
 	c := new(Controller)
 	c.cfg = cfg
-	c.ctx, c.cancel = context.WithCancel(ctx)
+	c.executor = e
+	c.compression = compression
+	c.syncPolicy = syncPolicy
+	// All the node operations of DB and nemesis use the executor bound to the context.
+	c.ctx, c.cancel = context.WithCancel(executor.WithExecutor(ctx, e))
 	c.nemesisGenerators = nemesisGenerators
-	c.suit = verifySuit
+	c.suits = verifySuits
+
+	if len(cfg.OutputDir) != 0 {
+		if c.runDir, err = newRunDir(cfg.OutputDir, time.Now()); err != nil {
+			log.Fatalf("create run directory in %s failed %v", cfg.OutputDir, err)
+		}
+		if c.runLogs, err = openRunLogs(c.runDir); err != nil {
+			log.Fatalf("open logs in %s failed %v", c.runDir, err)
+		}
+		if len(cfg.History) == 0 {
+			cfg.History = path.Join(c.runDir, historyFileName)
+		}
+		log.Printf("write the artifacts of this run to %s", c.runDir)
+	}
 
 	for _, node := range c.cfg.Nodes {
-		c.clients = append(c.clients, clientCreator.Create(node))
+		for i := 0; i < c.cfg.Concurrency; i++ {
+			c.clients = append(c.clients, clientCreator.Create(node))
+		}
 	}
 
 	log.Printf("start controller with %+v", cfg)
@@ -71,16 +137,59 @@ func NewController(
 	return c
 }
 
+// RunDir returns the directory of this run, it is empty if Config.OutputDir is not set.
+func (c *Controller) RunDir() string {
+	return c.runDir
+}
+
 // Close closes the controller.
 func (c *Controller) Close() {
 	c.cancel()
 }
 
-// Run runs the controller.
-func (c *Controller) Run() {
-	c.setUpDB()
-	c.setUpClient()
+// Run runs the controller. A failed round doesn't stop the test, all the
+// results are summarized and written to the summary file after tearing down.
+func (c *Controller) Run() verify.Summary {
+	if err := c.setUp(); err != nil {
+		log.Printf("set up failed %v", err)
+		c.addRoundError(c.historyFile(1), fmt.Errorf("set up failed %v", err))
+	} else {
+		c.runRounds()
+	}
+
+	c.tearDownClient()
+	c.collectNodeLogs()
+	c.tearDownDB()
+
+	if closer, ok := c.executor.(io.Closer); ok {
+		closer.Close()
+	}
+
+	summary := verify.Summarize(c.results)
+	summaryFile := fmt.Sprintf("%s.summary.json", c.cfg.History)
+	if len(c.runDir) != 0 {
+		summaryFile = path.Join(c.runDir, summaryFileName)
+	}
+	if err := verify.WriteSummary(summaryFile, summary); err != nil {
+		log.Printf("write summary to %s failed %v", summaryFile, err)
+	} else {
+		log.Printf("write summary to %s, outcome %s", summaryFile, summary.Outcome)
+	}
+
+	if c.runLogs != nil {
+		c.runLogs.close()
+	}
+	return summary
+}
+
+// historyFile returns the history file of the round.
+func (c *Controller) historyFile(round int) string {
+	return fmt.Sprintf("%s.%d%s", c.cfg.History, round, c.compression.Ext())
+}
 
+// runRounds runs the rounds with the nemeses, and verifies the history of
+// every round.
+func (c *Controller) runRounds() {
 	nctx, ncancel := context.WithTimeout(c.ctx, c.cfg.RunTime*time.Duration(int64(c.cfg.RunRound)))
 	var nemesisWg sync.WaitGroup
 	nemesisWg.Add(1)
@@ -95,35 +204,74 @@ ROUND:
 
 		ctx, cancel := context.WithTimeout(c.ctx, c.cfg.RunTime)
 
-		historyFile := fmt.Sprintf("%s.%d", c.cfg.History, round)
-		recorder, err := history.NewRecorder(historyFile)
+		historyFile := c.historyFile(round)
+		recorder, err := history.NewRecorderWithSync(historyFile, c.historyHeader(round), c.syncPolicy)
 		if err != nil {
-			log.Fatalf("prepare history failed %v", err)
+			log.Printf("prepare history failed %v", err)
+			cancel()
+			c.addRoundError(historyFile, err)
+			break ROUND
+		}
+		log.Printf("record history to %s, start at %s", historyFile, recorder.Start().Format(time.RFC3339Nano))
+
+		var online *verify.Online
+		if len(c.cfg.OnlineCheckers) != 0 {
+			// The checkers are created for every round, they are validated already.
+			checkers, _ := verify.NewOnlineCheckers(c.cfg.OnlineCheckers)
+			online = verify.NewOnline(historyFile, c.historyHeader(round), checkers, cancel)
+			recorder.Observe(online)
 		}
 
 		if err := c.dumpState(ctx, recorder); err != nil {
-			log.Fatalf("dump state failed %v", err)
+			log.Printf("dump state failed %v", err)
+			cancel()
+			recorder.Close()
+			c.addRoundError(historyFile, err)
+			break ROUND
 		}
+		c.setRecorder(recorder)
 
 		// requestCount for the round, shared by all clients.
 		requestCount := int64(c.cfg.RequestCount)
 		log.Printf("total request count %d", requestCount)
 
-		n := len(c.cfg.Nodes)
+		n := len(c.clients)
 		var clientWg sync.WaitGroup
 		clientWg.Add(n)
+		// errs are the errors of the clients, the round stops at the first one.
+		errs := make([]error, n)
 		for i := 0; i < n; i++ {
 			go func(i int) {
 				defer clientWg.Done()
-				c.onClientLoop(ctx, i, &requestCount, recorder)
+				if errs[i] = c.onClientLoop(ctx, i, &requestCount, recorder); errs[i] != nil {
+					cancel()
+				}
 			}(i)
 		}
 
 		clientWg.Wait()
 		cancel()
 
-		recorder.Close()
-		c.suit.Verify(historyFile)
+		c.setRecorder(nil)
+		if err := recorder.Close(); err != nil {
+			log.Printf("close history %s failed %v", historyFile, err)
+		}
+		if err := firstError(errs); err != nil {
+			log.Printf("round %d failed %v", round, err)
+			c.addRoundError(historyFile, err)
+			break ROUND
+		}
+		if online != nil {
+			c.results = append(c.results, online.Finish()...)
+		}
+		for _, suit := range c.suits {
+			c.results = append(c.results, suit.Verify(historyFile))
+		}
+
+		if online != nil && online.Failed() {
+			log.Printf("round %d is aborted by the online checkers", round)
+			break ROUND
+		}
 
 		select {
 		case <-c.ctx.Done():
@@ -137,14 +285,81 @@ ROUND:
 
 	ncancel()
 	nemesisWg.Wait()
+}
 
-	c.tearDownClient()
-	c.tearDownDB()
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addRoundError adds unknown results for the round which fails to run.
+func (c *Controller) addRoundError(historyFile string, err error) {
+	for _, suit := range c.suits {
+		r := verify.Result{
+			Outcome:     verify.Unknown,
+			Checker:     suit.Checker.Name(),
+			HistoryFile: historyFile,
+			Err:         err.Error(),
+		}
+		if suit.Model != nil {
+			r.Model = suit.Model.Name()
+		}
+		c.results = append(c.results, r)
+	}
+}
+
+func (c *Controller) setRecorder(recorder *history.Recorder) {
+	c.recorderMu.Lock()
+	defer c.recorderMu.Unlock()
+	c.recorder = recorder
+}
+
+// recordNemesis records the nemesis activity to the nemesis log and the history
+// of the running round. Activities between rounds are only in the nemesis log.
+func (c *Controller) recordNemesis(kind string, op *core.NemesisOperation, node string, args []string, start time.Time, err error) {
+	end := time.Now()
+
+	if c.runLogs != nil {
+		c.runLogs.logNemesis(kind, op, node, args, start, end, err)
+	}
+
+	c.recorderMu.Lock()
+	defer c.recorderMu.Unlock()
+	if c.recorder == nil {
+		return
+	}
+
+	record := core.NemesisRecord{
+		Kind:  kind,
+		Name:  op.Name,
+		Node:  node,
+		Args:  args,
+		Start: start.Sub(c.recorder.Start()),
+		End:   end.Sub(c.recorder.Start()),
+	}
+	if err != nil {
+		record.Err = err.Error()
+	}
+	if err := c.recorder.RecordNemesis(record); err != nil {
+		log.Printf("record nemesis %+v failed %v", record, err)
+	}
+}
+
+// clientNode returns the node of the ith client.
+func (c *Controller) clientNode(i int) string {
+	return c.cfg.Nodes[i/c.cfg.Concurrency]
 }
 
 func (c *Controller) syncExec(f func(i int)) {
+	c.syncExecN(len(c.cfg.Nodes), f)
+}
+
+func (c *Controller) syncExecN(n int, f func(i int)) {
 	var wg sync.WaitGroup
-	n := len(c.cfg.Nodes)
 	wg.Add(n)
 	for i := 0; i < n; i++ {
 		go func(i int) {
@@ -155,16 +370,53 @@ func (c *Controller) syncExec(f func(i int)) {
 	wg.Wait()
 }
 
-func (c *Controller) setUpDB() {
+// setUp sets up the database and the clients.
+func (c *Controller) setUp() error {
+	if err := c.setUpDB(); err != nil {
+		return err
+	}
+	return c.setUpClient()
+}
+
+func (c *Controller) setUpDB() error {
 	log.Printf("begin to set up database")
+	errs := make([]error, len(c.cfg.Nodes))
 	c.syncExec(func(i int) {
 		log.Printf("begin to set up database on %s", c.cfg.Nodes[i])
 		db := core.GetDB(c.cfg.DB)
-		err := db.SetUp(c.ctx, c.cfg.Nodes, c.cfg.Nodes[i])
-		if err != nil {
-			log.Fatalf("setup db %s at node %s failed %v", c.cfg.DB, c.cfg.Nodes[i], err)
+		if err := db.SetUp(c.ctx, c.cfg.Nodes, c.cfg.Nodes[i]); err != nil {
+			errs[i] = fmt.Errorf("setup db %s at node %s failed %v", c.cfg.DB, c.cfg.Nodes[i], err)
 		}
 	})
+	if err := firstError(errs); err != nil {
+		return err
+	}
+
+	if db, ok := core.GetDB(c.cfg.DB).(core.VersionedDB); ok {
+		version, err := db.Version(c.ctx, c.cfg.Nodes[0])
+		if err != nil {
+			log.Printf("get version of db %s failed %v", c.cfg.DB, err)
+		}
+		c.dbVersion = version
+		log.Printf("db %s version %s", c.cfg.DB, version)
+	}
+	return nil
+}
+
+// historyHeader returns the header of the history of the round.
+func (c *Controller) historyHeader(round int) history.Header {
+	h := history.Header{
+		DB:        c.cfg.DB,
+		DBVersion: c.dbVersion,
+		Workload:  c.cfg.Workload,
+		Nodes:     c.cfg.Nodes,
+		Seed:      c.cfg.Seed,
+		Round:     round,
+	}
+	for _, g := range c.nemesisGenerators {
+		h.Nemeses = append(h.Nemeses, g.Name())
+	}
+	return h
 }
 
 func (c *Controller) tearDownDB() {
@@ -178,23 +430,38 @@ func (c *Controller) tearDownDB() {
 	})
 }
 
-func (c *Controller) setUpClient() {
+func (c *Controller) setUpClient() error {
 	log.Printf("begin to set up client")
-	c.syncExec(func(i int) {
-		client := c.clients[i]
-		node := c.cfg.Nodes[i]
-		log.Printf("begin to set up db client for node %s", node)
-		if err := client.SetUp(c.ctx, c.cfg.Nodes, node); err != nil {
-			log.Fatalf("set up db client for node %s failed %v", node, err)
+	c.ready = make([]bool, len(c.clients))
+	// The first client of every node is set up before others, so the
+	// initialization on the first node is done before other clients connect.
+	for j := 0; j < c.cfg.Concurrency; j++ {
+		errs := make([]error, len(c.cfg.Nodes))
+		c.syncExec(func(i int) {
+			client := c.clients[i*c.cfg.Concurrency+j]
+			node := c.cfg.Nodes[i]
+			log.Printf("begin to set up db client %d for node %s", j, node)
+			if err := client.SetUp(c.ctx, c.cfg.Nodes, node); err != nil {
+				errs[i] = fmt.Errorf("set up db client %d for node %s failed %v", j, node, err)
+				return
+			}
+			c.ready[i*c.cfg.Concurrency+j] = true
+		})
+		if err := firstError(errs); err != nil {
+			return err
 		}
-	})
+	}
+	return nil
 }
 
 func (c *Controller) tearDownClient() {
 	log.Printf("begin to tear down client")
-	c.syncExec(func(i int) {
+	c.syncExecN(len(c.clients), func(i int) {
+		if i >= len(c.ready) || !c.ready[i] {
+			return
+		}
 		client := c.clients[i]
-		node := c.cfg.Nodes[i]
+		node := c.clientNode(i)
 		log.Printf("begin to tear down db client for node %s", node)
 		if err := client.TearDown(c.ctx, c.cfg.Nodes, node); err != nil {
 			log.Printf("tear down db client for node %s failed %v", node, err)
@@ -219,16 +486,18 @@ func (c *Controller) dumpState(ctx context.Context, recorder *history.Recorder)
 	return fmt.Errorf("fail to dump")
 }
 
+// onClientLoop runs the requests of the ith client until the requests are
+// used up or the context is done, it returns the error of recording.
 func (c *Controller) onClientLoop(
 	ctx context.Context,
 	i int,
 	requestCount *int64,
 	recorder *history.Recorder,
-) {
+) error {
 	client := c.clients[i]
-	node := c.cfg.Nodes[i]
+	node := c.clientNode(i)
 
-	log.Printf("begin to run command on node %s", node)
+	log.Printf("begin to run command on node %s with client %d", node, i)
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -237,8 +506,8 @@ func (c *Controller) onClientLoop(
 	for atomic.AddInt64(requestCount, -1) >= 0 {
 		request := client.NextRequest()
 
-		if err := recorder.RecordRequest(procID, request); err != nil {
-			log.Fatalf("record request %v failed %v", request, err)
+		if err := recorder.RecordRequest(procID, node, i, request); err != nil {
+			return fmt.Errorf("record request %v failed %v", request, err)
 		}
 
 		log.Printf("%s: call %+v", node, request)
@@ -249,8 +518,8 @@ func (c *Controller) onClientLoop(
 			isUnknown = v.IsUnknown()
 		}
 
-		if err := recorder.RecordResponse(procID, response); err != nil {
-			log.Fatalf("record response %v failed %v", response, err)
+		if err := recorder.RecordResponse(procID, node, i, response); err != nil {
+			return fmt.Errorf("record response %v failed %v", response, err)
 		}
 
 		// If Unknown, we need to use another process ID.
@@ -260,10 +529,11 @@ func (c *Controller) onClientLoop(
 
 		select {
 		case <-ctx.Done():
-			return
+			return nil
 		default:
 		}
 	}
+	return nil
 }
 
 func (c *Controller) dispatchNemesis(ctx context.Context) {
@@ -312,15 +582,21 @@ func (c *Controller) onNemesisLoop(ctx context.Context, index int, op *core.Neme
 	node := c.cfg.Nodes[index]
 
 	log.Printf("run nemesis %s on %s", op.Name, node)
-	if err := nemesis.Invoke(ctx, node, op.InvokeArgs...); err != nil {
+	start := time.Now()
+	err := nemesis.Invoke(ctx, node, op.InvokeArgs...)
+	if err != nil {
 		log.Printf("run nemesis %s on %s failed: %v", op.Name, node, err)
 	}
+	c.recordNemesis(core.NemesisInvoke, op, node, op.InvokeArgs, start, err)
 
 	select {
 	case <-time.After(op.RunTime):
 	case <-ctx.Done():
 	}
-	if err := nemesis.Recover(ctx, node, op.RecoverArgs...); err != nil {
+	start = time.Now()
+	err = nemesis.Recover(ctx, node, op.RecoverArgs...)
+	if err != nil {
 		log.Printf("run nemesis %s on %s failed: %v", op.Name, node, err)
 	}
+	c.recordNemesis(core.NemesisRecover, op, node, op.RecoverArgs, start, err)
 }
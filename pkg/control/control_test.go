@@ -2,7 +2,10 @@ package control
 
 import (
 	"context"
+	"errors"
+	"io/ioutil"
 	"os"
+	"path"
 	"testing"
 	"time"
 
@@ -14,17 +17,22 @@ import (
 func TestControl(t *testing.T) {
 	t.Log("test can only be run in the chaos docker")
 
+	dir, err := ioutil.TempDir("", "chaos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
 	cfg := &Config{
 		RequestCount: 10,
 		RunTime:      10 * time.Second,
 		RunRound:     3,
+		Concurrency:  2,
 		DB:           "noop",
-		History:      "/tmp/chaos/a.log",
+		History:      path.Join(dir, "a.log"),
 		Nodes:        []string{"n1", "n2"},
 	}
 
-	defer os.Remove("/tmp/chaos/a.log")
-
 	ngs := []core.NemesisGenerator{
 		core.NoopNemesisGenerator{},
 	}
@@ -36,8 +44,118 @@ func TestControl(t *testing.T) {
 		Parser:  history.NoopParser{},
 	}
 	ctx, cancel := context.WithCancel(context.Background())
-	c := NewController(ctx, cfg, client, ngs, verifySuit)
-	c.Run()
+	c := NewController(ctx, cfg, client, ngs, []verify.Suit{verifySuit})
+	if summary := c.Run(); summary.Outcome != verify.Valid || len(summary.Results) != cfg.RunRound {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
 	c.Close()
 	cancel()
 }
+
+// failClientCreator creates clients which fail to set up on the second node.
+type failClientCreator struct {
+	core.NoopClientCreator
+}
+
+func (c failClientCreator) Create(node string) core.Client {
+	return failClient{Client: c.NoopClientCreator.Create(node)}
+}
+
+type failClient struct {
+	core.Client
+}
+
+func (c failClient) SetUp(ctx context.Context, nodes []string, node string) error {
+	if node == nodes[1] {
+		return errors.New("set up failed")
+	}
+	return c.Client.SetUp(ctx, nodes, node)
+}
+
+func (c failClient) TearDown(ctx context.Context, nodes []string, node string) error {
+	if node == nodes[1] {
+		panic("the client which failed to set up must not be torn down")
+	}
+	return c.Client.TearDown(ctx, nodes, node)
+}
+
+func TestControlSetUpFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := &Config{
+		RequestCount: 10,
+		RunTime:      10 * time.Second,
+		RunRound:     2,
+		DB:           "noop",
+		OutputDir:    dir,
+		Nodes:        []string{"n1", "n2"},
+	}
+
+	verifySuit := verify.Suit{
+		Model:   &core.NoopModel{},
+		Checker: core.NoopChecker{},
+		Parser:  history.NoopParser{},
+	}
+	c := NewController(context.Background(), cfg, failClientCreator{}, nil, []verify.Suit{verifySuit})
+	summary := c.Run()
+	c.Close()
+
+	if summary.Outcome != verify.Unknown || len(summary.Results) != 1 {
+		t.Fatalf("a failed set up must be unknown, got %+v", summary)
+	}
+	if _, err = os.Stat(path.Join(c.RunDir(), summaryFileName)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestControlRunDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := &Config{
+		RequestCount: 10,
+		RunTime:      10 * time.Second,
+		RunRound:     2,
+		DB:           "noop",
+		OutputDir:    dir,
+		Nodes:        []string{"n1", "n2"},
+	}
+
+	verifySuit := verify.Suit{
+		Model:   &core.NoopModel{},
+		Checker: core.NoopChecker{},
+		Parser:  history.NoopParser{},
+	}
+	c := NewController(context.Background(), cfg, core.NoopClientCreator{}, nil, []verify.Suit{verifySuit})
+	c.Run()
+	c.Close()
+
+	runDir := c.RunDir()
+	if path.Dir(runDir) != dir {
+		t.Fatalf("run directory %s must be in %s", runDir, dir)
+	}
+	for _, name := range []string{"history.log.1", "history.log.2", summaryFileName, logFileName, nemesisFileName} {
+		if _, err = os.Stat(path.Join(runDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if latest, err := os.Readlink(path.Join(dir, latestRunDir)); err != nil || latest != path.Base(runDir) {
+		t.Fatalf("latest must link to %s, got %s, %v", runDir, latest, err)
+	}
+
+	// The next run creates another directory.
+	next, err := newRunDir(dir, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next == runDir {
+		t.Fatalf("run directory %s is reused", runDir)
+	}
+}
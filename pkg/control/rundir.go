@@ -0,0 +1,129 @@
+package control
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/util/executor"
+)
+
+const (
+	runDirTimeFormat = "20060102-150405"
+	latestRunDir     = "latest"
+
+	historyFileName = "history.log"
+	summaryFileName = "summary.json"
+	logFileName     = "chaos.log"
+	nemesisFileName = "nemesis.log"
+	nodeLogDirName  = "logs"
+)
+
+// newRunDir creates a directory named by the start time in the output
+// directory, and points the latest link in the output directory to it.
+func newRunDir(outputDir string, start time.Time) (string, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", err
+	}
+
+	name := start.Format(runDirTimeFormat)
+	dir := path.Join(outputDir, name)
+	// Another run may start in the same second.
+	for i := 1; ; i++ {
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		name = fmt.Sprintf("%s-%d", start.Format(runDirTimeFormat), i)
+		dir = path.Join(outputDir, name)
+	}
+
+	// Use a relative link so the output directory can be moved or archived.
+	latest := path.Join(outputDir, latestRunDir)
+	os.Remove(latest)
+	if err := os.Symlink(name, latest); err != nil {
+		log.Printf("link %s to %s failed %v", latest, name, err)
+	}
+	return dir, nil
+}
+
+// runLogs are the log files in the run directory.
+type runLogs struct {
+	sync.Mutex
+	log     *os.File
+	nemesis *os.File
+}
+
+// openRunLogs opens the controller log and the nemesis log in the run directory,
+// the standard logger writes to both stderr and the controller log.
+func openRunLogs(dir string) (*runLogs, error) {
+	l := new(runLogs)
+	var err error
+	if l.log, err = os.Create(path.Join(dir, logFileName)); err != nil {
+		return nil, err
+	}
+	if l.nemesis, err = os.Create(path.Join(dir, nemesisFileName)); err != nil {
+		l.log.Close()
+		return nil, err
+	}
+
+	log.SetOutput(io.MultiWriter(os.Stderr, l.log))
+	return l, nil
+}
+
+// logNemesis writes a nemesis activity to the nemesis log.
+func (l *runLogs) logNemesis(kind string, op *core.NemesisOperation, node string, args []string, start time.Time, end time.Time, err error) {
+	status := "ok"
+	if err != nil {
+		status = err.Error()
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	fmt.Fprintf(l.nemesis, "%s\t%s\t%s\t%s\t%s\t[%s]\t%s\n",
+		start.Format(time.RFC3339Nano), end.Format(time.RFC3339Nano),
+		kind, op.Name, node, strings.Join(args, " "), status)
+}
+
+// close restores the standard logger and closes the log files.
+func (l *runLogs) close() {
+	log.SetOutput(os.Stderr)
+
+	l.Lock()
+	defer l.Unlock()
+	l.log.Close()
+	l.nemesis.Close()
+}
+
+// collectNodeLogs downloads the logs of the database on every node to
+// the logs directory in the run directory.
+func (c *Controller) collectNodeLogs() {
+	collector, ok := core.GetDB(c.cfg.DB).(core.LogCollector)
+	if !ok || len(c.runDir) == 0 {
+		return
+	}
+
+	log.Printf("begin to collect node logs")
+	c.syncExec(func(i int) {
+		node := c.cfg.Nodes[i]
+		dir := path.Join(c.runDir, nodeLogDirName, node)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Printf("create log directory %s failed %v", dir, err)
+			return
+		}
+		for _, name := range collector.LogFiles(node) {
+			if err := executor.Download(c.ctx, dir, node, name); err != nil {
+				log.Printf("collect log %s on node %s failed %v", name, node, err)
+			}
+		}
+	})
+}
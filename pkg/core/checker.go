@@ -1,20 +1,38 @@
 package core
 
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
 // Checker checks a history of operations.
 type Checker interface {
 	// Check a series of operations with the given model.
 	// Return false or error if operations do not satisfy the model.
-	Check(m Model, ops []Operation) (bool, error)
+	// A checker should stop when the context is done and return its error,
+	// e.g, context.DeadlineExceeded, then the history is unknown.
+	Check(ctx context.Context, m Model, ops []Operation) (bool, error)
 
 	// Name returns the unique name for the checker.
 	Name() string
 }
 
+// StreamChecker is a Checker which checks the operations in one pass, so
+// the history is checked while it is read, without holding it in memory.
+type StreamChecker interface {
+	Checker
+	// CheckStream checks the operations returned by next one by one, next
+	// returns false at the end of the history. The operations are completed
+	// like the ones passed to Check.
+	CheckStream(ctx context.Context, m Model, next func() (Operation, bool)) (bool, error)
+}
+
 // NoopChecker is a noop checker.
 type NoopChecker struct{}
 
 // Check impls Checker.
-func (NoopChecker) Check(m Model, ops []Operation) (bool, error) {
+func (NoopChecker) Check(ctx context.Context, m Model, ops []Operation) (bool, error) {
 	return true, nil
 }
 
@@ -22,3 +40,85 @@ func (NoopChecker) Check(m Model, ops []Operation) (bool, error) {
 func (NoopChecker) Name() string {
 	return "NoopChecker"
 }
+
+var checkers = map[string]func() Checker{}
+
+// RegisterChecker registers a function to create the named checker. Not thread-safe.
+func RegisterChecker(name string, newChecker func() Checker) {
+	if _, ok := checkers[name]; ok {
+		panic(fmt.Sprintf("checker %s is already registered", name))
+	}
+
+	checkers[name] = newChecker
+}
+
+// NewChecker creates the registered checker, returns nil if it is not registered.
+func NewChecker(name string) Checker {
+	newChecker, ok := checkers[name]
+	if !ok {
+		return nil
+	}
+	return newChecker()
+}
+
+// CheckerNames returns the sorted names of the registered checkers.
+func CheckerNames() []string {
+	names := make([]string, 0, len(checkers))
+	for name := range checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OnlineChecker checks the completed operations one by one while the test
+// runs, so a broken invariant aborts the run without waiting for the round
+// to end. It sees the requests and responses sent by the clients, in the
+// order they are recorded, and must be cheap.
+type OnlineChecker interface {
+	// Prepare sets the state dumped before the operations.
+	Prepare(state interface{})
+	// Step checks the completed operation, the response is nil if it is
+	// unknown. It returns an error describing the violation if the
+	// invariant is broken.
+	Step(req interface{}, resp interface{}) error
+
+	// Name returns the unique name for the checker.
+	Name() string
+}
+
+var onlineCheckers = map[string]func() OnlineChecker{}
+
+// RegisterOnlineChecker registers a function to create the named online
+// checker. Not thread-safe.
+func RegisterOnlineChecker(name string, newChecker func() OnlineChecker) {
+	if _, ok := onlineCheckers[name]; ok {
+		panic(fmt.Sprintf("online checker %s is already registered", name))
+	}
+
+	onlineCheckers[name] = newChecker
+}
+
+// NewOnlineChecker creates the registered online checker, returns nil if it
+// is not registered.
+func NewOnlineChecker(name string) OnlineChecker {
+	newChecker, ok := onlineCheckers[name]
+	if !ok {
+		return nil
+	}
+	return newChecker()
+}
+
+// OnlineCheckerNames returns the sorted names of the registered online checkers.
+func OnlineCheckerNames() []string {
+	names := make([]string, 0, len(onlineCheckers))
+	for name := range onlineCheckers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterChecker("noop", func() Checker { return NoopChecker{} })
+}
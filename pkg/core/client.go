@@ -28,7 +28,8 @@ type Client interface {
 }
 
 // ClientCreator creates a client.
-// The control will create one client for one node.
+// The control will create Concurrency clients for one node, all of
+// them are set up, so the initialization in SetUp should be idempotent.
 type ClientCreator interface {
 	// Create creates the client.
 	Create(node string) Client
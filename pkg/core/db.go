@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"sort"
 )
 
 // DB allows Chaos to set up and tear down database.
@@ -23,6 +24,19 @@ type DB interface {
 	Name() string
 }
 
+// LogCollector is implemented by a DB whose logs can be collected after a test.
+type LogCollector interface {
+	// LogFiles returns the log files of the database on the node.
+	LogFiles(node string) []string
+}
+
+// VersionedDB is implemented by a DB which reports its version, the version
+// is recorded in the history header.
+type VersionedDB interface {
+	// Version returns the version of the database on the node.
+	Version(ctx context.Context, node string) (string, error)
+}
+
 // NoopDB is a DB but does nothing
 type NoopDB struct {
 }
@@ -80,6 +94,16 @@ func GetDB(name string) DB {
 	return dbs[name]
 }
 
+// DBNames returns the sorted names of the registered databases.
+func DBNames() []string {
+	names := make([]string, 0, len(dbs))
+	for name := range dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func init() {
 	RegisterDB(NoopDB{})
 }
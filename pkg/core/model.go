@@ -1,5 +1,11 @@
 package core
 
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
 // Model specifies the behavior of a data object.
 type Model interface {
 	// Prepare the initial state of the data object.
@@ -22,6 +28,33 @@ type Model interface {
 	Name() string
 }
 
+// PartitionModel is a Model whose operations on different keys don't affect
+// each other, like a map of registers. The checkers may split the history by
+// key and check the operations of every key alone, which is much cheaper
+// than checking them as one state.
+type PartitionModel interface {
+	Model
+
+	// Partition returns the key of the operation by its request.
+	Partition(input interface{}) string
+
+	// PartitionInit returns the initial state of the key, it must be like
+	// the state returned by Init with only the key. It is called
+	// concurrently for different keys.
+	PartitionInit(key string) interface{}
+}
+
+// ReadOnlyModel is a Model which tells the requests that never change the
+// state, like reads. Removing them from a history keeps the states of the
+// other operations reachable, so an invalid history is shrunk by removing
+// only them.
+type ReadOnlyModel interface {
+	Model
+
+	// ReadOnly tells whether the request never changes the state.
+	ReadOnly(input interface{}) bool
+}
+
 // Operation action
 const (
 	InvokeOperation = "call"
@@ -33,6 +66,12 @@ type Operation struct {
 	Action string      `json:"action"`
 	Proc   int64       `json:"proc"`
 	Data   interface{} `json:"data"`
+	// Time is when the operation is invoked or returns, since the run starts.
+	Time time.Duration `json:"time"`
+	// Node is the node which the operation is sent to.
+	Node string `json:"node"`
+	// Client is the index of the client which issues the operation.
+	Client int `json:"client"`
 }
 
 // NoopModel is noop model.
@@ -64,3 +103,38 @@ func (*NoopModel) Equal(state1, state2 interface{}) bool {
 func (*NoopModel) Name() string {
 	return "NoopModel"
 }
+
+var models = map[string]func() Model{}
+
+// RegisterModel registers a function to create the named model, a model keeps
+// the prepared state so every verification needs a new one. Not thread-safe.
+func RegisterModel(name string, newModel func() Model) {
+	if _, ok := models[name]; ok {
+		panic(fmt.Sprintf("model %s is already registered", name))
+	}
+
+	models[name] = newModel
+}
+
+// NewModel creates the registered model, returns nil if it is not registered.
+func NewModel(name string) Model {
+	newModel, ok := models[name]
+	if !ok {
+		return nil
+	}
+	return newModel()
+}
+
+// ModelNames returns the sorted names of the registered models.
+func ModelNames() []string {
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterModel("noop", func() Model { return &NoopModel{} })
+}
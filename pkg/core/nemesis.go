@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 )
 
@@ -80,6 +81,29 @@ type NemesisOperation struct {
 	RunTime time.Duration
 }
 
+// Nemesis record kinds
+const (
+	NemesisInvoke  = "invoke"
+	NemesisRecover = "recover"
+)
+
+// NemesisRecord is a nemesis activity recorded in the history.
+type NemesisRecord struct {
+	// Kind is NemesisInvoke or NemesisRecover.
+	Kind string `json:"kind"`
+	// Nemesis name
+	Name string   `json:"name"`
+	Node string   `json:"node"`
+	Args []string `json:"args"`
+	// Start and End are when the nemesis starts and ends to invoke or recover,
+	// since the history starts. Start may be negative if the nemesis starts
+	// before the history.
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+	// Err is the error message if the nemesis fails.
+	Err string `json:"err,omitempty"`
+}
+
 // NemesisGenerator is used in control, it will generate a nemesis operation
 // and then the control can use it to disturb the cluster.
 type NemesisGenerator interface {
@@ -112,6 +136,16 @@ func (NoopNemesisGenerator) Generate(nodes []string) []*NemesisOperation {
 	return ops
 }
 
+// NemesisNames returns the sorted names of the registered nemeses.
+func NemesisNames() []string {
+	names := make([]string, 0, len(nemesises))
+	for name := range nemesises {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func init() {
 	RegisterNemesis(NoopNemesis{})
 }
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Workload is a named client test case of a database, with the default
+// components to verify its history.
+type Workload struct {
+	// DB is the name of the database which the workload runs on.
+	DB string
+	// Name is the unique name of the workload in the database.
+	Name string
+	// NewClientCreator returns a new creator of the workload clients. The
+	// creator is usually a pointer, so the workload parameters can be decoded
+	// into it.
+	NewClientCreator func() ClientCreator
+
+	// Model is the name of the registered model, empty if the checkers
+	// need no model.
+	Model string
+	// Parser is the name of the registered history parser.
+	Parser string
+	// Checkers are the names of the registered checkers run by default.
+	Checkers []string
+}
+
+var workloads = map[string]map[string]Workload{}
+
+// RegisterWorkload registers the workload. Not thread-safe.
+func RegisterWorkload(w Workload) {
+	dbWorkloads, ok := workloads[w.DB]
+	if !ok {
+		dbWorkloads = make(map[string]Workload)
+		workloads[w.DB] = dbWorkloads
+	}
+
+	if _, ok = dbWorkloads[w.Name]; ok {
+		panic(fmt.Sprintf("workload %s of db %s is already registered", w.Name, w.DB))
+	}
+
+	dbWorkloads[w.Name] = w
+}
+
+// GetWorkload gets the registered workload of the database.
+func GetWorkload(db string, name string) (Workload, bool) {
+	w, ok := workloads[db][name]
+	return w, ok
+}
+
+// Workloads returns the registered workloads of the database sorted by name,
+// or the workloads of all databases sorted by database and name if db is empty.
+func Workloads(db string) []Workload {
+	var ws []Workload
+	for dbName, dbWorkloads := range workloads {
+		if len(db) != 0 && db != dbName {
+			continue
+		}
+		for _, w := range dbWorkloads {
+			ws = append(ws, w)
+		}
+	}
+
+	sort.Slice(ws, func(i, j int) bool {
+		if ws[i].DB != ws[j].DB {
+			return ws[i].DB < ws[j].DB
+		}
+		return ws[i].Name < ws[j].Name
+	})
+	return ws
+}
@@ -0,0 +1,399 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EDN values are represented by Go values:
+//
+//	nil                 nil
+//	true, false         bool
+//	integers            int64
+//	floats              float64
+//	strings             string
+//	keywords            Keyword
+//	symbols             Symbol
+//	vectors, lists      []interface{}
+//	sets                []interface{}
+//	maps                EDNMap
+//
+// Tagged elements, like #inst "...", are read as the tagged value.
+
+// Keyword is an EDN keyword, written with a leading colon, like :read.
+type Keyword string
+
+// Symbol is an EDN symbol.
+type Symbol string
+
+// EDNEntry is an entry of an EDN map.
+type EDNEntry struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// EDNMap is an EDN map which keeps the order of its entries.
+type EDNMap []EDNEntry
+
+// Get returns the value of the key, or nil if the key does not exist.
+func (m EDNMap) Get(key interface{}) interface{} {
+	for _, e := range m {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return nil
+}
+
+// WriteEDNValue writes the value in EDN.
+func WriteEDNValue(w io.Writer, v interface{}) error {
+	var buf bytes.Buffer
+	if err := appendEDN(&buf, v); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func appendEDN(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("nil")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int:
+		buf.WriteString(strconv.Itoa(v))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case uint64:
+		buf.WriteString(strconv.FormatUint(v, 10))
+	case float64:
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eEn") {
+			// Keep it a float when read back.
+			s += ".0"
+		}
+		buf.WriteString(s)
+	case string:
+		appendEDNString(buf, v)
+	case Keyword:
+		buf.WriteByte(':')
+		buf.WriteString(string(v))
+	case Symbol:
+		buf.WriteString(string(v))
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			if err := appendEDN(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case EDNMap:
+		buf.WriteByte('{')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if err := appendEDN(buf, e.Key); err != nil {
+				return err
+			}
+			buf.WriteByte(' ')
+			if err := appendEDN(buf, e.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("can not write %T in edn", v)
+	}
+	return nil
+}
+
+// appendEDNString writes a quoted string, the escapes of Go like \x and \a
+// are not valid in EDN, so the control characters are written as \uXXXX.
+func appendEDNString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, c := range s {
+		switch {
+		case c == '"':
+			buf.WriteString(`\"`)
+		case c == '\\':
+			buf.WriteString(`\\`)
+		case c == '\n':
+			buf.WriteString(`\n`)
+		case c == '\t':
+			buf.WriteString(`\t`)
+		case c == '\r':
+			buf.WriteString(`\r`)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(buf, `\u%04x`, c)
+		default:
+			buf.WriteRune(c)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// EDNReader reads EDN values one by one.
+type EDNReader struct {
+	r *bufio.Reader
+	// line is the current line, for error messages.
+	line int
+}
+
+// NewEDNReader creates a reader of EDN values.
+func NewEDNReader(r io.Reader) *EDNReader {
+	return &EDNReader{r: bufio.NewReader(r), line: 1}
+}
+
+// Read reads the next value, it returns io.EOF if there are no more values.
+func (er *EDNReader) Read() (interface{}, error) {
+	v, err := er.readValue()
+	if err == errEDNClose {
+		return nil, er.errorf("unexpected closing delimiter")
+	}
+	return v, err
+}
+
+var errEDNClose = fmt.Errorf("edn closing delimiter")
+
+func (er *EDNReader) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("edn line %d: %s", er.line, fmt.Sprintf(format, args...))
+}
+
+func (er *EDNReader) readRune() (rune, error) {
+	c, _, err := er.r.ReadRune()
+	if c == '\n' {
+		er.line++
+	}
+	return c, err
+}
+
+func (er *EDNReader) unreadRune(c rune) {
+	er.r.UnreadRune()
+	if c == '\n' {
+		er.line--
+	}
+}
+
+// skipSpace skips whitespaces, commas and comments, and returns the next rune.
+func (er *EDNReader) skipSpace() (rune, error) {
+	for {
+		c, err := er.readRune()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case c == ',' || unicode.IsSpace(c):
+		case c == ';':
+			for c != '\n' {
+				if c, err = er.readRune(); err != nil {
+					return 0, err
+				}
+			}
+		default:
+			return c, nil
+		}
+	}
+}
+
+func (er *EDNReader) readValue() (interface{}, error) {
+	c, err := er.skipSpace()
+	if err != nil {
+		return nil, err
+	}
+
+	switch c {
+	case '[':
+		return er.readSeq(']')
+	case '(':
+		return er.readSeq(')')
+	case '{':
+		return er.readMap()
+	case ']', ')', '}':
+		return nil, errEDNClose
+	case '"':
+		return er.readString()
+	case ':':
+		token, err := er.readToken()
+		if err != nil {
+			return nil, err
+		}
+		return Keyword(token), nil
+	case '#':
+		c, err = er.readRune()
+		if err != nil {
+			return nil, er.errorf("unexpected end after #")
+		}
+		switch c {
+		case '{':
+			return er.readSeq('}')
+		case '_':
+			// Discard the next value.
+			if _, err = er.readValue(); err != nil {
+				return nil, err
+			}
+			return er.readValue()
+		default:
+			// A tagged element, read the tag and return the value.
+			er.unreadRune(c)
+			if _, err = er.readToken(); err != nil {
+				return nil, err
+			}
+			return er.readValue()
+		}
+	case '\\':
+		token, err := er.readToken()
+		if err != nil {
+			return nil, err
+		}
+		return token, nil
+	}
+
+	er.unreadRune(c)
+	token, err := er.readToken()
+	if err != nil {
+		return nil, err
+	}
+	return er.parseToken(token)
+}
+
+func (er *EDNReader) readSeq(end rune) ([]interface{}, error) {
+	seq := []interface{}{}
+	for {
+		v, err := er.readValue()
+		if err == errEDNClose {
+			return seq, nil
+		}
+		if err == io.EOF {
+			return nil, er.errorf("missing %c", end)
+		}
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, v)
+	}
+}
+
+func (er *EDNReader) readMap() (EDNMap, error) {
+	seq, err := er.readSeq('}')
+	if err != nil {
+		return nil, err
+	}
+	if len(seq)%2 != 0 {
+		return nil, er.errorf("map has odd number of forms")
+	}
+	m := make(EDNMap, 0, len(seq)/2)
+	for i := 0; i < len(seq); i += 2 {
+		m = append(m, EDNEntry{Key: seq[i], Value: seq[i+1]})
+	}
+	return m, nil
+}
+
+func (er *EDNReader) readString() (string, error) {
+	var b strings.Builder
+	for {
+		c, err := er.readRune()
+		if err != nil {
+			return "", er.errorf("unterminated string")
+		}
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if c, err = er.readRune(); err != nil {
+				return "", er.errorf("unterminated string")
+			}
+			switch c {
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			case 'r':
+				b.WriteRune('\r')
+			case 'u':
+				var hex [4]rune
+				for i := range hex {
+					if hex[i], err = er.readRune(); err != nil {
+						return "", er.errorf("unterminated string")
+					}
+				}
+				u, err := strconv.ParseUint(string(hex[:]), 16, 16)
+				if err != nil {
+					return "", er.errorf("invalid unicode escape \\u%s", string(hex[:]))
+				}
+				b.WriteRune(rune(u))
+			default:
+				b.WriteRune(c)
+			}
+		default:
+			b.WriteRune(c)
+		}
+	}
+}
+
+// readToken reads until a delimiter.
+func (er *EDNReader) readToken() (string, error) {
+	var b strings.Builder
+	for {
+		c, err := er.readRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if unicode.IsSpace(c) || strings.ContainsRune(",;()[]{}\"", c) {
+			er.unreadRune(c)
+			break
+		}
+		b.WriteRune(c)
+	}
+	if b.Len() == 0 {
+		return "", er.errorf("empty token")
+	}
+	return b.String(), nil
+}
+
+func (er *EDNReader) parseToken(token string) (interface{}, error) {
+	switch token {
+	case "nil":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	c := token[0]
+	if c >= '0' && c <= '9' || (len(token) > 1 && (c == '-' || c == '+') && token[1] >= '0' && token[1] <= '9') {
+		// Drop the arbitrary precision suffixes.
+		num := strings.TrimRight(token, "NM")
+		if i, err := strconv.ParseInt(num, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(num, 64); err == nil {
+			return f, nil
+		}
+		return nil, er.errorf("invalid number %s", token)
+	}
+	return Symbol(token), nil
+}
+
+// EDNInt returns the integer value, it is an error if v is not an integer.
+func EDNInt(v interface{}) (int64, error) {
+	i, ok := v.(int64)
+	if !ok {
+		return 0, fmt.Errorf("%v is not an integer", v)
+	}
+	return i, nil
+}
@@ -0,0 +1,70 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// FormatVersion is the version of the history format written by Recorder.
+// Histories without a header are version 0, they are still readable.
+const FormatVersion = 1
+
+// headerOperation records the Header, it is the first record of a history.
+const headerOperation = "header"
+
+// Header describes how a history is produced.
+type Header struct {
+	// Version is the history format version.
+	Version int `json:"version"`
+	// DB is the name of the tested database, and DBVersion is its version
+	// if the database reports it.
+	DB        string `json:"db,omitempty"`
+	DBVersion string `json:"db_version,omitempty"`
+	// Workload is the name of the client test case, it selects the model,
+	// parser and checkers to verify the history.
+	Workload string   `json:"workload,omitempty"`
+	Nodes    []string `json:"nodes,omitempty"`
+	// Nemeses are the names of the nemesis generators.
+	Nemeses []string `json:"nemeses,omitempty"`
+	// Seed is the seed of the random source of the controller and nemeses.
+	Seed int64 `json:"seed"`
+	// Round is the round of the run which produces the history.
+	Round int `json:"round,omitempty"`
+	// Start is when the history starts, the time of every operation is
+	// relative to it.
+	Start time.Time `json:"start"`
+}
+
+// checkVersion refuses a history written in a newer format.
+func (h *Header) checkVersion() error {
+	if h.Version > FormatVersion {
+		return fmt.Errorf("history format version %d is not supported, the latest supported version is %d", h.Version, FormatVersion)
+	}
+	return nil
+}
+
+// ReadHeader reads the header of a history file, it returns a zero Header
+// for a history written before the header is added.
+func ReadHeader(historyFile string) (*Header, error) {
+	r, err := openHistoryReader(historyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	h := new(Header)
+	var record opRecord
+	ok, err := r.scan(&record)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || record.Action != headerOperation {
+		return h, nil
+	}
+
+	if err = json.Unmarshal(record.Data, h); err != nil {
+		return nil, err
+	}
+	return h, h.checkVersion()
+}
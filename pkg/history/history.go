@@ -1,36 +1,61 @@
 package history
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/pingcap/chaos/pkg/core"
 )
 
 // opRecord is similar to core.Operation, but it stores data in json.RawMessage
 // instead of interface{} in order to marshal into bytes.
+// Histories written before Time, Node and Client are added are still readable,
+// these fields are zero values then.
 type opRecord struct {
 	Action string          `json:"action"`
 	Proc   int64           `json:"proc"`
 	Data   json.RawMessage `json:"data"`
+	Time   time.Duration   `json:"time,omitempty"`
+	Node   string          `json:"node,omitempty"`
+	Client int             `json:"client,omitempty"`
 }
 
 // TODO: different operation for initial state and final state.
 const dumpOperation = "dump"
 
-// Recorder records operation history.
+// nemesisOperation records a core.NemesisRecord, RecordParser never sees it.
+const nemesisOperation = "nemesis"
+
+// Recorder records operation history. The records are buffered, and
+// compressed if the history file ends with .gz or .zst, so the history
+// file is complete only after Close, unless the sync policy writes every
+// record.
 type Recorder struct {
 	sync.Mutex
-	f *os.File
+	w        *historyWriter
+	policy   SyncPolicy
+	observer Observer
+	// start is when the recorder is created. time.Since(start) uses the
+	// monotonic clock, so the recorded time is not affected by clock adjustment.
+	start time.Time
+}
+
+// NewRecorder creates a recorder to log the history to the file, the
+// header is written as the first record with the format version and
+// the start time filled.
+func NewRecorder(name string, header Header) (*Recorder, error) {
+	return NewRecorderWithSync(name, header, SyncNone)
 }
 
-// NewRecorder creates a recorder to log the history to the file.
-func NewRecorder(name string) (*Recorder, error) {
+// NewRecorderWithSync creates a recorder which syncs every record by the
+// policy, see NewRecorder.
+func NewRecorderWithSync(name string, header Header, policy SyncPolicy) (*Recorder, error) {
 	os.MkdirAll(path.Dir(name), 0755)
 
 	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
@@ -38,59 +63,169 @@ func NewRecorder(name string) (*Recorder, error) {
 		return nil, err
 	}
 
-	return &Recorder{f: f}, nil
+	w, err := newHistoryWriter(f, CompressionOf(name))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	r := &Recorder{w: w, policy: policy, start: time.Now()}
+	header.Version = FormatVersion
+	header.Start = r.start
+	if err = r.record(0, "", 0, headerOperation, header); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return r, nil
 }
 
-// Close closes the recorder.
-func (r *Recorder) Close() {
-	r.f.Close()
+// Observer sees the dumped states and the operations in the order they are
+// recorded, the data are the ones given to the Recorder, not marshaled. It
+// is called with the recorder locked, so it must be cheap.
+type Observer interface {
+	OnState(state interface{})
+	OnOperation(op core.Operation)
+}
+
+// Observe sets the observer of the following records.
+func (r *Recorder) Observe(o Observer) {
+	r.Lock()
+	defer r.Unlock()
+	r.observer = o
+}
+
+// Start returns the wall-clock time when the recorder is created. The time of
+// every recorded operation is relative to it.
+func (r *Recorder) Start() time.Time {
+	return r.start
+}
+
+// Close flushes the buffered records and closes the recorder.
+func (r *Recorder) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	return r.w.Close()
 }
 
 // RecordState records the request.
 func (r *Recorder) RecordState(state interface{}) error {
-	return r.record(0, dumpOperation, state)
+	return r.record(0, "", 0, dumpOperation, state)
+}
+
+// RecordNemesis records a nemesis activity.
+func (r *Recorder) RecordNemesis(record core.NemesisRecord) error {
+	return r.record(0, record.Node, 0, nemesisOperation, record)
 }
 
-// RecordRequest records the request.
-func (r *Recorder) RecordRequest(proc int64, op interface{}) error {
-	return r.record(proc, core.InvokeOperation, op)
+// RecordRequest records the request which the client sends to the node.
+func (r *Recorder) RecordRequest(proc int64, node string, client int, op interface{}) error {
+	return r.record(proc, node, client, core.InvokeOperation, op)
 }
 
-// RecordResponse records the response.
-func (r *Recorder) RecordResponse(proc int64, op interface{}) error {
-	return r.record(proc, core.ReturnOperation, op)
+// RecordResponse records the response which the client receives from the node.
+func (r *Recorder) RecordResponse(proc int64, node string, client int, op interface{}) error {
+	return r.record(proc, node, client, core.ReturnOperation, op)
+}
+
+func (r *Recorder) record(proc int64, node string, client int, action string, op interface{}) error {
+	// Marshal the op before waiting for the lock.
+	raw, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	// Take the time with the lock, so the records are written in time order.
+	t := time.Since(r.start)
+	data, err := marshalRecord(action, proc, json.RawMessage(raw), t, node, client)
+	if err != nil {
+		return err
+	}
+	if _, err = r.w.Write(data); err != nil {
+		return err
+	}
+	if r.observer != nil {
+		switch action {
+		case dumpOperation:
+			r.observer.OnState(op)
+		case core.InvokeOperation, core.ReturnOperation:
+			r.observer.OnOperation(core.Operation{
+				Action: action,
+				Proc:   proc,
+				Data:   op,
+				Time:   t,
+				Node:   node,
+				Client: client,
+			})
+		}
+	}
+	return r.w.Sync(r.policy)
 }
 
-func (r *Recorder) record(proc int64, action string, op interface{}) error {
+// marshalRecord marshals the record as a line of the history file.
+func marshalRecord(action string, proc int64, op interface{}, t time.Duration, node string, client int) ([]byte, error) {
 	// Marshal the op to json in order to store it in a history file.
 	data, err := json.Marshal(op)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	v := opRecord{
 		Action: action,
 		Proc:   proc,
 		Data:   json.RawMessage(data),
+		Time:   t,
+		Node:   node,
+		Client: client,
 	}
 
 	data, err = json.Marshal(v)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return append(data, '\n'), nil
+}
 
-	r.Lock()
-	defer r.Unlock()
+// WriteHistory writes the header, the dumped state if it is not nil, and
+// the operations to a history file compressed by its extension, like a
+// Recorder does, so a part of a history can be kept and verified.
+func WriteHistory(name string, header Header, state interface{}, ops []core.Operation) error {
+	os.MkdirAll(path.Dir(name), 0755)
 
-	if _, err = r.f.Write(data); err != nil {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
 		return err
 	}
-
-	if _, err = r.f.WriteString("\n"); err != nil {
+	w, err := newHistoryWriter(f, CompressionOf(name))
+	if err != nil {
+		f.Close()
 		return err
 	}
 
-	return nil
+	header.Version = FormatVersion
+	data, err := marshalRecord(headerOperation, 0, header, 0, "", 0)
+	if err == nil {
+		_, err = w.Write(data)
+	}
+	if err == nil && state != nil {
+		if data, err = marshalRecord(dumpOperation, 0, state, 0, "", 0); err == nil {
+			_, err = w.Write(data)
+		}
+	}
+	for _, op := range ops {
+		if err != nil {
+			break
+		}
+		if data, err = marshalRecord(op.Action, op.Proc, op.Data, op.Time, op.Node, op.Client); err == nil {
+			_, err = w.Write(data)
+		}
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return err
 }
 
 // RecordParser is to parses the operation data.
@@ -109,53 +244,173 @@ type RecordParser interface {
 	OnState(state json.RawMessage) (interface{}, error)
 }
 
-// ReadHistory reads operations and a model state from a history file.
-func ReadHistory(historyFile string, p RecordParser) ([]core.Operation, interface{}, error) {
-	f, err := os.Open(historyFile)
+// Iterator reads the operations of a history file one by one, so a
+// checker which needs only one pass does not hold the whole history.
+type Iterator struct {
+	r      *historyReader
+	p      RecordParser
+	header Header
+	op     core.Operation
+	state  interface{}
+	err    error
+}
+
+// NewIterator creates an iterator of the history file, which may be compressed.
+func NewIterator(historyFile string, p RecordParser) (*Iterator, error) {
+	r, err := openHistoryReader(historyFile)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	defer f.Close()
+	return &Iterator{r: r, p: p}, nil
+}
 
-	var state interface{}
-	ops := make([]core.Operation, 0, 1024)
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		var record opRecord
-		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
-			return nil, nil, err
+// Next advances to the next operation, it returns false at the end of
+// the history or on error.
+func (it *Iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	var record opRecord
+	for {
+		var ok bool
+		if ok, it.err = it.r.scan(&record); !ok {
+			return false
 		}
 
 		var data interface{}
-		if record.Action == core.InvokeOperation {
-			if data, err = p.OnRequest(record.Data); err != nil {
-				return nil, nil, err
+		switch record.Action {
+		case core.InvokeOperation:
+			data, it.err = it.p.OnRequest(record.Data)
+		case core.ReturnOperation:
+			data, it.err = it.p.OnResponse(record.Data)
+		case dumpOperation:
+			// A dumped state is not an operation.
+			if it.state, it.err = it.p.OnState(record.Data); it.err != nil {
+				return false
 			}
-		} else if record.Action == core.ReturnOperation {
-			if data, err = p.OnResponse(record.Data); err != nil {
-				return nil, nil, err
+			continue
+		case headerOperation:
+			if it.err = json.Unmarshal(record.Data, &it.header); it.err != nil {
+				return false
 			}
-		} else {
-			if state, err = p.OnState(record.Data); err != nil {
-				return nil, nil, err
+			if it.err = it.header.checkVersion(); it.err != nil {
+				return false
 			}
-			// A dumped state is not an operation.
+			continue
+		default:
+			// Nemesis records are read by ReadNemesisHistory.
 			continue
 		}
+		if it.err != nil {
+			return false
+		}
 
-		op := core.Operation{
+		it.op = core.Operation{
 			Action: record.Action,
 			Proc:   record.Proc,
 			Data:   data,
+			Time:   record.Time,
+			Node:   record.Node,
+			Client: record.Client,
 		}
-		ops = append(ops, op)
+		return true
 	}
+}
+
+// Header returns the header of the history, it is read before the first
+// operation, and is a zero Header if the history has no header.
+func (it *Iterator) Header() Header {
+	return it.header
+}
+
+// Operation returns the current operation.
+func (it *Iterator) Operation() core.Operation {
+	return it.op
+}
+
+// State returns the last dumped state read so far, the initial state is
+// dumped before any operation.
+func (it *Iterator) State() interface{} {
+	return it.state
+}
+
+// Truncated tells whether the history ends with a partial record, which is
+// skipped, e.g, the recorder is killed. It is known at the end of the history.
+func (it *Iterator) Truncated() bool {
+	return it.r.truncated
+}
+
+// Line returns the line number of the last record read, it is the line of
+// the partial record if the history is truncated.
+func (it *Iterator) Line() int {
+	return it.r.line
+}
 
-	if err = scanner.Err(); err != nil {
+// Err returns the error which stops the iteration.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Close closes the history file.
+func (it *Iterator) Close() error {
+	return it.r.Close()
+}
+
+// ReadHistory reads operations and a model state from a history file.
+// A history written in a newer format version is refused, use ReadHeader
+// to get the header before choosing the parser. A truncated history is
+// read up to the partial record, and reported in the log.
+func ReadHistory(historyFile string, p RecordParser) ([]core.Operation, interface{}, error) {
+	it, err := NewIterator(historyFile, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer it.Close()
+
+	ops := make([]core.Operation, 0, 1024)
+	for it.Next() {
+		ops = append(ops, it.Operation())
+	}
+	if err = it.Err(); err != nil {
 		return nil, nil, err
 	}
+	if it.Truncated() {
+		log.Printf("history %s is truncated, the partial record at line %d is skipped", historyFile, it.Line())
+	}
+
+	return ops, it.State(), nil
+}
+
+// ReadNemesisHistory reads the nemesis activities from a history file.
+func ReadNemesisHistory(historyFile string) ([]core.NemesisRecord, error) {
+	r, err := openHistoryReader(historyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var records []core.NemesisRecord
+	var record opRecord
+	for {
+		ok, err := r.scan(&record)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return records, nil
+		}
 
-	return ops, state, nil
+		if record.Action != nemesisOperation {
+			continue
+		}
+
+		var n core.NemesisRecord
+		if err = json.Unmarshal(record.Data, &n); err != nil {
+			return nil, err
+		}
+		records = append(records, n)
+	}
 }
 
 // int64Slice attaches the methods of Interface to []int, sorting in increasing order.
@@ -166,43 +421,114 @@ func (p int64Slice) Less(i, j int) bool { return p[i] < p[j] }
 func (p int64Slice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
 
 // CompleteOperations completes the history of operation.
+// A pending operation is completed with a noop response at the end of the history.
 func CompleteOperations(ops []core.Operation, p RecordParser) ([]core.Operation, error) {
-	procID := map[int64]struct{}{}
+	c := newCompleter(p)
 	compOps := make([]core.Operation, 0, len(ops))
 	for _, op := range ops {
-		if op.Action == core.InvokeOperation {
-			if _, ok := procID[op.Proc]; ok {
-				return nil, fmt.Errorf("missing return, op: %v", op)
-			}
-			procID[op.Proc] = struct{}{}
+		ok, err := c.add(op)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
 			compOps = append(compOps, op)
-		} else {
-			if _, ok := procID[op.Proc]; !ok {
-				return nil, fmt.Errorf("missing invoke, op: %v", op)
+		}
+	}
+	return append(compOps, c.finish()...), nil
+}
+
+// CompleteIterator completes the operations of the iterator like
+// CompleteOperations while they are read. The returned next returns the
+// completed operations one by one, and false at the end of the history or
+// on error, the error is returned by it.Err.
+func CompleteIterator(it *Iterator) (next func() (core.Operation, bool)) {
+	c := newCompleter(it.p)
+	var rest []core.Operation
+	done := false
+	return func() (core.Operation, bool) {
+		for !done {
+			if !it.Next() {
+				done = true
+				if it.err == nil {
+					rest = c.finish()
+				}
+				break
 			}
-			if op.Data == nil {
-				continue
+			op := it.Operation()
+			ok, err := c.add(op)
+			if err != nil {
+				it.err = err
+				done = true
+				break
 			}
-			delete(procID, op.Proc)
-			compOps = append(compOps, op)
+			if ok {
+				return op, true
+			}
+		}
+		if len(rest) == 0 {
+			return core.Operation{}, false
+		}
+		op := rest[0]
+		rest = rest[1:]
+		return op, true
+	}
+}
+
+// completer completes the operations one by one.
+type completer struct {
+	p       RecordParser
+	pending map[int64]core.Operation
+	end     time.Duration
+}
+
+func newCompleter(p RecordParser) *completer {
+	return &completer{p: p, pending: map[int64]core.Operation{}}
+}
+
+// add adds the next operation, it returns false if the operation is an
+// unknown response, which is dropped.
+func (c *completer) add(op core.Operation) (bool, error) {
+	if op.Time > c.end {
+		c.end = op.Time
+	}
+	if op.Action == core.InvokeOperation {
+		if _, ok := c.pending[op.Proc]; ok {
+			return false, fmt.Errorf("missing return, op: %v", op)
 		}
+		c.pending[op.Proc] = op
+		return true, nil
+	}
+	if _, ok := c.pending[op.Proc]; !ok {
+		return false, fmt.Errorf("missing invoke, op: %v", op)
 	}
+	if op.Data == nil {
+		return false, nil
+	}
+	delete(c.pending, op.Proc)
+	return true, nil
+}
 
+// finish returns the noop responses of the pending operations at the end
+// of the history.
+func (c *completer) finish() []core.Operation {
 	// To get a determined complete history of operations, we sort procIDs.
 	var keys []int64
-	for k := range procID {
+	for k := range c.pending {
 		keys = append(keys, k)
 	}
 	sort.Sort(int64Slice(keys))
 
+	ops := make([]core.Operation, 0, len(keys))
 	for _, proc := range keys {
-		op := core.Operation{
+		invoke := c.pending[proc]
+		ops = append(ops, core.Operation{
 			Action: core.ReturnOperation,
 			Proc:   proc,
-			Data:   p.OnNoopResponse(),
-		}
-		compOps = append(compOps, op)
+			Data:   c.p.OnNoopResponse(),
+			Time:   c.end,
+			Node:   invoke.Node,
+			Client: invoke.Client,
+		})
 	}
-
-	return compOps, nil
+	return ops
 }
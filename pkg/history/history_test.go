@@ -4,13 +4,15 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/pingcap/chaos/pkg/core"
 )
 
 func TestRecordAndReadHistory(t *testing.T) {
-	tmpDir, err := ioutil.TempDir(".", "var")
+	tmpDir, err := ioutil.TempDir("", "history")
 	if err != nil {
 		t.Fatalf("create temp dir failed %v", err)
 	}
@@ -19,13 +21,11 @@ func TestRecordAndReadHistory(t *testing.T) {
 
 	var r *Recorder
 	name := path.Join(tmpDir, "history.log")
-	r, err = NewRecorder(name)
+	r, err = NewRecorder(name, Header{})
 	if err != nil {
 		t.Fatalf("create recorder failed %v", err)
 	}
 
-	defer r.Close()
-
 	actions := []action{
 		{1, NoopRequest{Op: 0}},
 		{1, NoopResponse{Value: 10}},
@@ -39,11 +39,11 @@ func TestRecordAndReadHistory(t *testing.T) {
 	for _, action := range actions {
 		switch v := action.op.(type) {
 		case NoopRequest:
-			if err = r.RecordRequest(action.proc, v); err != nil {
+			if err = r.RecordRequest(action.proc, "n1", int(action.proc), v); err != nil {
 				t.Fatalf("record request failed %v", err)
 			}
 		case NoopResponse:
-			if err = r.RecordResponse(action.proc, v); err != nil {
+			if err = r.RecordResponse(action.proc, "n1", int(action.proc), v); err != nil {
 				t.Fatalf("record response failed %v", err)
 			}
 		}
@@ -51,6 +51,10 @@ func TestRecordAndReadHistory(t *testing.T) {
 	if err = r.RecordState(parserState); err != nil {
 		t.Fatalf("record dump failed %v", err)
 	}
+	// The records are buffered until the recorder is closed.
+	if err = r.Close(); err != nil {
+		t.Fatalf("close recorder failed %v", err)
+	}
 
 	ops, state, err := ReadHistory(name, NoopParser{State: parserState})
 	if err != nil {
@@ -66,6 +70,13 @@ func TestRecordAndReadHistory(t *testing.T) {
 	}
 
 	for idx, ac := range actions {
+		if ops[idx].Node != "n1" || ops[idx].Client != int(ac.proc) {
+			t.Fatalf("unexpected node or client: %#v", ops[idx])
+		}
+		if idx > 0 && ops[idx].Time < ops[idx-1].Time {
+			t.Fatalf("time must not go backwards: %#v, %#v", ops[idx-1], ops[idx])
+		}
+
 		switch v := ac.op.(type) {
 		case NoopRequest:
 			a, ok := ops[idx].Data.(NoopRequest)
@@ -87,6 +98,199 @@ func TestRecordAndReadHistory(t *testing.T) {
 	}
 }
 
+func TestRecordNemesis(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatalf("create temp dir failed %v", err)
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	name := path.Join(tmpDir, "history.log")
+	r, err := NewRecorder(name, Header{})
+	if err != nil {
+		t.Fatalf("create recorder failed %v", err)
+	}
+
+	records := []core.NemesisRecord{
+		{Kind: core.NemesisInvoke, Name: "kill", Node: "n1", Args: []string{"tidb"}, Start: 1, End: 2},
+		{Kind: core.NemesisRecover, Name: "kill", Node: "n1", Args: []string{"tidb"}, Start: 5, End: 6, Err: "start failed"},
+	}
+
+	r.RecordState(7)
+	r.RecordRequest(1, "n1", 0, NoopRequest{Op: 0})
+	r.RecordNemesis(records[0])
+	r.RecordResponse(1, "n1", 0, NoopResponse{Value: 10})
+	r.RecordNemesis(records[1])
+	r.Close()
+
+	// Parsers never see nemesis records.
+	ops, _, err := ReadHistory(name, NoopParser{State: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 {
+		t.Fatalf("expect 2 operations, got %v", ops)
+	}
+
+	nemesisRecords, err := ReadNemesisHistory(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nemesisRecords) != len(records) {
+		t.Fatalf("expect %v, got %v", records, nemesisRecords)
+	}
+	for i, record := range nemesisRecords {
+		if record.Kind != records[i].Kind || record.Name != records[i].Name || record.Node != records[i].Node ||
+			record.Start != records[i].Start || record.End != records[i].End || record.Err != records[i].Err ||
+			len(record.Args) != 1 || record.Args[0] != "tidb" {
+			t.Fatalf("expect %#v, got %#v", records[i], record)
+		}
+	}
+}
+
+func TestRecordInTimeOrder(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatalf("create temp dir failed %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	name := path.Join(tmpDir, "history.log")
+	r, err := NewRecorder(name, Header{})
+	if err != nil {
+		t.Fatalf("create recorder failed %v", err)
+	}
+	var wg sync.WaitGroup
+	for proc := int64(0); proc < 8; proc++ {
+		wg.Add(1)
+		go func(proc int64) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				r.RecordRequest(proc, "n1", 0, NoopRequest{Op: 0})
+				r.RecordResponse(proc, "n1", 0, NoopResponse{Value: i})
+			}
+		}(proc)
+	}
+	wg.Wait()
+	r.Close()
+
+	ops, _, err := ReadHistory(name, NoopParser{})
+	if err != nil {
+		t.Fatalf("read history failed %v", err)
+	}
+	for i := 1; i < len(ops); i++ {
+		if ops[i].Time < ops[i-1].Time {
+			t.Fatalf("operation %d at %s is recorded after %s", i, ops[i].Time, ops[i-1].Time)
+		}
+	}
+}
+
+func TestReadOldHistory(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatalf("create temp dir failed %v", err)
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	// A history written before time, node and client are recorded.
+	name := path.Join(tmpDir, "history.log")
+	data := `{"action":"call","proc":1,"data":{"Op":0,"Value":0}}
+{"action":"return","proc":1,"data":{"Value":10,"Ok":true,"Unknown":false}}
+{"action":"dump","proc":0,"data":7}
+`
+	if err = ioutil.WriteFile(name, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ops, _, err := ReadHistory(name, NoopParser{State: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}},
+		{Action: core.ReturnOperation, Proc: 1, Data: NoopResponse{Value: 10, Ok: true}},
+	}
+	if len(ops) != len(expect) {
+		t.Fatalf("expect %v, got %v", expect, ops)
+	}
+	for idx, op := range ops {
+		if op != expect[idx] {
+			t.Fatalf("expect %#v, got %#v", expect[idx], op)
+		}
+	}
+
+	// A history without header has a zero header.
+	h, err := ReadHeader(name)
+	if err != nil || h.Version != 0 || len(h.Workload) != 0 {
+		t.Fatalf("unexpected header %+v, err %v", h, err)
+	}
+}
+
+func TestHistoryHeader(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatalf("create temp dir failed %v", err)
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	name := path.Join(tmpDir, "history.log.gz")
+	header := Header{
+		DB:       "tidb",
+		Workload: "bank",
+		Nodes:    []string{"n1", "n2"},
+		Nemeses:  []string{"random_kill"},
+		Seed:     42,
+		Round:    3,
+	}
+	r, err := NewRecorder(name, header)
+	if err != nil {
+		t.Fatalf("create recorder failed %v", err)
+	}
+	r.RecordState(7)
+	r.RecordRequest(1, "n1", 0, NoopRequest{Op: 0})
+	r.RecordResponse(1, "n1", 0, NoopResponse{Value: 10})
+	r.Close()
+
+	h, err := ReadHeader(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Version != FormatVersion || h.DB != "tidb" || h.Workload != "bank" || len(h.Nodes) != 2 ||
+		h.Nemeses[0] != "random_kill" || h.Seed != 42 || h.Round != 3 || !h.Start.Equal(r.Start()) {
+		t.Fatalf("unexpected header %+v", h)
+	}
+
+	it, err := NewIterator(name, NoopParser{State: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for it.Next() {
+	}
+	it.Close()
+	if it.Err() != nil || it.Header().Workload != "bank" {
+		t.Fatalf("unexpected header %+v, err %v", it.Header(), it.Err())
+	}
+
+	// A history in a newer format is refused.
+	name = path.Join(tmpDir, "history.log")
+	data := `{"action":"header","proc":0,"data":{"version":100}}
+{"action":"call","proc":1,"data":{"Op":0,"Value":0}}
+`
+	if err = ioutil.WriteFile(name, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ReadHeader(name); err == nil {
+		t.Fatal("newer format version must be refused")
+	}
+	if _, _, err = ReadHistory(name, NoopParser{State: 7}); err == nil {
+		t.Fatal("newer format version must be refused")
+	}
+}
+
 func TestCompleteOperation(t *testing.T) {
 	cases := []struct {
 		ops     []core.Operation
@@ -95,80 +299,95 @@ func TestCompleteOperation(t *testing.T) {
 		// A complete history of operations.
 		{
 			ops: []core.Operation{
-				{core.InvokeOperation, 1, NoopRequest{Op: 0}},
-				{core.ReturnOperation, 1, NoopResponse{Value: 10}},
-				{core.InvokeOperation, 2, NoopRequest{Op: 1, Value: 15}},
-				{core.ReturnOperation, 2, NoopResponse{Value: 15}},
+				{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}},
+				{Action: core.ReturnOperation, Proc: 1, Data: NoopResponse{Value: 10}},
+				{Action: core.InvokeOperation, Proc: 2, Data: NoopRequest{Op: 1, Value: 15}},
+				{Action: core.ReturnOperation, Proc: 2, Data: NoopResponse{Value: 15}},
 			},
 			compOps: []core.Operation{
-				{core.InvokeOperation, 1, NoopRequest{Op: 0}},
-				{core.ReturnOperation, 1, NoopResponse{Value: 10}},
-				{core.InvokeOperation, 2, NoopRequest{Op: 1, Value: 15}},
-				{core.ReturnOperation, 2, NoopResponse{Value: 15}},
+				{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}},
+				{Action: core.ReturnOperation, Proc: 1, Data: NoopResponse{Value: 10}},
+				{Action: core.InvokeOperation, Proc: 2, Data: NoopRequest{Op: 1, Value: 15}},
+				{Action: core.ReturnOperation, Proc: 2, Data: NoopResponse{Value: 15}},
 			},
 		},
 		// A complete but repeated proc operations.
 		{
 			ops: []core.Operation{
-				{core.InvokeOperation, 1, NoopRequest{Op: 0}},
-				{core.ReturnOperation, 1, NoopResponse{Value: 10}},
-				{core.InvokeOperation, 2, NoopRequest{Op: 1, Value: 15}},
-				{core.ReturnOperation, 2, NoopResponse{Value: 15}},
-				{core.InvokeOperation, 1, NoopRequest{Op: 0}},
-				{core.ReturnOperation, 1, NoopResponse{Value: 15}},
+				{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}},
+				{Action: core.ReturnOperation, Proc: 1, Data: NoopResponse{Value: 10}},
+				{Action: core.InvokeOperation, Proc: 2, Data: NoopRequest{Op: 1, Value: 15}},
+				{Action: core.ReturnOperation, Proc: 2, Data: NoopResponse{Value: 15}},
+				{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}},
+				{Action: core.ReturnOperation, Proc: 1, Data: NoopResponse{Value: 15}},
 			},
 			compOps: []core.Operation{
-				{core.InvokeOperation, 1, NoopRequest{Op: 0}},
-				{core.ReturnOperation, 1, NoopResponse{Value: 10}},
-				{core.InvokeOperation, 2, NoopRequest{Op: 1, Value: 15}},
-				{core.ReturnOperation, 2, NoopResponse{Value: 15}},
-				{core.InvokeOperation, 1, NoopRequest{Op: 0}},
-				{core.ReturnOperation, 1, NoopResponse{Value: 15}},
+				{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}},
+				{Action: core.ReturnOperation, Proc: 1, Data: NoopResponse{Value: 10}},
+				{Action: core.InvokeOperation, Proc: 2, Data: NoopRequest{Op: 1, Value: 15}},
+				{Action: core.ReturnOperation, Proc: 2, Data: NoopResponse{Value: 15}},
+				{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}},
+				{Action: core.ReturnOperation, Proc: 1, Data: NoopResponse{Value: 15}},
 			},
 		},
 
 		// Pending requests.
 		{
 			ops: []core.Operation{
-				{core.InvokeOperation, 1, NoopRequest{Op: 0}},
-				{core.ReturnOperation, 1, nil},
+				{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}},
+				{Action: core.ReturnOperation, Proc: 1, Data: nil},
 			},
 			compOps: []core.Operation{
-				{core.InvokeOperation, 1, NoopRequest{Op: 0}},
-				{core.ReturnOperation, 1, NoopResponse{Unknown: true}},
+				{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}},
+				{Action: core.ReturnOperation, Proc: 1, Data: NoopResponse{Unknown: true}},
 			},
 		},
 
 		// Missing a response
 		{
 			ops: []core.Operation{
-				{core.InvokeOperation, 1, NoopRequest{Op: 0}},
+				{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}},
 			},
 			compOps: []core.Operation{
-				{core.InvokeOperation, 1, NoopRequest{Op: 0}},
-				{core.ReturnOperation, 1, NoopResponse{Unknown: true}},
+				{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}},
+				{Action: core.ReturnOperation, Proc: 1, Data: NoopResponse{Unknown: true}},
+			},
+		},
+
+		// A pending request is completed at the end of the history.
+		{
+			ops: []core.Operation{
+				{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}, Time: 10, Node: "n1", Client: 1},
+				{Action: core.InvokeOperation, Proc: 2, Data: NoopRequest{Op: 0}, Time: 20, Node: "n2", Client: 2},
+				{Action: core.ReturnOperation, Proc: 2, Data: NoopResponse{Value: 10}, Time: 30, Node: "n2", Client: 2},
+			},
+			compOps: []core.Operation{
+				{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}, Time: 10, Node: "n1", Client: 1},
+				{Action: core.InvokeOperation, Proc: 2, Data: NoopRequest{Op: 0}, Time: 20, Node: "n2", Client: 2},
+				{Action: core.ReturnOperation, Proc: 2, Data: NoopResponse{Value: 10}, Time: 30, Node: "n2", Client: 2},
+				{Action: core.ReturnOperation, Proc: 1, Data: NoopResponse{Unknown: true}, Time: 30, Node: "n1", Client: 1},
 			},
 		},
 
 		// A complex out of order history.
 		{
 			ops: []core.Operation{
-				{core.InvokeOperation, 1, NoopRequest{Op: 0}},
-				{core.InvokeOperation, 3, NoopRequest{Op: 0}},
-				{core.InvokeOperation, 2, NoopRequest{Op: 1, Value: 15}},
-				{core.ReturnOperation, 2, nil},
-				{core.InvokeOperation, 4, NoopRequest{Op: 1, Value: 16}},
-				{core.ReturnOperation, 3, nil},
+				{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}},
+				{Action: core.InvokeOperation, Proc: 3, Data: NoopRequest{Op: 0}},
+				{Action: core.InvokeOperation, Proc: 2, Data: NoopRequest{Op: 1, Value: 15}},
+				{Action: core.ReturnOperation, Proc: 2, Data: nil},
+				{Action: core.InvokeOperation, Proc: 4, Data: NoopRequest{Op: 1, Value: 16}},
+				{Action: core.ReturnOperation, Proc: 3, Data: nil},
 			},
 			compOps: []core.Operation{
-				{core.InvokeOperation, 1, NoopRequest{Op: 0}},
-				{core.InvokeOperation, 3, NoopRequest{Op: 0}},
-				{core.InvokeOperation, 2, NoopRequest{Op: 1, Value: 15}},
-				{core.InvokeOperation, 4, NoopRequest{Op: 1, Value: 16}},
-				{core.ReturnOperation, 1, NoopResponse{Unknown: true}},
-				{core.ReturnOperation, 2, NoopResponse{Unknown: true}},
-				{core.ReturnOperation, 3, NoopResponse{Unknown: true}},
-				{core.ReturnOperation, 4, NoopResponse{Unknown: true}},
+				{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 0}},
+				{Action: core.InvokeOperation, Proc: 3, Data: NoopRequest{Op: 0}},
+				{Action: core.InvokeOperation, Proc: 2, Data: NoopRequest{Op: 1, Value: 15}},
+				{Action: core.InvokeOperation, Proc: 4, Data: NoopRequest{Op: 1, Value: 16}},
+				{Action: core.ReturnOperation, Proc: 1, Data: NoopResponse{Unknown: true}},
+				{Action: core.ReturnOperation, Proc: 2, Data: NoopResponse{Unknown: true}},
+				{Action: core.ReturnOperation, Proc: 3, Data: NoopResponse{Unknown: true}},
+				{Action: core.ReturnOperation, Proc: 4, Data: NoopResponse{Unknown: true}},
 			},
 		},
 	}
@@ -185,3 +404,141 @@ func TestCompleteOperation(t *testing.T) {
 		}
 	}
 }
+
+func TestCompressedHistory(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatalf("create temp dir failed %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, c := range []Compression{NoCompression, Gzip, Zstd} {
+		name := path.Join(tmpDir, "history.log"+c.Ext())
+		r, err := NewRecorder(name, Header{})
+		if err != nil {
+			t.Fatalf("create recorder failed %v", err)
+		}
+		r.RecordState(7)
+		for i := int64(1); i <= 100; i++ {
+			r.RecordRequest(i, "n1", 0, NoopRequest{Op: 1, Value: int(i)})
+			r.RecordResponse(i, "n1", 0, NoopResponse{Value: int(i)})
+		}
+		if err = r.Close(); err != nil {
+			t.Fatalf("close recorder failed %v", err)
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		magic := make([]byte, 4)
+		f.Read(magic)
+		f.Close()
+		if compressed := magic[0] != '{'; compressed != (c != NoCompression) {
+			t.Fatalf("%s: unexpected magic %v", name, magic)
+		}
+
+		// Read the history without telling the compression.
+		it, err := NewIterator(name, NoopParser{State: 7})
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for it.Next() {
+			op := it.Operation()
+			if op.Proc != int64(n/2+1) {
+				t.Fatalf("%s: unexpected operation %#v at %d", name, op, n)
+			}
+			n++
+		}
+		if err = it.Err(); err != nil {
+			t.Fatal(err)
+		}
+		it.Close()
+		if n != 200 || it.State() != 7 {
+			t.Fatalf("%s: expect 200 operations and state 7, got %d and %v", name, n, it.State())
+		}
+	}
+
+	if _, err = ParseCompression("lz4"); err == nil {
+		t.Fatal("unknown compression must fail")
+	}
+}
+
+func TestTruncatedHistory(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatalf("create temp dir failed %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, c := range []Compression{NoCompression, Gzip, Zstd} {
+		name := path.Join(tmpDir, "history.log"+c.Ext())
+		r, err := NewRecorderWithSync(name, Header{}, SyncFlush)
+		if err != nil {
+			t.Fatalf("create recorder failed %v", err)
+		}
+		r.RecordState(7)
+		for i := int64(1); i <= 10; i++ {
+			r.RecordRequest(i, "n1", 0, NoopRequest{Op: 1, Value: int(i)})
+			r.RecordResponse(i, "n1", 0, NoopResponse{Value: int(i)})
+		}
+		r.RecordRequest(11, "n1", 0, NoopRequest{Op: 1, Value: 11})
+		// The recorder is killed without Close.
+		if c == NoCompression {
+			f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.WriteString(`{"action":"return","proc":11,"da`)
+			f.Close()
+		}
+
+		it, err := NewIterator(name, NoopParser{State: 7})
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for it.Next() {
+			n++
+		}
+		if err = it.Err(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if n != 21 || it.State() != 7 || !it.Truncated() {
+			t.Fatalf("%s: expect 21 operations, state 7 and truncated, got %d, %v and %v", name, n, it.State(), it.Truncated())
+		}
+		it.Close()
+
+		ops, _, err := ReadHistory(name, NoopParser{State: 7})
+		if err != nil || len(ops) != 21 {
+			t.Fatalf("%s: expect 21 operations, got %d, %v", name, len(ops), err)
+		}
+		r.Close()
+
+		// A closed history is not truncated.
+		if c != NoCompression {
+			it, _ = NewIterator(name, NoopParser{State: 7})
+			for it.Next() {
+			}
+			if it.Err() != nil || it.Truncated() {
+				t.Fatalf("%s: unexpected %v, truncated %v", name, it.Err(), it.Truncated())
+			}
+			it.Close()
+		}
+	}
+
+	// A broken record in the middle is still an error.
+	name := path.Join(tmpDir, "broken.log")
+	ioutil.WriteFile(name, []byte("{\"action\":\"call\",\"proc\":1,\"data\":{}}\n{\"action\n{\"action\":\"return\",\"proc\":1,\"data\":{}}\n"), 0644)
+	if _, _, err = ReadHistory(name, NoopParser{}); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("expect an error at line 2, got %v", err)
+	}
+
+	if policy, err := ParseSyncPolicy(""); err != nil || policy != SyncNone {
+		t.Fatalf("the default sync policy must be none, got %s %v", policy, err)
+	}
+	if _, err = ParseSyncPolicy("always"); err == nil {
+		t.Fatal("unknown sync policy must fail")
+	}
+}
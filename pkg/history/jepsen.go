@@ -0,0 +1,278 @@
+package history
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/core"
+)
+
+// Types of Jepsen operations.
+const (
+	EDNInvoke Keyword = "invoke"
+	EDNOk     Keyword = "ok"
+	EDNFail   Keyword = "fail"
+	// EDNInfo means the result of the operation is unknown.
+	EDNInfo Keyword = "info"
+)
+
+// EDNCodec converts the requests and responses of a workload to and from
+// the :f and :value of Jepsen operations, e.g, Knossos and Elle read the
+// histories. It must be thread-safe.
+type EDNCodec interface {
+	// EncodeRequest returns the :f and :value of the invoke operation.
+	EncodeRequest(req interface{}) (Keyword, interface{}, error)
+	// EncodeResponse returns the :type, which is :ok or :fail, and the
+	// :value of the completion of the request. An unknown response is
+	// written as :info without calling the codec.
+	EncodeResponse(req interface{}, resp interface{}) (Keyword, interface{}, error)
+	// DecodeRequest decodes the :f and :value of an invoke operation.
+	DecodeRequest(f Keyword, value interface{}) (interface{}, error)
+	// DecodeResponse decodes an :ok or :fail completion of the request.
+	// An :info completion is decoded as a nil response, like the
+	// RecordParser does for an unknown response.
+	DecodeResponse(typ Keyword, req interface{}, value interface{}) (interface{}, error)
+}
+
+var ednCodecs = map[string]EDNCodec{}
+
+// RegisterEDNCodec registers the codec for the histories read by the parser
+// with the same name. Not thread-safe.
+func RegisterEDNCodec(name string, c EDNCodec) {
+	if _, ok := ednCodecs[name]; ok {
+		panic(fmt.Sprintf("edn codec %s is already registered", name))
+	}
+
+	ednCodecs[name] = c
+}
+
+// GetEDNCodec gets the registered codec.
+func GetEDNCodec(name string) EDNCodec {
+	return ednCodecs[name]
+}
+
+// EDNCodecNames returns the sorted names of the registered codecs.
+func EDNCodecNames() []string {
+	names := make([]string, 0, len(ednCodecs))
+	for name := range ednCodecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isUnknown tells whether the response is unknown, the parsers return
+// nil for an unknown response.
+func isUnknown(resp interface{}) bool {
+	if resp == nil {
+		return true
+	}
+	u, ok := resp.(core.UnknownResponse)
+	return ok && u.IsUnknown()
+}
+
+// WriteEDN writes the operations as Jepsen operation maps, one per line, like
+//
+//	{:type :invoke, :f :write, :value 3, :process 1, :time 1000, :index 0}
+//	{:type :ok, :f :write, :value 3, :process 1, :time 2000, :index 1}
+//
+// The node and the client of the operation are kept in :node and :client.
+func WriteEDN(w io.Writer, ops []core.Operation, c EDNCodec) error {
+	bw := bufio.NewWriter(w)
+	// The pending request of every process.
+	reqs := make(map[int64]interface{})
+	for i, op := range ops {
+		var (
+			typ   Keyword
+			f     Keyword
+			value interface{}
+			err   error
+		)
+		switch op.Action {
+		case core.InvokeOperation:
+			typ = EDNInvoke
+			if f, value, err = c.EncodeRequest(op.Data); err != nil {
+				return fmt.Errorf("encode operation %d failed: %v", i, err)
+			}
+			reqs[op.Proc] = op.Data
+		case core.ReturnOperation:
+			req, ok := reqs[op.Proc]
+			if !ok {
+				return fmt.Errorf("missing invoke, op: %v", op)
+			}
+			delete(reqs, op.Proc)
+
+			// :f of the completion is the same as the invoke.
+			if f, value, err = c.EncodeRequest(req); err != nil {
+				return fmt.Errorf("encode operation %d failed: %v", i, err)
+			}
+			if isUnknown(op.Data) {
+				typ = EDNInfo
+			} else if typ, value, err = c.EncodeResponse(req, op.Data); err != nil {
+				return fmt.Errorf("encode operation %d failed: %v", i, err)
+			}
+		default:
+			return fmt.Errorf("unknown action %s of operation %d", op.Action, i)
+		}
+
+		m := EDNMap{
+			{Keyword("type"), typ},
+			{Keyword("f"), f},
+			{Keyword("value"), value},
+			{Keyword("process"), op.Proc},
+			{Keyword("time"), op.Time.Nanoseconds()},
+			{Keyword("index"), int64(i)},
+		}
+		if len(op.Node) != 0 {
+			m = append(m, EDNEntry{Keyword("node"), op.Node})
+			m = append(m, EDNEntry{Keyword("client"), int64(op.Client)})
+		}
+		if err = WriteEDNValue(bw, m); err != nil {
+			return err
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// ReadEDN reads the Jepsen operations into core.Operation. The operations
+// are either top level maps, or in one vector or list. Operations of a non
+// integer process, like :nemesis, are skipped. An :info completion is read
+// as a nil response, so CompleteOperations completes it.
+func ReadEDN(r io.Reader, c EDNCodec) ([]core.Operation, error) {
+	er := NewEDNReader(r)
+	var ops []core.Operation
+	reqs := make(map[int64]interface{})
+	for {
+		v, err := er.Read()
+		if err == io.EOF {
+			return ops, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var maps []interface{}
+		if seq, ok := v.([]interface{}); ok {
+			maps = seq
+		} else {
+			maps = []interface{}{v}
+		}
+		for _, v := range maps {
+			m, ok := v.(EDNMap)
+			if !ok {
+				return nil, fmt.Errorf("operation %v is not a map", v)
+			}
+			op, ok, err := decodeEDNOperation(m, c, reqs)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				ops = append(ops, op)
+			}
+		}
+	}
+}
+
+func decodeEDNOperation(m EDNMap, c EDNCodec, reqs map[int64]interface{}) (core.Operation, bool, error) {
+	var op core.Operation
+	proc, ok := m.Get(Keyword("process")).(int64)
+	if !ok {
+		return op, false, nil
+	}
+	typ, _ := m.Get(Keyword("type")).(Keyword)
+	f, _ := m.Get(Keyword("f")).(Keyword)
+	value := m.Get(Keyword("value"))
+
+	op.Proc = proc
+	if t, ok := m.Get(Keyword("time")).(int64); ok {
+		op.Time = time.Duration(t)
+	}
+	op.Node, _ = m.Get(Keyword("node")).(string)
+	if client, ok := m.Get(Keyword("client")).(int64); ok {
+		op.Client = int(client)
+	}
+
+	var err error
+	switch typ {
+	case EDNInvoke:
+		op.Action = core.InvokeOperation
+		if op.Data, err = c.DecodeRequest(f, value); err != nil {
+			return op, false, fmt.Errorf("decode %v failed: %v", m, err)
+		}
+		reqs[proc] = op.Data
+	case EDNOk, EDNFail, EDNInfo:
+		op.Action = core.ReturnOperation
+		req, ok := reqs[proc]
+		if !ok {
+			return op, false, fmt.Errorf("missing invoke, op: %v", m)
+		}
+		delete(reqs, proc)
+		if typ == EDNInfo {
+			break
+		}
+		if op.Data, err = c.DecodeResponse(typ, req, value); err != nil {
+			return op, false, fmt.Errorf("decode %v failed: %v", m, err)
+		}
+	default:
+		return op, false, fmt.Errorf("unknown type %v of %v", typ, m)
+	}
+	return op, true, nil
+}
+
+// ExportEDN reads the history file with the parser and writes its operations
+// in Jepsen EDN. If the parser name is empty, the parser of the workload in
+// the history header is used.
+func ExportEDN(historyFile string, parserName string, w io.Writer) error {
+	if len(parserName) == 0 {
+		h, err := ReadHeader(historyFile)
+		if err != nil {
+			return err
+		}
+		workload, ok := core.GetWorkload(h.DB, h.Workload)
+		if !ok {
+			return fmt.Errorf("history %s has no known workload in the header, the parser is required", historyFile)
+		}
+		parserName = workload.Parser
+	}
+
+	p := GetParser(parserName)
+	c := GetEDNCodec(parserName)
+	if p == nil || c == nil {
+		return fmt.Errorf("parser %s can not be exported to edn", parserName)
+	}
+
+	ops, _, err := ReadHistory(historyFile, p)
+	if err != nil {
+		return err
+	}
+	return WriteEDN(w, ops, c)
+}
+
+// OutcomeOf returns the type of the completion of the request, which is
+// :ok, :fail or :info for an unknown response. Without a codec, every known
+// response is :ok.
+func OutcomeOf(req interface{}, resp interface{}, c EDNCodec) Keyword {
+	if isUnknown(resp) {
+		return EDNInfo
+	}
+	if c != nil {
+		if typ, _, err := c.EncodeResponse(req, resp); err == nil && typ == EDNFail {
+			return EDNFail
+		}
+	}
+	return EDNOk
+}
+
+// OpName returns the :f of the request, or its type name without a codec.
+func OpName(req interface{}, c EDNCodec) string {
+	if c != nil {
+		if f, _, err := c.EncodeRequest(req); err == nil {
+			return string(f)
+		}
+	}
+	return fmt.Sprintf("%T", req)
+}
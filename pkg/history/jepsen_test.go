@@ -0,0 +1,145 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/core"
+)
+
+// noopCodec converts NoopRequest and NoopResponse like a register.
+type noopCodec struct{}
+
+func (noopCodec) EncodeRequest(req interface{}) (Keyword, interface{}, error) {
+	r := req.(NoopRequest)
+	if r.Op == 0 {
+		return "read", nil, nil
+	}
+	return "write", int64(r.Value), nil
+}
+
+func (noopCodec) EncodeResponse(req interface{}, resp interface{}) (Keyword, interface{}, error) {
+	return EDNOk, int64(resp.(NoopResponse).Value), nil
+}
+
+func (noopCodec) DecodeRequest(f Keyword, value interface{}) (interface{}, error) {
+	switch f {
+	case "read":
+		return NoopRequest{Op: 0}, nil
+	case "write":
+		v, err := EDNInt(value)
+		return NoopRequest{Op: 1, Value: int(v)}, err
+	}
+	return nil, fmt.Errorf("unknown f %s", f)
+}
+
+func (noopCodec) DecodeResponse(typ Keyword, req interface{}, value interface{}) (interface{}, error) {
+	v, err := EDNInt(value)
+	return NoopResponse{Value: int(v)}, err
+}
+
+func TestReadEDNValue(t *testing.T) {
+	r := NewEDNReader(strings.NewReader(`; a comment
+{:a [1 -2 3.5], "b" (nil true false), :c #{:x} #_ :skipped :d #inst "2019"}
+:tail`))
+
+	v, err := r.Read()
+	if err != nil {
+		t.Fatalf("read edn failed %v", err)
+	}
+	expected := EDNMap{
+		{Keyword("a"), []interface{}{int64(1), int64(-2), 3.5}},
+		{"b", []interface{}{nil, true, false}},
+		{Keyword("c"), []interface{}{Keyword("x")}},
+		{Keyword("d"), "2019"},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Fatalf("expect %v, but got %v", expected, v)
+	}
+
+	if v, err = r.Read(); err != nil || v != Keyword("tail") {
+		t.Fatalf("expect :tail, but got %v %v", v, err)
+	}
+
+	if _, err = NewEDNReader(strings.NewReader("[1 2")).Read(); err == nil {
+		t.Fatal("expect an error for unclosed vector")
+	}
+}
+
+func TestEDNString(t *testing.T) {
+	s := "a \"quoted\" \\ line\n\ttab\r\x00\a\v\x7f 你好 \U0001F600"
+	var buf bytes.Buffer
+	if err := WriteEDNValue(&buf, []interface{}{s}); err != nil {
+		t.Fatalf("write edn failed %v", err)
+	}
+	// The non-ASCII characters are written as they are.
+	expected := `["a \"quoted\" \\ line\n\ttab\r\u0000\u0007\u000b\u007f 你好 😀"]`
+	if buf.String() != expected {
+		t.Fatalf("expect %s, but got %s", expected, buf.String())
+	}
+
+	v, err := NewEDNReader(&buf).Read()
+	if err != nil {
+		t.Fatalf("read edn failed %v", err)
+	}
+	if !reflect.DeepEqual(v, []interface{}{s}) {
+		t.Fatalf("expect %q, but got %q", s, v)
+	}
+}
+
+func TestEDNRoundTrip(t *testing.T) {
+	ops := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 1, Data: NoopRequest{Op: 1, Value: 3}, Time: time.Millisecond, Node: "n1", Client: 0},
+		{Action: core.InvokeOperation, Proc: 2, Data: NoopRequest{Op: 0}, Time: 2 * time.Millisecond, Node: "n2", Client: 1},
+		{Action: core.ReturnOperation, Proc: 1, Data: NoopResponse{Value: 3}, Time: 3 * time.Millisecond, Node: "n1", Client: 0},
+		// An unknown response.
+		{Action: core.ReturnOperation, Proc: 2, Data: nil, Time: 4 * time.Millisecond, Node: "n2", Client: 1},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEDN(&buf, ops, noopCodec{}); err != nil {
+		t.Fatalf("write edn failed %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expect 4 operations, but got %s", buf.String())
+	}
+	if expected := `{:type :info, :f :read, :value nil, :process 2, :time 4000000, :index 3, :node "n2", :client 1}`; lines[3] != expected {
+		t.Fatalf("expect %s, but got %s", expected, lines[3])
+	}
+
+	readOps, err := ReadEDN(&buf, noopCodec{})
+	if err != nil {
+		t.Fatalf("read edn failed %v", err)
+	}
+	if !reflect.DeepEqual(readOps, ops) {
+		t.Fatalf("expect %v, but got %v", ops, readOps)
+	}
+}
+
+func TestReadJepsenEDN(t *testing.T) {
+	// A history written by Jepsen, in a vector, with nemesis operations.
+	s := `[{:type :invoke, :f :write, :value 1, :process 0, :time 10}
+{:type :info, :f :start, :value nil, :process :nemesis, :time 11}
+{:type :ok, :f :write, :value 1, :process 0, :time 20}]`
+
+	ops, err := ReadEDN(strings.NewReader(s), noopCodec{})
+	if err != nil {
+		t.Fatalf("read edn failed %v", err)
+	}
+	expected := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 0, Data: NoopRequest{Op: 1, Value: 1}, Time: 10},
+		{Action: core.ReturnOperation, Proc: 0, Data: NoopResponse{Value: 1}, Time: 20},
+	}
+	if !reflect.DeepEqual(ops, expected) {
+		t.Fatalf("expect %v, but got %v", expected, ops)
+	}
+
+	if _, err = ReadEDN(strings.NewReader(`{:type :ok, :f :read, :value 1, :process 3}`), noopCodec{}); err == nil {
+		t.Fatal("expect an error for a completion without invoke")
+	}
+}
@@ -0,0 +1,195 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pingcap/chaos/pkg/core"
+)
+
+// Kinds of the issues found by Lint.
+const (
+	// IssueInvalidRecord is a line which is not a record.
+	IssueInvalidRecord = "invalid-record"
+	// IssueTruncated is a partial record at the end of the history.
+	IssueTruncated = "truncated"
+	// IssueUnknownAction is a record of an unknown action.
+	IssueUnknownAction = "unknown-action"
+	// IssueBadHeader is a header which is not the first record, or can not
+	// be decoded, or is written in a newer format.
+	IssueBadHeader = "bad-header"
+	// IssueUndecodable is a request, response or dumped state which the
+	// parser can not decode.
+	IssueUndecodable = "undecodable"
+	// IssueOrphanReturn is a return without an invoke of the process.
+	IssueOrphanReturn = "orphan-return"
+	// IssueDoubleInvoke is an invoke of a process whose last invoke does
+	// not return.
+	IssueDoubleInvoke = "double-invoke"
+	// IssueReuseAfterUnknown is an invoke of a process whose last response
+	// is unknown, the process must be changed after an unknown response.
+	IssueReuseAfterUnknown = "reuse-after-unknown"
+	// IssueMissingDump means no state is dumped before the operations.
+	IssueMissingDump = "missing-dump"
+)
+
+// Issue is a structural issue of a history, it is usually a bug of the
+// recorder or the client instead of the database.
+type Issue struct {
+	Line    int    `json:"line"`
+	Kind    string `json:"kind"`
+	Proc    int64  `json:"proc,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("line %d: %s: %s", i.Line, i.Kind, i.Message)
+}
+
+// linter tracks the processes of the history.
+type linter struct {
+	p      RecordParser
+	issues []Issue
+	// pending is the line of the invoke of every process which does not
+	// return yet.
+	pending map[int64]int
+	// unknown is the line of the unknown response of every process.
+	unknown map[int64]int
+	dumped  bool
+	// opLine is the line of the first operation.
+	opLine int
+}
+
+// Lint checks the history is well-formed, it reports every issue with its
+// line number instead of stopping at the first one, so a broken history is
+// found before running the checkers. The issues are sorted by line, the
+// error is only for failing to read the history file.
+func Lint(historyFile string, p RecordParser) ([]Issue, error) {
+	r, err := openHistoryReader(historyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	l := &linter{
+		p:       p,
+		pending: make(map[int64]int),
+		unknown: make(map[int64]int),
+	}
+	// invalid is the line which can not be unmarshaled, it is a partial
+	// record if it is the last line.
+	var (
+		invalid    int
+		invalidErr error
+	)
+	line := 0
+	for r.scanner.Scan() {
+		line++
+		if invalid != 0 {
+			l.add(invalid, IssueInvalidRecord, 0, "%v", invalidErr)
+			invalid = 0
+		}
+
+		var record opRecord
+		if err = json.Unmarshal(r.scanner.Bytes(), &record); err != nil {
+			invalid, invalidErr = line, err
+			continue
+		}
+		l.lint(line, record)
+	}
+	if err = r.scanner.Err(); err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	if invalid != 0 {
+		l.add(invalid, IssueTruncated, 0, "partial record at the end: %v", invalidErr)
+	} else if err == io.ErrUnexpectedEOF {
+		l.add(line+1, IssueTruncated, 0, "compressed history ends unexpectedly")
+	}
+
+	if !l.dumped && l.opLine != 0 {
+		l.add(l.opLine, IssueMissingDump, 0, "no state is dumped before the first operation")
+	}
+	sort.SliceStable(l.issues, func(i, j int) bool { return l.issues[i].Line < l.issues[j].Line })
+	return l.issues, nil
+}
+
+func (l *linter) add(line int, kind string, proc int64, format string, args ...interface{}) {
+	l.issues = append(l.issues, Issue{
+		Line:    line,
+		Kind:    kind,
+		Proc:    proc,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) lint(line int, record opRecord) {
+	switch record.Action {
+	case headerOperation:
+		if line != 1 {
+			l.add(line, IssueBadHeader, 0, "header is not the first record")
+		}
+		var h Header
+		if err := json.Unmarshal(record.Data, &h); err != nil {
+			l.add(line, IssueBadHeader, 0, "%v", err)
+		} else if err = h.checkVersion(); err != nil {
+			l.add(line, IssueBadHeader, 0, "%v", err)
+		}
+	case dumpOperation:
+		if _, err := l.p.OnState(record.Data); err != nil {
+			l.add(line, IssueUndecodable, 0, "dumped state %s: %v", record.Data, err)
+		}
+		if l.opLine == 0 {
+			l.dumped = true
+		}
+	case nemesisOperation:
+		var n core.NemesisRecord
+		if err := json.Unmarshal(record.Data, &n); err != nil {
+			l.add(line, IssueUndecodable, 0, "nemesis %s: %v", record.Data, err)
+		}
+	case core.InvokeOperation:
+		l.invoke(line, record)
+	case core.ReturnOperation:
+		l.ret(line, record)
+	default:
+		l.add(line, IssueUnknownAction, record.Proc, "unknown action %q", record.Action)
+	}
+}
+
+func (l *linter) invoke(line int, record opRecord) {
+	if l.opLine == 0 {
+		l.opLine = line
+	}
+	proc := record.Proc
+	if _, err := l.p.OnRequest(record.Data); err != nil {
+		l.add(line, IssueUndecodable, proc, "request %s: %v", record.Data, err)
+	}
+	if invoked, ok := l.pending[proc]; ok {
+		l.add(line, IssueDoubleInvoke, proc, "process %d invokes again, its invoke at line %d does not return", proc, invoked)
+	}
+	if unknown, ok := l.unknown[proc]; ok {
+		l.add(line, IssueReuseAfterUnknown, proc, "process %d invokes again after the unknown response at line %d", proc, unknown)
+		delete(l.unknown, proc)
+	}
+	l.pending[proc] = line
+}
+
+func (l *linter) ret(line int, record opRecord) {
+	if l.opLine == 0 {
+		l.opLine = line
+	}
+	proc := record.Proc
+	resp, err := l.p.OnResponse(record.Data)
+	if err != nil {
+		l.add(line, IssueUndecodable, proc, "response %s: %v", record.Data, err)
+	}
+	if _, ok := l.pending[proc]; !ok {
+		l.add(line, IssueOrphanReturn, proc, "process %d returns without an invoke", proc)
+		return
+	}
+	delete(l.pending, proc)
+	if err == nil && isUnknown(resp) {
+		l.unknown[proc] = line
+	}
+}
@@ -0,0 +1,77 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "lint")
+	if err != nil {
+		t.Fatalf("create temp dir failed %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	lines := []string{
+		`{"action":"call","proc":1,"data":{"Op":0}}`,
+		`{"action":"return","proc":2,"data":{"Value":1}}`,
+		`{"action":"return","proc":1,"data":{"Unknown":true}}`,
+		`{"action":"call","proc":1,"data":{"Op":0}}`,
+		`{"action":"call","proc":1,"data":{"Op":0}}`,
+		`not a record`,
+		`{"action":"return","proc":1,"data":{"Value":"x"}}`,
+		`{"action":"header","proc":0,"data":{"version":1}}`,
+		`{"action":"sleep","proc":3,"data":null}`,
+		`{"action":"call","proc":3,"da`,
+	}
+	name := path.Join(tmpDir, "history.log")
+	if err = ioutil.WriteFile(name, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := Lint(name, NoopParser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, i := range issues {
+		got = append(got, i.Kind)
+	}
+	expected := []string{
+		IssueMissingDump,
+		IssueOrphanReturn,
+		IssueReuseAfterUnknown,
+		IssueDoubleInvoke,
+		IssueInvalidRecord,
+		IssueUndecodable,
+		IssueBadHeader,
+		IssueUnknownAction,
+		IssueTruncated,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expect issues %v, got %v", expected, issues)
+	}
+	for i, line := range []int{1, 2, 4, 5, 6, 7, 8, 9, 10} {
+		if issues[i].Line != line {
+			t.Fatalf("expect issue %v at line %d", issues[i], line)
+		}
+	}
+
+	// A recorded history has no issue.
+	r, err := NewRecorder(name, Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.RecordState(7)
+	r.RecordRequest(1, "n1", 0, NoopRequest{})
+	r.RecordResponse(1, "n1", 0, NoopResponse{Unknown: true})
+	r.RecordRequest(2, "n1", 0, NoopRequest{})
+	r.Close()
+	if issues, err = Lint(name, NoopParser{}); err != nil || len(issues) != 0 {
+		t.Fatalf("expect no issue, got %v, %v", issues, err)
+	}
+}
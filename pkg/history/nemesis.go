@@ -0,0 +1,43 @@
+package history
+
+import (
+	"time"
+
+	"github.com/pingcap/chaos/pkg/core"
+)
+
+// NemesisWindow is when a nemesis disturbs a node, from the start of its
+// invoke to the end of its recover.
+type NemesisWindow struct {
+	Name  string        `json:"name"`
+	Node  string        `json:"node"`
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+	Err   string        `json:"err,omitempty"`
+}
+
+// NemesisWindows pairs every nemesis invoke with the next recover of the
+// same nemesis on the same node, a nemesis never recovered lasts to the end.
+func NemesisWindows(records []core.NemesisRecord, end time.Duration) []NemesisWindow {
+	var ws []NemesisWindow
+	open := make(map[string]int)
+	for _, r := range records {
+		key := r.Name + "/" + r.Node
+		switch r.Kind {
+		case core.NemesisInvoke:
+			open[key] = len(ws)
+			ws = append(ws, NemesisWindow{Name: r.Name, Node: r.Node, Start: r.Start, End: end, Err: r.Err})
+		case core.NemesisRecover:
+			i, ok := open[key]
+			if !ok {
+				continue
+			}
+			delete(open, key)
+			ws[i].End = r.End
+			if len(ws[i].Err) == 0 {
+				ws[i].Err = r.Err
+			}
+		}
+	}
+	return ws
+}
@@ -0,0 +1,36 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+)
+
+var parsers = map[string]RecordParser{}
+
+// RegisterParser registers the named parser. Not thread-safe.
+func RegisterParser(name string, p RecordParser) {
+	if _, ok := parsers[name]; ok {
+		panic(fmt.Sprintf("parser %s is already registered", name))
+	}
+
+	parsers[name] = p
+}
+
+// GetParser gets the registered parser.
+func GetParser(name string) RecordParser {
+	return parsers[name]
+}
+
+// ParserNames returns the sorted names of the registered parsers.
+func ParserNames() []string {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterParser("noop", NoopParser{})
+}
@@ -0,0 +1,290 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/pingcap/chaos/pkg/core"
+)
+
+// FailFunc tells whether the operations of a history, which are not
+// completed, with the dumped state still fail a checker.
+type FailFunc func(ops []core.Operation, state interface{}) bool
+
+// shrinkRecord is a record read by Shrink, the data of an operation is parsed.
+type shrinkRecord struct {
+	line   []byte
+	record opRecord
+	data   interface{}
+}
+
+// shrinkOp is an operation of the history to shrink, its invoke and return
+// records are removed together.
+type shrinkOp struct {
+	proc      int64
+	client    int
+	records   []int
+	removable bool
+}
+
+type shrinker struct {
+	ctx     context.Context
+	records []shrinkRecord
+	ops     []shrinkOp
+	state   interface{}
+	fails   FailFunc
+}
+
+// Shrink minimizes a failing history. It repeatedly removes the removable
+// operations of a client, of a process, and then chunks of them, keeping a
+// removal if fails still returns true, until no operation can be removed or
+// the context is done. An operation is removable if removable returns true
+// for its request, only the operations which never change the state, like
+// reads, should be removable, so the states of the kept operations are still
+// reachable from the dumped state. The invoke and the return of an operation
+// are always removed together, the header, dumped states and nemesis records
+// are kept. The smallest failing history is written to out, compressed by
+// its extension, and the count of its operations is returned.
+func Shrink(ctx context.Context, historyFile string, out string, p RecordParser, removable func(req interface{}) bool, fails FailFunc) (int, error) {
+	s, err := readShrinker(historyFile, p, removable)
+	if err != nil {
+		return 0, err
+	}
+	s.ctx = ctx
+	s.fails = fails
+
+	kept := make([]bool, len(s.ops))
+	for i := range kept {
+		kept[i] = true
+	}
+	if !s.check(kept) {
+		return 0, ErrNotFailing
+	}
+
+	s.removeGroups(kept, func(op shrinkOp) int64 { return int64(op.client) })
+	s.removeGroups(kept, func(op shrinkOp) int64 { return op.proc })
+	s.removeChunks(kept)
+
+	return s.write(out, kept)
+}
+
+// ErrNotFailing means the history to shrink does not fail.
+var ErrNotFailing = errors.New("history does not fail")
+
+func readShrinker(historyFile string, p RecordParser, removable func(req interface{}) bool) (*shrinker, error) {
+	r, err := openHistoryReader(historyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	s := new(shrinker)
+	pending := make(map[int64]int)
+	for {
+		var sr shrinkRecord
+		ok, err := r.scan(&sr.record)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return s, nil
+		}
+		sr.line = append([]byte(nil), r.scanner.Bytes()...)
+
+		i := len(s.records)
+		switch sr.record.Action {
+		case core.InvokeOperation:
+			if sr.data, err = p.OnRequest(sr.record.Data); err != nil {
+				return nil, err
+			}
+			pending[sr.record.Proc] = len(s.ops)
+			s.ops = append(s.ops, shrinkOp{
+				proc:      sr.record.Proc,
+				client:    sr.record.Client,
+				records:   []int{i},
+				removable: removable(sr.data),
+			})
+		case core.ReturnOperation:
+			if sr.data, err = p.OnResponse(sr.record.Data); err != nil {
+				return nil, err
+			}
+			if j, ok := pending[sr.record.Proc]; ok {
+				delete(pending, sr.record.Proc)
+				s.ops[j].records = append(s.ops[j].records, i)
+			}
+		case dumpOperation:
+			if s.state, err = p.OnState(sr.record.Data); err != nil {
+				return nil, err
+			}
+		case headerOperation:
+			var h Header
+			if err = json.Unmarshal(sr.record.Data, &h); err != nil {
+				return nil, err
+			}
+			if err = h.checkVersion(); err != nil {
+				return nil, err
+			}
+		}
+		s.records = append(s.records, sr)
+	}
+}
+
+// check tells whether the kept operations still fail.
+func (s *shrinker) check(kept []bool) bool {
+	keptRecords := s.keptRecords(kept)
+	ops := make([]core.Operation, 0, len(keptRecords))
+	for _, i := range keptRecords {
+		r := s.records[i]
+		switch r.record.Action {
+		case core.InvokeOperation, core.ReturnOperation:
+			ops = append(ops, core.Operation{
+				Action: r.record.Action,
+				Proc:   r.record.Proc,
+				Data:   r.data,
+				Time:   r.record.Time,
+				Node:   r.record.Node,
+				Client: r.record.Client,
+			})
+		}
+	}
+	return s.fails(ops, s.state)
+}
+
+// keptRecords returns the indexes of the kept records in order.
+func (s *shrinker) keptRecords(kept []bool) []int {
+	removed := make(map[int]bool)
+	for i, op := range s.ops {
+		if kept[i] {
+			continue
+		}
+		for _, r := range op.records {
+			removed[r] = true
+		}
+	}
+
+	records := make([]int, 0, len(s.records)-len(removed))
+	for i := range s.records {
+		if !removed[i] {
+			records = append(records, i)
+		}
+	}
+	return records
+}
+
+func (s *shrinker) done() bool {
+	return s.ctx.Err() != nil
+}
+
+// try removes the operations, and restores them if the history does not
+// fail without them.
+func (s *shrinker) try(kept []bool, ops []int) bool {
+	for _, i := range ops {
+		kept[i] = false
+	}
+	if s.check(kept) {
+		return true
+	}
+	for _, i := range ops {
+		kept[i] = true
+	}
+	return false
+}
+
+// removeGroups tries to remove the kept removable operations of every group.
+func (s *shrinker) removeGroups(kept []bool, group func(op shrinkOp) int64) {
+	groups := make(map[int64][]int)
+	var keys []int64
+	for i, op := range s.ops {
+		if !kept[i] || !op.removable {
+			continue
+		}
+		key := group(op)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+	if len(groups) < 2 {
+		return
+	}
+	sort.Sort(int64Slice(keys))
+
+	for _, key := range keys {
+		if s.done() {
+			return
+		}
+		s.try(kept, groups[key])
+	}
+}
+
+// removeChunks tries to remove chunks of the kept removable operations,
+// halving the chunk size until single operations.
+func (s *shrinker) removeChunks(kept []bool) {
+	for {
+		var ops []int
+		for i, op := range s.ops {
+			if kept[i] && op.removable {
+				ops = append(ops, i)
+			}
+		}
+
+		removed := false
+		for size := len(ops) / 2; size >= 1; size /= 2 {
+			for start := 0; start < len(ops); start += size {
+				if s.done() {
+					return
+				}
+				end := start + size
+				if end > len(ops) {
+					end = len(ops)
+				}
+				var chunk []int
+				for _, i := range ops[start:end] {
+					if kept[i] {
+						chunk = append(chunk, i)
+					}
+				}
+				if len(chunk) != 0 && s.try(kept, chunk) {
+					removed = true
+				}
+			}
+		}
+		// Removing some operations may make others removable.
+		if !removed {
+			return
+		}
+	}
+}
+
+func (s *shrinker) write(out string, kept []bool) (int, error) {
+	os.MkdirAll(path.Dir(out), 0755)
+
+	f, err := os.OpenFile(out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	w, err := newHistoryWriter(f, CompressionOf(out))
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+
+	for _, i := range s.keptRecords(kept) {
+		if _, err = w.Write(append(s.records[i].line, '\n')); err != nil {
+			w.Close()
+			return 0, err
+		}
+	}
+
+	count := 0
+	for _, k := range kept {
+		if k {
+			count++
+		}
+	}
+	return count, w.Close()
+}
@@ -0,0 +1,86 @@
+package history
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/pingcap/chaos/pkg/core"
+)
+
+func TestShrink(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "shrink")
+	if err != nil {
+		t.Fatalf("create temp dir failed %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	name := path.Join(tmpDir, "history.log")
+	r, err := NewRecorder(name, Header{Workload: "noop"})
+	if err != nil {
+		t.Fatalf("create recorder failed %v", err)
+	}
+	r.RecordState(7)
+	for i := 0; i < 100; i++ {
+		proc := int64(i % 5)
+		// Every tenth operation is a write, others are reads.
+		op := 0
+		if i%10 == 0 {
+			op = 1
+		}
+		r.RecordRequest(proc, "n1", int(proc), NoopRequest{Op: op, Value: i})
+		if i == 37 || i == 64 {
+			// Unknown responses.
+			r.RecordResponse(proc, "n1", int(proc), NoopResponse{Unknown: true})
+			continue
+		}
+		r.RecordResponse(proc, "n1", int(proc), NoopResponse{Value: i})
+	}
+	r.Close()
+
+	// Only the reads are removable.
+	removable := func(req interface{}) bool {
+		return req.(NoopRequest).Op == 0
+	}
+	// The history fails if it has both the operations of 42 and 81.
+	fails := func(ops []core.Operation, state interface{}) bool {
+		if state.(int) != 7 {
+			t.Fatalf("unexpected state %v", state)
+		}
+		found := 0
+		for _, op := range ops {
+			if resp, ok := op.Data.(NoopResponse); ok && (resp.Value == 42 || resp.Value == 81) {
+				found++
+			}
+		}
+		return found == 2
+	}
+
+	out := path.Join(tmpDir, "history.shrunk.log.gz")
+	n, err := Shrink(context.Background(), name, out, NoopParser{State: 7}, removable, fails)
+	if err != nil {
+		t.Fatalf("shrink failed %v", err)
+	}
+	// The writes are kept.
+	if n != 12 {
+		t.Fatalf("expect 12 operations, but got %d", n)
+	}
+
+	h, err := ReadHeader(out)
+	if err != nil || h.Workload != "noop" {
+		t.Fatalf("the header must be kept, got %v %v", h, err)
+	}
+	ops, state, err := ReadHistory(out, NoopParser{State: 7})
+	if err != nil {
+		t.Fatalf("read shrunk history failed %v", err)
+	}
+	if len(ops) != 24 || state.(int) != 7 || !fails(ops, state) {
+		t.Fatalf("unexpected shrunk history %v", ops)
+	}
+
+	if _, err = Shrink(context.Background(), out, out+".2", NoopParser{}, removable, func([]core.Operation, interface{}) bool { return false }); err != ErrNotFailing {
+		t.Fatalf("expect ErrNotFailing, but got %v", err)
+	}
+}
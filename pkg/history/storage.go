@@ -0,0 +1,276 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is how a history file is compressed.
+type Compression string
+
+// Compressions of history files.
+const (
+	// NoCompression writes plain newline-delimited json.
+	NoCompression Compression = ""
+	// Gzip compresses the history file with gzip.
+	Gzip Compression = "gzip"
+	// Zstd compresses the history file with zstd.
+	Zstd Compression = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+const (
+	writeBufferSize = 256 * 1024
+	// maxRecordSize limits the size of one record, a dumped state may be large.
+	maxRecordSize = 64 * 1024 * 1024
+)
+
+// ParseCompression parses the compression name, which is empty or none,
+// gzip or zstd.
+func ParseCompression(name string) (Compression, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return NoCompression, nil
+	case "gzip", "gz":
+		return Gzip, nil
+	case "zstd", "zst":
+		return Zstd, nil
+	default:
+		return NoCompression, fmt.Errorf("unknown history compression %s", name)
+	}
+}
+
+// CompressionOf returns the compression implied by the file extension,
+// .gz for gzip and .zst for zstd.
+func CompressionOf(name string) Compression {
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		return Gzip
+	case strings.HasSuffix(name, ".zst"):
+		return Zstd
+	default:
+		return NoCompression
+	}
+}
+
+// Ext returns the file extension of the compression.
+func (c Compression) Ext() string {
+	switch c {
+	case Gzip:
+		return ".gz"
+	case Zstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// SyncPolicy is how often the Recorder makes the records durable.
+type SyncPolicy string
+
+// Sync policies of the Recorder.
+const (
+	// SyncNone buffers the records, they are written when the buffer is
+	// full or the recorder is closed. A killed controller loses the buffered
+	// records and may leave a partial record at the end. It is the default.
+	SyncNone SyncPolicy = "none"
+	// SyncFlush writes every record to the file, so only a crashed machine
+	// loses records.
+	SyncFlush SyncPolicy = "flush"
+	// SyncFsync writes and fsyncs every record, it is the slowest.
+	SyncFsync SyncPolicy = "fsync"
+)
+
+// ParseSyncPolicy parses the sync policy name, which is empty or none,
+// flush or fsync.
+func ParseSyncPolicy(name string) (SyncPolicy, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return SyncNone, nil
+	case "flush":
+		return SyncFlush, nil
+	case "fsync":
+		return SyncFsync, nil
+	default:
+		return SyncNone, fmt.Errorf("unknown history sync policy %s", name)
+	}
+}
+
+// flushWriteCloser is a compressor which can flush the compressed data
+// written so far, both gzip and zstd can.
+type flushWriteCloser interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// historyWriter buffers and compresses the records written to a history file.
+type historyWriter struct {
+	f          *os.File
+	compressor flushWriteCloser
+	w          *bufio.Writer
+}
+
+func newHistoryWriter(f *os.File, c Compression) (*historyWriter, error) {
+	hw := &historyWriter{f: f}
+	var w io.Writer = f
+	switch c {
+	case NoCompression:
+	case Gzip:
+		hw.compressor = gzip.NewWriter(f)
+		w = hw.compressor
+	case Zstd:
+		enc, err := zstd.NewWriter(f)
+		if err != nil {
+			return nil, err
+		}
+		hw.compressor = enc
+		w = enc
+	default:
+		return nil, fmt.Errorf("unknown history compression %s", c)
+	}
+	hw.w = bufio.NewWriterSize(w, writeBufferSize)
+	return hw, nil
+}
+
+func (hw *historyWriter) Write(p []byte) (int, error) {
+	return hw.w.Write(p)
+}
+
+// Sync writes the buffered records to the file by the policy. A flushed
+// compressed stream can be decompressed up to the last flush, even if it
+// is never closed.
+func (hw *historyWriter) Sync(policy SyncPolicy) error {
+	if policy == SyncNone {
+		return nil
+	}
+	if err := hw.w.Flush(); err != nil {
+		return err
+	}
+	if hw.compressor != nil {
+		if err := hw.compressor.Flush(); err != nil {
+			return err
+		}
+	}
+	if policy == SyncFsync {
+		return hw.f.Sync()
+	}
+	return nil
+}
+
+// Close flushes the buffered records, finishes the compressed stream and
+// closes the file.
+func (hw *historyWriter) Close() error {
+	err := hw.w.Flush()
+	if hw.compressor != nil {
+		if cerr := hw.compressor.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := hw.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// historyReader reads the records from a plain or compressed history file,
+// the compression is detected by the magic number of the file.
+type historyReader struct {
+	f       *os.File
+	closer  func()
+	scanner *bufio.Scanner
+	// line is the line number of the last scanned record.
+	line int
+	// truncated is set if the history ends with a partial record.
+	truncated bool
+}
+
+func openHistoryReader(name string) (*historyReader, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	hr := &historyReader{f: f}
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(len(zstdMagic))
+
+	var r io.Reader = br
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		hr.closer = func() { gr.Close() }
+		r = gr
+	case bytes.HasPrefix(magic, zstdMagic):
+		dec, err := zstd.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		hr.closer = dec.Close
+		r = dec
+	}
+
+	hr.scanner = bufio.NewScanner(r)
+	hr.scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	return hr, nil
+}
+
+// Close closes the file.
+func (hr *historyReader) Close() error {
+	if hr.closer != nil {
+		hr.closer()
+	}
+	return hr.f.Close()
+}
+
+// scan reads the next record, it returns false at the end of the history.
+// A recorder which is killed may leave a partial record at the end, or a
+// compressed stream which ends unexpectedly, the history is marked truncated
+// and ends before it instead of failing. A broken record which is followed
+// by others is still an error.
+func (hr *historyReader) scan(record *opRecord) (bool, error) {
+	if !hr.scanner.Scan() {
+		return false, hr.scanErr()
+	}
+	hr.line++
+
+	*record = opRecord{}
+	if err := json.Unmarshal(hr.scanner.Bytes(), record); err != nil {
+		if !hr.scanner.Scan() {
+			if serr := hr.scanErr(); serr != nil {
+				return false, serr
+			}
+			hr.truncated = true
+			return false, nil
+		}
+		return false, fmt.Errorf("invalid record at line %d: %v", hr.line, err)
+	}
+	return true, nil
+}
+
+// scanErr returns the error of the scanner, an unexpected end of the
+// compressed stream marks the history truncated.
+func (hr *historyReader) scanErr() error {
+	err := hr.scanner.Err()
+	if err == io.ErrUnexpectedEOF {
+		hr.truncated = true
+		return nil
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/pingcap/chaos/pkg/core"
 	"github.com/pingcap/chaos/pkg/history"
@@ -87,6 +88,10 @@ func (*casRegister) Name() string {
 	return "cas_register"
 }
 
+func (*casRegister) ReadOnly(input interface{}) bool {
+	return input.(CasRegisterRequest).Op == CasRegisterRead
+}
+
 // CasRegisterModel returns a cas register model
 func CasRegisterModel() core.Model {
 	return &casRegister{}
@@ -127,3 +132,91 @@ func (p casRegisterParser) OnState(data json.RawMessage) (interface{}, error) {
 func CasRegisterParser() history.RecordParser {
 	return casRegisterParser{}
 }
+
+type casRegisterCodec struct{}
+
+func (casRegisterCodec) EncodeRequest(req interface{}) (history.Keyword, interface{}, error) {
+	r := req.(CasRegisterRequest)
+	switch r.Op {
+	case CasRegisterRead:
+		return "read", nil, nil
+	case CasRegisterWrite:
+		return "write", int64(r.Arg1), nil
+	case CasRegisterCAS:
+		return "cas", []interface{}{int64(r.Arg1), int64(r.Arg2)}, nil
+	default:
+		return "", nil, fmt.Errorf("unknown cas register operation %d", r.Op)
+	}
+}
+
+func (c casRegisterCodec) EncodeResponse(req interface{}, resp interface{}) (history.Keyword, interface{}, error) {
+	r := resp.(CasRegisterResponse)
+	_, value, err := c.EncodeRequest(req)
+	switch req.(CasRegisterRequest).Op {
+	case CasRegisterRead:
+		if !r.Exists {
+			return history.EDNOk, nil, nil
+		}
+		return history.EDNOk, int64(r.Value), nil
+	case CasRegisterCAS:
+		if !r.Ok {
+			return history.EDNFail, value, err
+		}
+	}
+	return history.EDNOk, value, err
+}
+
+func (casRegisterCodec) DecodeRequest(f history.Keyword, value interface{}) (interface{}, error) {
+	switch f {
+	case "read":
+		return CasRegisterRequest{Op: CasRegisterRead}, nil
+	case "write":
+		v, err := history.EDNInt(value)
+		return CasRegisterRequest{Op: CasRegisterWrite, Arg1: int(v)}, err
+	case "cas":
+		args, ok := value.([]interface{})
+		if !ok || len(args) != 2 {
+			return nil, fmt.Errorf("cas value %v is not [from to]", value)
+		}
+		from, err := history.EDNInt(args[0])
+		if err != nil {
+			return nil, err
+		}
+		to, err := history.EDNInt(args[1])
+		return CasRegisterRequest{Op: CasRegisterCAS, Arg1: int(from), Arg2: int(to)}, err
+	default:
+		return nil, fmt.Errorf("unknown cas register operation %s", f)
+	}
+}
+
+func (casRegisterCodec) DecodeResponse(typ history.Keyword, req interface{}, value interface{}) (interface{}, error) {
+	r := req.(CasRegisterRequest)
+	if typ == history.EDNFail {
+		if r.Op != CasRegisterCAS {
+			// Only a cas fails definitely.
+			return CasRegisterResponse{Unknown: true}, nil
+		}
+		return CasRegisterResponse{}, nil
+	}
+	if r.Op != CasRegisterRead {
+		return CasRegisterResponse{Ok: true}, nil
+	}
+	if value == nil {
+		return CasRegisterResponse{Ok: true}, nil
+	}
+	v, err := history.EDNInt(value)
+	return CasRegisterResponse{Ok: true, Exists: true, Value: int(v)}, err
+}
+
+// CasRegisterEDNCodec converts CasRegister history to and from Jepsen EDN,
+// a read of a missing value is nil, and a cas is {:f :cas, :value [from to]}
+// which is :fail if the register is not from.
+func CasRegisterEDNCodec() history.EDNCodec {
+	return casRegisterCodec{}
+}
+
+func init() {
+	core.RegisterModel("cas_register", CasRegisterModel)
+	history.RegisterParser("cas_register", CasRegisterParser())
+	history.RegisterEDNCodec("cas_register", CasRegisterEDNCodec())
+}
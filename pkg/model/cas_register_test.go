@@ -1,10 +1,13 @@
 package model
 
 import (
+	"bytes"
+	"reflect"
 	"testing"
 
 	"github.com/anishathalye/porcupine"
 	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
 )
 
 func convertModel(m core.Model) porcupine.Model {
@@ -42,3 +45,32 @@ func TestCasRegisterModelPrepare(t *testing.T) {
 		t.Fatalf("expected to be 888, got %v", state)
 	}
 }
+
+func TestCasRegisterEDN(t *testing.T) {
+	ops := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 0, Data: CasRegisterRequest{Op: CasRegisterRead}},
+		{Action: core.InvokeOperation, Proc: 1, Data: CasRegisterRequest{Op: CasRegisterWrite, Arg1: 100}},
+		{Action: core.InvokeOperation, Proc: 2, Data: CasRegisterRequest{Op: CasRegisterCAS, Arg1: 100, Arg2: 200}},
+		{Action: core.ReturnOperation, Proc: 0, Data: CasRegisterResponse{Ok: true}},
+		{Action: core.ReturnOperation, Proc: 1, Data: CasRegisterResponse{Ok: true}},
+		{Action: core.ReturnOperation, Proc: 2, Data: CasRegisterResponse{}},
+		{Action: core.InvokeOperation, Proc: 3, Data: CasRegisterRequest{Op: CasRegisterRead}},
+		{Action: core.ReturnOperation, Proc: 3, Data: CasRegisterResponse{Ok: true, Exists: true, Value: 100}},
+	}
+
+	var buf bytes.Buffer
+	if err := history.WriteEDN(&buf, ops, CasRegisterEDNCodec()); err != nil {
+		t.Fatalf("write edn failed %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("{:type :fail, :f :cas, :value [100 200], :process 2")) {
+		t.Fatalf("expect a failed cas, but got %s", buf.String())
+	}
+
+	readOps, err := history.ReadEDN(&buf, CasRegisterEDNCodec())
+	if err != nil {
+		t.Fatalf("read edn failed %v", err)
+	}
+	if !reflect.DeepEqual(readOps, ops) {
+		t.Fatalf("expect %v, but got %v", ops, readOps)
+	}
+}
@@ -0,0 +1,268 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+// CounterOp is an operation of a counter.
+type CounterOp bool
+
+const (
+	// CounterRead reads a counter
+	CounterRead CounterOp = false
+	// CounterAdd adds to a counter
+	CounterAdd CounterOp = true
+)
+
+// CounterRequest is the request that is issued to a counter, Value is the
+// delta to add, it must not be negative.
+type CounterRequest struct {
+	Op    CounterOp
+	Value int
+}
+
+// CounterResponse is the response returned by a counter.
+type CounterResponse struct {
+	Unknown bool
+	Value   int
+}
+
+var _ core.UnknownResponse = (*CounterResponse)(nil)
+
+// IsUnknown implements UnknownResponse interface
+func (r CounterResponse) IsUnknown() bool {
+	return r.Unknown
+}
+
+type counter struct {
+	perparedState *int
+}
+
+func (c *counter) Prepare(state interface{}) {
+	s := state.(int)
+	c.perparedState = &s
+}
+
+func (c *counter) Init() interface{} {
+	if c.perparedState != nil {
+		return *c.perparedState
+	}
+	return 0
+}
+
+func (*counter) Step(state interface{}, input interface{}, output interface{}) (bool, interface{}) {
+	st := state.(int)
+	inp := input.(CounterRequest)
+	out := output.(CounterResponse)
+
+	// read
+	if inp.Op == CounterRead {
+		ok := out.Value == st || out.Unknown
+		return ok, st
+	}
+
+	// add
+	return true, st + inp.Value
+}
+
+func (*counter) Equal(state1, state2 interface{}) bool {
+	st1 := state1.(int)
+	st2 := state2.(int)
+	return st1 == st2
+}
+
+func (*counter) Name() string {
+	return "counter"
+}
+
+func (*counter) ReadOnly(input interface{}) bool {
+	return input.(CounterRequest).Op == CounterRead
+}
+
+// CounterModel returns a grow-only counter model.
+func CounterModel() core.Model {
+	return &counter{}
+}
+
+type counterParser struct {
+}
+
+func (p counterParser) OnRequest(data json.RawMessage) (interface{}, error) {
+	r := CounterRequest{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+func (p counterParser) OnResponse(data json.RawMessage) (interface{}, error) {
+	r := CounterResponse{}
+	err := json.Unmarshal(data, &r)
+	if r.Unknown {
+		return nil, err
+	}
+	return r, err
+}
+
+func (p counterParser) OnNoopResponse() interface{} {
+	return CounterResponse{Unknown: true}
+}
+
+func (p counterParser) OnState(data json.RawMessage) (interface{}, error) {
+	var state int
+	err := json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// CounterParser parses Counter history.
+func CounterParser() history.RecordParser {
+	return counterParser{}
+}
+
+type counterCodec struct{}
+
+func (counterCodec) EncodeRequest(req interface{}) (history.Keyword, interface{}, error) {
+	r := req.(CounterRequest)
+	if r.Op == CounterRead {
+		return "read", nil, nil
+	}
+	return "add", int64(r.Value), nil
+}
+
+func (counterCodec) EncodeResponse(req interface{}, resp interface{}) (history.Keyword, interface{}, error) {
+	if req.(CounterRequest).Op == CounterRead {
+		return history.EDNOk, int64(resp.(CounterResponse).Value), nil
+	}
+	return history.EDNOk, int64(req.(CounterRequest).Value), nil
+}
+
+func (counterCodec) DecodeRequest(f history.Keyword, value interface{}) (interface{}, error) {
+	switch f {
+	case "read":
+		return CounterRequest{Op: CounterRead}, nil
+	case "add":
+		v, err := history.EDNInt(value)
+		return CounterRequest{Op: CounterAdd, Value: int(v)}, err
+	default:
+		return nil, fmt.Errorf("unknown counter operation %s", f)
+	}
+}
+
+func (counterCodec) DecodeResponse(typ history.Keyword, req interface{}, value interface{}) (interface{}, error) {
+	if typ != history.EDNOk {
+		// A failed add may have taken effect as well.
+		return CounterResponse{Unknown: true}, nil
+	}
+	if req.(CounterRequest).Op == CounterAdd {
+		return CounterResponse{}, nil
+	}
+	v, err := history.EDNInt(value)
+	return CounterResponse{Value: int(v)}, err
+}
+
+// CounterEDNCodec converts Counter history to and from Jepsen EDN, a read
+// is {:f :read, :value 1} and an add is {:f :add, :value 1}, like the
+// counter workload of Jepsen.
+func CounterEDNCodec() history.EDNCodec {
+	return counterCodec{}
+}
+
+// counterChecker checks every read of a counter is within the bounds.
+type counterChecker struct{}
+
+// Check implements core.Checker, see CheckStream.
+func (c counterChecker) Check(ctx context.Context, m core.Model, ops []core.Operation) (bool, error) {
+	return c.CheckStream(ctx, m, func() (core.Operation, bool) {
+		if len(ops) == 0 {
+			return core.Operation{}, false
+		}
+		op := ops[0]
+		ops = ops[1:]
+		return op, true
+	})
+}
+
+// CheckStream implements core.StreamChecker. A read must be at least the sum
+// of the adds acknowledged before it is invoked, and at most the sum of the
+// adds invoked before it returns, including the unknown ones. It is linear
+// and needs only one pass, so it can check much larger histories than
+// porcupine.
+func (counterChecker) CheckStream(_ context.Context, m core.Model, next func() (core.Operation, bool)) (bool, error) {
+	// The counter starts from the state of the model, 0 by default.
+	base := 0
+	if m != nil {
+		if st, ok := m.Init().(int); ok {
+			base = st
+		}
+	}
+
+	var (
+		// acked is the sum of the adds which returned.
+		acked = base
+		// attempted is the sum of the adds which were invoked.
+		attempted = base
+		pending   = make(map[int64]CounterRequest)
+		// lower is the lower bound of the pending reads.
+		lower = make(map[int64]int)
+	)
+	for op, ok := next(); ok; op, ok = next() {
+		if op.Action == core.InvokeOperation {
+			req, ok := op.Data.(CounterRequest)
+			if !ok {
+				return false, fmt.Errorf("unexpected request %v", op.Data)
+			}
+			if req.Op == CounterAdd {
+				if req.Value < 0 {
+					return false, fmt.Errorf("counter can't add a negative value, op: %v", op)
+				}
+				attempted += req.Value
+			} else {
+				lower[op.Proc] = acked
+			}
+			pending[op.Proc] = req
+			continue
+		}
+
+		req, ok := pending[op.Proc]
+		if !ok {
+			return false, fmt.Errorf("missing invoke, op: %v", op)
+		}
+		delete(pending, op.Proc)
+		resp, ok := op.Data.(CounterResponse)
+		if !ok || resp.Unknown {
+			continue
+		}
+		if req.Op == CounterAdd {
+			acked += req.Value
+			continue
+		}
+		if resp.Value < lower[op.Proc] || resp.Value > attempted {
+			log.Printf("read %d is out of bounds [%d, %d], op: %v", resp.Value, lower[op.Proc], attempted, op)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (counterChecker) Name() string {
+	return "counter_bounds_checker"
+}
+
+// CounterChecker returns a checker of the bounds of the counter reads.
+func CounterChecker() core.Checker {
+	return counterChecker{}
+}
+
+func init() {
+	core.RegisterModel("counter", CounterModel)
+	core.RegisterChecker("counter_bounds", CounterChecker)
+	history.RegisterParser("counter", CounterParser())
+	history.RegisterEDNCodec("counter", CounterEDNCodec())
+}
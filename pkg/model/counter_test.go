@@ -0,0 +1,121 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/anishathalye/porcupine"
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+func TestCounterModel(t *testing.T) {
+	m := CounterModel()
+	m.Prepare(10)
+
+	events := []porcupine.Event{
+		{Kind: porcupine.CallEvent, Value: CounterRequest{Op: CounterAdd, Value: 2}, Id: 0},
+		{Kind: porcupine.CallEvent, Value: CounterRequest{Op: CounterRead}, Id: 1},
+		{Kind: porcupine.ReturnEvent, Value: CounterResponse{Value: 12}, Id: 1},
+		{Kind: porcupine.ReturnEvent, Value: CounterResponse{}, Id: 0},
+		{Kind: porcupine.CallEvent, Value: CounterRequest{Op: CounterRead}, Id: 2},
+		{Kind: porcupine.ReturnEvent, Value: CounterResponse{Value: 12}, Id: 2},
+	}
+	if !porcupine.CheckEvents(convertModel(m), events) {
+		t.Fatal("expected operations to be linearizable")
+	}
+
+	// Read the value before the add after it returns.
+	events[5] = porcupine.Event{Kind: porcupine.ReturnEvent, Value: CounterResponse{Value: 10}, Id: 2}
+	if porcupine.CheckEvents(convertModel(m), events) {
+		t.Fatal("expected operations to not be linearizable")
+	}
+}
+
+func TestCounterChecker(t *testing.T) {
+	add := func(proc int64, v int) core.Operation {
+		return core.Operation{Action: core.InvokeOperation, Proc: proc, Data: CounterRequest{Op: CounterAdd, Value: v}}
+	}
+	read := func(proc int64) core.Operation {
+		return core.Operation{Action: core.InvokeOperation, Proc: proc, Data: CounterRequest{Op: CounterRead}}
+	}
+	ret := func(proc int64, resp CounterResponse) core.Operation {
+		return core.Operation{Action: core.ReturnOperation, Proc: proc, Data: resp}
+	}
+
+	cases := []struct {
+		name  string
+		ops   []core.Operation
+		valid bool
+	}{
+		{"concurrent add", []core.Operation{
+			add(0, 1), ret(0, CounterResponse{}),
+			add(0, 2), read(1), ret(1, CounterResponse{Value: 3}), ret(0, CounterResponse{}),
+			read(1), ret(1, CounterResponse{Value: 3}),
+		}, true},
+		{"unknown add", []core.Operation{
+			add(0, 1), ret(0, CounterResponse{Unknown: true}),
+			read(1), ret(1, CounterResponse{Value: 1}),
+			read(1), ret(1, CounterResponse{Value: 0}),
+		}, true},
+		{"lost add", []core.Operation{
+			add(0, 1), ret(0, CounterResponse{}),
+			read(1), ret(1, CounterResponse{Value: 0}),
+		}, false},
+		{"read before add", []core.Operation{
+			read(1), ret(1, CounterResponse{Value: 1}),
+			add(0, 1), ret(0, CounterResponse{}),
+		}, false},
+		{"read returns before add is invoked", []core.Operation{
+			read(1), add(0, 2), ret(1, CounterResponse{Value: 2}), ret(0, CounterResponse{}),
+		}, true},
+	}
+
+	for _, c := range cases {
+		ok, err := CounterChecker().Check(context.Background(), CounterModel(), c.ops)
+		if err != nil {
+			t.Fatalf("%s: check failed %v", c.name, err)
+		}
+		if ok != c.valid {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.valid, ok)
+		}
+	}
+
+	m := CounterModel()
+	m.Prepare(5)
+	ops := []core.Operation{read(0), ret(0, CounterResponse{Value: 5})}
+	if ok, err := CounterChecker().Check(context.Background(), m, ops); err != nil || !ok {
+		t.Fatalf("the counter must start from the prepared state, err %v", err)
+	}
+	ops = []core.Operation{add(0, -1), ret(0, CounterResponse{})}
+	if _, err := CounterChecker().Check(context.Background(), m, ops); err == nil {
+		t.Fatal("a negative add must fail")
+	}
+}
+
+func TestCounterEDN(t *testing.T) {
+	ops := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 0, Data: CounterRequest{Op: CounterAdd, Value: 3}},
+		{Action: core.InvokeOperation, Proc: 1, Data: CounterRequest{Op: CounterRead}},
+		{Action: core.ReturnOperation, Proc: 0, Data: CounterResponse{}},
+		{Action: core.ReturnOperation, Proc: 1, Data: CounterResponse{Value: 7}},
+	}
+
+	var buf bytes.Buffer
+	if err := history.WriteEDN(&buf, ops, CounterEDNCodec()); err != nil {
+		t.Fatalf("write edn failed %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`{:type :invoke, :f :add, :value 3, :process 0`)) {
+		t.Fatalf("expect an add, but got %s", buf.String())
+	}
+
+	readOps, err := history.ReadEDN(&buf, CounterEDNCodec())
+	if err != nil {
+		t.Fatalf("read edn failed %v", err)
+	}
+	if !reflect.DeepEqual(readOps, ops) {
+		t.Fatalf("expect %v, but got %v", ops, readOps)
+	}
+}
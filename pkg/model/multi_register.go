@@ -0,0 +1,224 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+// MultiRegisterRequest is the request that is issued to the register of a key.
+type MultiRegisterRequest struct {
+	Op    Op
+	Key   string
+	Value int
+}
+
+// MultiRegisterResponse is the response returned by the register of a key.
+type MultiRegisterResponse struct {
+	Unknown bool
+	Value   int
+}
+
+var _ core.UnknownResponse = (*MultiRegisterResponse)(nil)
+
+// IsUnknown implements UnknownResponse interface
+func (r MultiRegisterResponse) IsUnknown() bool {
+	return r.Unknown
+}
+
+// multiRegister is a map of read/write registers, a register which is never
+// written is 0. The state is a map from the key to the value.
+type multiRegister struct {
+	perparedState map[string]int
+}
+
+var _ core.PartitionModel = (*multiRegister)(nil)
+
+func (r *multiRegister) Prepare(state interface{}) {
+	// No state is dumped if the history has no state, then every register
+	// is 0.
+	r.perparedState, _ = state.(map[string]int)
+}
+
+func (r *multiRegister) Init() interface{} {
+	st := make(map[string]int, len(r.perparedState))
+	for k, v := range r.perparedState {
+		st[k] = v
+	}
+	return st
+}
+
+func (*multiRegister) Step(state interface{}, input interface{}, output interface{}) (bool, interface{}) {
+	st := state.(map[string]int)
+	inp := input.(MultiRegisterRequest)
+	out := output.(MultiRegisterResponse)
+
+	// read
+	if inp.Op == RegisterRead {
+		ok := out.Value == st[inp.Key] || out.Unknown
+		return ok, st
+	}
+
+	// write, the state must not be mutated.
+	next := make(map[string]int, len(st)+1)
+	for k, v := range st {
+		next[k] = v
+	}
+	next[inp.Key] = inp.Value
+	return true, next
+}
+
+func (*multiRegister) Equal(state1, state2 interface{}) bool {
+	st1 := state1.(map[string]int)
+	st2 := state2.(map[string]int)
+	for k, v := range st1 {
+		if st2[k] != v {
+			return false
+		}
+	}
+	for k, v := range st2 {
+		if st1[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (*multiRegister) Name() string {
+	return "multi_register"
+}
+
+func (*multiRegister) ReadOnly(input interface{}) bool {
+	return input.(MultiRegisterRequest).Op == RegisterRead
+}
+
+func (*multiRegister) Partition(input interface{}) string {
+	return input.(MultiRegisterRequest).Key
+}
+
+func (r *multiRegister) PartitionInit(key string) interface{} {
+	return map[string]int{key: r.perparedState[key]}
+}
+
+// MultiRegisterModel returns a model of read/write registers by key, the
+// history is checked key by key.
+func MultiRegisterModel() core.Model {
+	return &multiRegister{}
+}
+
+type multiRegisterParser struct {
+}
+
+func (p multiRegisterParser) OnRequest(data json.RawMessage) (interface{}, error) {
+	r := MultiRegisterRequest{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+func (p multiRegisterParser) OnResponse(data json.RawMessage) (interface{}, error) {
+	r := MultiRegisterResponse{}
+	err := json.Unmarshal(data, &r)
+	if r.Unknown {
+		return nil, err
+	}
+	return r, err
+}
+
+func (p multiRegisterParser) OnNoopResponse() interface{} {
+	return MultiRegisterResponse{Unknown: true}
+}
+
+func (p multiRegisterParser) OnState(data json.RawMessage) (interface{}, error) {
+	state := make(map[string]int)
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// MultiRegisterParser parses MultiRegister history.
+func MultiRegisterParser() history.RecordParser {
+	return multiRegisterParser{}
+}
+
+type multiRegisterCodec struct{}
+
+func (multiRegisterCodec) EncodeRequest(req interface{}) (history.Keyword, interface{}, error) {
+	r := req.(MultiRegisterRequest)
+	if r.Op == RegisterRead {
+		return "read", []interface{}{r.Key, nil}, nil
+	}
+	return "write", []interface{}{r.Key, int64(r.Value)}, nil
+}
+
+func (multiRegisterCodec) EncodeResponse(req interface{}, resp interface{}) (history.Keyword, interface{}, error) {
+	r := req.(MultiRegisterRequest)
+	if r.Op == RegisterRead {
+		return history.EDNOk, []interface{}{r.Key, int64(resp.(MultiRegisterResponse).Value)}, nil
+	}
+	return history.EDNOk, []interface{}{r.Key, int64(r.Value)}, nil
+}
+
+// decodeKeyValue decodes the [key value] tuple of Jepsen independent keys.
+func decodeKeyValue(value interface{}) (string, interface{}, error) {
+	tuple, ok := value.([]interface{})
+	if !ok || len(tuple) != 2 {
+		return "", nil, fmt.Errorf("%v is not a [key value] tuple", value)
+	}
+	key, ok := tuple[0].(string)
+	if !ok {
+		return "", nil, fmt.Errorf("key %v is not a string", tuple[0])
+	}
+	return key, tuple[1], nil
+}
+
+func (multiRegisterCodec) DecodeRequest(f history.Keyword, value interface{}) (interface{}, error) {
+	key, v, err := decodeKeyValue(value)
+	if err != nil {
+		return nil, err
+	}
+	switch f {
+	case "read":
+		return MultiRegisterRequest{Op: RegisterRead, Key: key}, nil
+	case "write":
+		n, err := history.EDNInt(v)
+		return MultiRegisterRequest{Op: RegisterWrite, Key: key, Value: int(n)}, err
+	default:
+		return nil, fmt.Errorf("unknown multi register operation %s", f)
+	}
+}
+
+func (multiRegisterCodec) DecodeResponse(typ history.Keyword, req interface{}, value interface{}) (interface{}, error) {
+	if typ != history.EDNOk {
+		// A register operation never fails, so it may have taken effect.
+		return MultiRegisterResponse{Unknown: true}, nil
+	}
+	if req.(MultiRegisterRequest).Op == RegisterWrite {
+		return MultiRegisterResponse{}, nil
+	}
+	_, v, err := decodeKeyValue(value)
+	if err != nil {
+		return nil, err
+	}
+	// A register reads 0 if it is never written.
+	if v == nil {
+		return MultiRegisterResponse{}, nil
+	}
+	n, err := history.EDNInt(v)
+	return MultiRegisterResponse{Value: int(n)}, err
+}
+
+// MultiRegisterEDNCodec converts MultiRegister history to and from Jepsen
+// EDN with independent keys, a read is {:f :read, :value ["k" 1]} and a
+// write is {:f :write, :value ["k" 1]}.
+func MultiRegisterEDNCodec() history.EDNCodec {
+	return multiRegisterCodec{}
+}
+
+func init() {
+	core.RegisterModel("multi_register", MultiRegisterModel)
+	history.RegisterParser("multi_register", MultiRegisterParser())
+	history.RegisterEDNCodec("multi_register", MultiRegisterEDNCodec())
+}
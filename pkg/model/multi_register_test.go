@@ -0,0 +1,73 @@
+package model
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/anishathalye/porcupine"
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+func TestMultiRegisterModel(t *testing.T) {
+	m := MultiRegisterModel()
+	m.Prepare(map[string]int{"a": 1})
+
+	// The writes to b don't affect the reads of a.
+	events := []porcupine.Event{
+		{Kind: porcupine.CallEvent, Value: MultiRegisterRequest{Op: RegisterWrite, Key: "b", Value: 2}, Id: 0},
+		{Kind: porcupine.CallEvent, Value: MultiRegisterRequest{Op: RegisterRead, Key: "a"}, Id: 1},
+		{Kind: porcupine.ReturnEvent, Value: MultiRegisterResponse{Value: 1}, Id: 1},
+		{Kind: porcupine.ReturnEvent, Value: MultiRegisterResponse{}, Id: 0},
+		{Kind: porcupine.CallEvent, Value: MultiRegisterRequest{Op: RegisterRead, Key: "b"}, Id: 2},
+		{Kind: porcupine.ReturnEvent, Value: MultiRegisterResponse{Value: 2}, Id: 2},
+		{Kind: porcupine.CallEvent, Value: MultiRegisterRequest{Op: RegisterRead, Key: "c"}, Id: 3},
+		{Kind: porcupine.ReturnEvent, Value: MultiRegisterResponse{Value: 0}, Id: 3},
+	}
+	if !porcupine.CheckEvents(convertModel(m), events) {
+		t.Fatal("expected operations to be linearizable")
+	}
+
+	// Read the old value of b after the write returns.
+	events[5] = porcupine.Event{Kind: porcupine.ReturnEvent, Value: MultiRegisterResponse{Value: 0}, Id: 2}
+	if porcupine.CheckEvents(convertModel(m), events) {
+		t.Fatal("expected operations to not be linearizable")
+	}
+
+	pm := m.(core.PartitionModel)
+	if key := pm.Partition(MultiRegisterRequest{Key: "b"}); key != "b" {
+		t.Fatalf("expected partition b, got %s", key)
+	}
+	if st := pm.PartitionInit("a"); !reflect.DeepEqual(st, map[string]int{"a": 1}) {
+		t.Fatalf("unexpected initial state %v", st)
+	}
+	if !m.Equal(map[string]int{"a": 0}, map[string]int{}) {
+		t.Fatal("a missing key must be 0")
+	}
+}
+
+func TestMultiRegisterEDN(t *testing.T) {
+	ops := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 0, Data: MultiRegisterRequest{Op: RegisterWrite, Key: "a", Value: 3}},
+		{Action: core.InvokeOperation, Proc: 1, Data: MultiRegisterRequest{Op: RegisterRead, Key: "b"}},
+		{Action: core.ReturnOperation, Proc: 0, Data: MultiRegisterResponse{}},
+		{Action: core.ReturnOperation, Proc: 1, Data: MultiRegisterResponse{Value: 7}},
+	}
+
+	var buf bytes.Buffer
+	if err := history.WriteEDN(&buf, ops, MultiRegisterEDNCodec()); err != nil {
+		t.Fatalf("write edn failed %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`{:type :ok, :f :read, :value ["b" 7], :process 1`)) {
+		t.Fatalf("expect a read of b, but got %s", buf.String())
+	}
+
+	readOps, err := history.ReadEDN(&buf, MultiRegisterEDNCodec())
+	if err != nil {
+		t.Fatalf("read edn failed %v", err)
+	}
+	if !reflect.DeepEqual(readOps, ops) {
+		t.Fatalf("expect %v, but got %v", ops, readOps)
+	}
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/pingcap/chaos/pkg/core"
 	"github.com/pingcap/chaos/pkg/history"
@@ -77,6 +78,10 @@ func (*register) Name() string {
 	return "register"
 }
 
+func (*register) ReadOnly(input interface{}) bool {
+	return input.(RegisterRequest).Op == RegisterRead
+}
+
 // RegisterModel returns a read/write register model
 func RegisterModel() core.Model {
 	return &register{}
@@ -117,3 +122,60 @@ func (p registerParser) OnState(data json.RawMessage) (interface{}, error) {
 func RegisterParser() history.RecordParser {
 	return registerParser{}
 }
+
+type registerCodec struct{}
+
+func (registerCodec) EncodeRequest(req interface{}) (history.Keyword, interface{}, error) {
+	r := req.(RegisterRequest)
+	if r.Op == RegisterRead {
+		return "read", nil, nil
+	}
+	return "write", int64(r.Value), nil
+}
+
+func (registerCodec) EncodeResponse(req interface{}, resp interface{}) (history.Keyword, interface{}, error) {
+	if req.(RegisterRequest).Op == RegisterRead {
+		return history.EDNOk, int64(resp.(RegisterResponse).Value), nil
+	}
+	return history.EDNOk, int64(req.(RegisterRequest).Value), nil
+}
+
+func (registerCodec) DecodeRequest(f history.Keyword, value interface{}) (interface{}, error) {
+	switch f {
+	case "read":
+		return RegisterRequest{Op: RegisterRead}, nil
+	case "write":
+		v, err := history.EDNInt(value)
+		return RegisterRequest{Op: RegisterWrite, Value: int(v)}, err
+	default:
+		return nil, fmt.Errorf("unknown register operation %s", f)
+	}
+}
+
+func (registerCodec) DecodeResponse(typ history.Keyword, req interface{}, value interface{}) (interface{}, error) {
+	if typ != history.EDNOk {
+		// A register operation never fails, so it may have taken effect.
+		return RegisterResponse{Unknown: true}, nil
+	}
+	if req.(RegisterRequest).Op == RegisterWrite {
+		return RegisterResponse{}, nil
+	}
+	// A register reads 0 if it is never written.
+	if value == nil {
+		return RegisterResponse{}, nil
+	}
+	v, err := history.EDNInt(value)
+	return RegisterResponse{Value: int(v)}, err
+}
+
+// RegisterEDNCodec converts Register history to and from Jepsen EDN, a read
+// is {:f :read, :value 1} and a write is {:f :write, :value 1}.
+func RegisterEDNCodec() history.EDNCodec {
+	return registerCodec{}
+}
+
+func init() {
+	core.RegisterModel("register", RegisterModel)
+	history.RegisterParser("register", RegisterParser())
+	history.RegisterEDNCodec("register", RegisterEDNCodec())
+}
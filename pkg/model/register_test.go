@@ -13,9 +13,9 @@ func TestRegisterModel(t *testing.T) {
 	// section VII
 
 	ops := []porcupine.Operation{
-		{RegisterRequest{RegisterWrite, 100}, 0, RegisterResponse{false, 0}, 100},
-		{RegisterRequest{RegisterRead, 0}, 25, RegisterResponse{false, 100}, 75},
-		{RegisterRequest{RegisterRead, 0}, 30, RegisterResponse{false, 0}, 60},
+		{Input: RegisterRequest{RegisterWrite, 100}, Call: 0, Output: RegisterResponse{false, 0}, Return: 100},
+		{Input: RegisterRequest{RegisterRead, 0}, Call: 25, Output: RegisterResponse{false, 100}, Return: 75},
+		{Input: RegisterRequest{RegisterRead, 0}, Call: 30, Output: RegisterResponse{false, 0}, Return: 60},
 	}
 	res := porcupine.CheckOperations(convertModel(RegisterModel()), ops)
 	if res != true {
@@ -24,12 +24,12 @@ func TestRegisterModel(t *testing.T) {
 
 	// same example as above, but with Event
 	events := []porcupine.Event{
-		{porcupine.CallEvent, RegisterRequest{RegisterWrite, 100}, 0},
-		{porcupine.CallEvent, RegisterRequest{RegisterRead, 0}, 1},
-		{porcupine.CallEvent, RegisterRequest{RegisterRead, 0}, 2},
-		{porcupine.ReturnEvent, RegisterResponse{false, 0}, 2},
-		{porcupine.ReturnEvent, RegisterResponse{false, 100}, 1},
-		{porcupine.ReturnEvent, RegisterResponse{false, 0}, 0},
+		{Kind: porcupine.CallEvent, Value: RegisterRequest{RegisterWrite, 100}, Id: 0},
+		{Kind: porcupine.CallEvent, Value: RegisterRequest{RegisterRead, 0}, Id: 1},
+		{Kind: porcupine.CallEvent, Value: RegisterRequest{RegisterRead, 0}, Id: 2},
+		{Kind: porcupine.ReturnEvent, Value: RegisterResponse{false, 0}, Id: 2},
+		{Kind: porcupine.ReturnEvent, Value: RegisterResponse{false, 100}, Id: 1},
+		{Kind: porcupine.ReturnEvent, Value: RegisterResponse{false, 0}, Id: 0},
 	}
 	res = porcupine.CheckEvents(convertModel(RegisterModel()), events)
 	if res != true {
@@ -37,9 +37,9 @@ func TestRegisterModel(t *testing.T) {
 	}
 
 	ops = []porcupine.Operation{
-		{RegisterRequest{RegisterWrite, 200}, 0, RegisterResponse{false, 0}, 100},
-		{RegisterRequest{RegisterRead, 0}, 10, RegisterResponse{false, 200}, 30},
-		{RegisterRequest{RegisterRead, 0}, 40, RegisterResponse{false, 0}, 90},
+		{Input: RegisterRequest{RegisterWrite, 200}, Call: 0, Output: RegisterResponse{false, 0}, Return: 100},
+		{Input: RegisterRequest{RegisterRead, 0}, Call: 10, Output: RegisterResponse{false, 200}, Return: 30},
+		{Input: RegisterRequest{RegisterRead, 0}, Call: 40, Output: RegisterResponse{false, 0}, Return: 90},
 	}
 	res = porcupine.CheckOperations(convertModel(RegisterModel()), ops)
 	if res != false {
@@ -48,12 +48,12 @@ func TestRegisterModel(t *testing.T) {
 
 	// same example as above, but with Event
 	events = []porcupine.Event{
-		{porcupine.CallEvent, RegisterRequest{RegisterWrite, 200}, 0},
-		{porcupine.CallEvent, RegisterRequest{RegisterRead, 0}, 1},
-		{porcupine.ReturnEvent, RegisterResponse{false, 200}, 1},
-		{porcupine.CallEvent, RegisterRequest{RegisterRead, 0}, 2},
-		{porcupine.ReturnEvent, RegisterResponse{false, 0}, 2},
-		{porcupine.ReturnEvent, RegisterResponse{false, 0}, 0},
+		{Kind: porcupine.CallEvent, Value: RegisterRequest{RegisterWrite, 200}, Id: 0},
+		{Kind: porcupine.CallEvent, Value: RegisterRequest{RegisterRead, 0}, Id: 1},
+		{Kind: porcupine.ReturnEvent, Value: RegisterResponse{false, 200}, Id: 1},
+		{Kind: porcupine.CallEvent, Value: RegisterRequest{RegisterRead, 0}, Id: 2},
+		{Kind: porcupine.ReturnEvent, Value: RegisterResponse{false, 0}, Id: 2},
+		{Kind: porcupine.ReturnEvent, Value: RegisterResponse{false, 0}, Id: 0},
 	}
 	res = porcupine.CheckEvents(convertModel(RegisterModel()), events)
 	if res != false {
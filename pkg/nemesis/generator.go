@@ -1,6 +1,7 @@
 package nemesis
 
 import (
+	"fmt"
 	"math/rand"
 	"time"
 
@@ -119,3 +120,28 @@ func shuffleIndices(n int) []int {
 func NewDropGenerator(name string) core.NemesisGenerator {
 	return dropGenerator{name: name}
 }
+
+var (
+	killGeneratorNames = []string{"random_kill", "minor_kill", "major_kill", "all_kill"}
+	dropGeneratorNames = []string{"random_drop", "minor_drop", "major_drop", "all_drop"}
+)
+
+// GeneratorNames returns the names of the generators which NewGenerator creates.
+func GeneratorNames() []string {
+	return append(append([]string(nil), killGeneratorNames...), dropGeneratorNames...)
+}
+
+// NewGenerator creates a kill or drop generator by name.
+func NewGenerator(db string, name string) (core.NemesisGenerator, error) {
+	for _, n := range killGeneratorNames {
+		if n == name {
+			return NewKillGenerator(db, name), nil
+		}
+	}
+	for _, n := range dropGeneratorNames {
+		if n == name {
+			return NewDropGenerator(name), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid nemesis generator %s", name)
+}
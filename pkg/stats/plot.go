@@ -0,0 +1,244 @@
+package stats
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"path"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+// Size of a plot in pixels.
+const (
+	plotWidth   = 900
+	plotHeight  = 400
+	plotLeft    = 70
+	plotRight   = 120
+	plotTop     = 30
+	plotBottom  = 40
+	nemesisFill = "rgba(255,160,0,0.2)"
+)
+
+// Colours of the outcomes, the same as the timeline.
+var outcomeColors = []struct {
+	outcome history.Keyword
+	label   string
+	color   string
+}{
+	{history.EDNOk, "ok", "#4caf50"},
+	{history.EDNFail, "fail", "#e53935"},
+	{history.EDNInfo, "unknown", "#9e9e9e"},
+}
+
+// plot draws the axes, the nemesis windows and the legend of a chart, the
+// x axis is the time in seconds since the history starts.
+type plot struct {
+	w     *bufio.Writer
+	xMax  float64
+	yMin  float64
+	yMax  float64
+	logY  bool
+	ticks []float64
+}
+
+func (p *plot) x(seconds float64) float64 {
+	return plotLeft + seconds/p.xMax*(plotWidth-plotLeft-plotRight)
+}
+
+func (p *plot) y(v float64) float64 {
+	h := float64(plotHeight - plotTop - plotBottom)
+	if p.logY {
+		return plotTop + h - (math.Log10(v)-math.Log10(p.yMin))/(math.Log10(p.yMax)-math.Log10(p.yMin))*h
+	}
+	return plotTop + h - (v-p.yMin)/(p.yMax-p.yMin)*h
+}
+
+func (p *plot) begin(title string, yLabel string, nemeses []NemesisStats) {
+	fmt.Fprintf(p.w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="11">`+"\n", plotWidth, plotHeight)
+	fmt.Fprintf(p.w, `<rect width="%d" height="%d" fill="white"/>`+"\n", plotWidth, plotHeight)
+	fmt.Fprintf(p.w, `<text x="%d" y="18" font-size="14">%s</text>`+"\n", plotLeft, html.EscapeString(title))
+
+	bottom := p.y(p.yMin)
+	for _, n := range nemeses {
+		start := math.Max(n.Start.Seconds(), 0)
+		end := math.Min(n.End.Seconds(), p.xMax)
+		if end < start {
+			continue
+		}
+		fmt.Fprintf(p.w, `<rect x="%.1f" y="%d" width="%.1f" height="%.1f" fill="%s"><title>%s</title></rect>`+"\n",
+			p.x(start), plotTop, math.Max(p.x(end)-p.x(start), 1), bottom-plotTop, nemesisFill,
+			html.EscapeString(fmt.Sprintf("%s on %s", n.Name, n.Node)))
+	}
+
+	// Axes.
+	fmt.Fprintf(p.w, `<g stroke="black"><line x1="%d" y1="%.1f" x2="%d" y2="%.1f"/><line x1="%d" y1="%d" x2="%d" y2="%.1f"/></g>`+"\n",
+		plotLeft, bottom, plotWidth-plotRight, bottom, plotLeft, plotTop, plotLeft, bottom)
+	for _, t := range niceTicks(0, p.xMax) {
+		fmt.Fprintf(p.w, `<text x="%.1f" y="%.1f" text-anchor="middle">%g</text>`+"\n", p.x(t), bottom+15, t)
+	}
+	fmt.Fprintf(p.w, `<text x="%d" y="%d" text-anchor="middle">time (s)</text>`+"\n", (plotWidth-plotRight+plotLeft)/2, plotHeight-5)
+	for _, t := range p.ticks {
+		fmt.Fprintf(p.w, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/><text x="%d" y="%.1f" text-anchor="end">%g</text>`+"\n",
+			plotLeft, p.y(t), plotWidth-plotRight, p.y(t), plotLeft-5, p.y(t)+4, t)
+	}
+	fmt.Fprintf(p.w, `<text transform="translate(15,%d) rotate(-90)" text-anchor="middle">%s</text>`+"\n", (plotHeight)/2, yLabel)
+
+	// Legend.
+	for i, c := range outcomeColors {
+		y := plotTop + 10 + i*18
+		fmt.Fprintf(p.w, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/><text x="%d" y="%d">%s</text>`+"\n",
+			plotWidth-plotRight+15, y, c.color, plotWidth-plotRight+30, y+9, c.label)
+	}
+	y := plotTop + 10 + len(outcomeColors)*18
+	fmt.Fprintf(p.w, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/><text x="%d" y="%d">nemesis</text>`+"\n",
+		plotWidth-plotRight+15, y, nemesisFill, plotWidth-plotRight+30, y+9)
+}
+
+func (p *plot) end() error {
+	fmt.Fprintln(p.w, "</svg>")
+	return p.w.Flush()
+}
+
+// niceTicks returns about 10 round ticks from min to max.
+func niceTicks(min float64, max float64) []float64 {
+	if max <= min {
+		return []float64{min}
+	}
+	step := math.Pow(10, math.Floor(math.Log10((max-min)/10)))
+	for _, f := range []float64{1, 2, 5, 10} {
+		if (max-min)/(step*f) <= 10 {
+			step *= f
+			break
+		}
+	}
+	var ticks []float64
+	for t := math.Ceil(min/step) * step; t <= max+step/1e6; t += step {
+		ticks = append(ticks, math.Round(t/step)*step)
+	}
+	return ticks
+}
+
+// WriteLatencySVG plots the latency of every operation by its invoke time,
+// coloured by its outcome, in log scale. An operation never returns is not
+// plotted.
+func (s *Stats) WriteLatencySVG(w io.Writer) error {
+	p := &plot{
+		w:    bufio.NewWriter(w),
+		xMax: math.Max(s.Duration.Seconds(), 1),
+		yMin: math.MaxFloat64,
+		yMax: 1,
+		logY: true,
+	}
+	for _, o := range s.ops {
+		if !o.returned {
+			continue
+		}
+		ms := latencyMillis(o.latency)
+		p.yMin = math.Min(p.yMin, ms)
+		p.yMax = math.Max(p.yMax, ms)
+	}
+	p.yMin = math.Pow(10, math.Floor(math.Log10(math.Min(p.yMin, p.yMax))))
+	p.yMax = math.Pow(10, math.Ceil(math.Log10(p.yMax)))
+	if p.yMax <= p.yMin {
+		p.yMax = p.yMin * 10
+	}
+	for t := p.yMin; t <= p.yMax*1.001; t *= 10 {
+		p.ticks = append(p.ticks, t)
+	}
+
+	p.begin(s.title("latency"), "latency (ms)", s.Nemeses)
+	for _, c := range outcomeColors {
+		fmt.Fprintf(p.w, `<g fill="%s" fill-opacity="0.6">`+"\n", c.color)
+		for _, o := range s.ops {
+			if o.returned && o.outcome == c.outcome {
+				fmt.Fprintf(p.w, `<circle cx="%.1f" cy="%.1f" r="2"/>`+"\n", p.x(o.start.Seconds()), p.y(latencyMillis(o.latency)))
+			}
+		}
+		fmt.Fprintln(p.w, "</g>")
+	}
+	return p.end()
+}
+
+// latencyMillis returns the latency in milliseconds, at least 1us to be
+// plotted in log scale.
+func latencyMillis(d time.Duration) float64 {
+	if d < time.Microsecond {
+		d = time.Microsecond
+	}
+	return float64(d) / float64(time.Millisecond)
+}
+
+// WriteThroughputSVG plots the operations completed every second by outcome.
+func (s *Stats) WriteThroughputSVG(w io.Writer) error {
+	seconds := int(s.Duration.Seconds()) + 1
+	counts := make(map[history.Keyword][]int)
+	for _, c := range outcomeColors {
+		counts[c.outcome] = make([]int, seconds)
+	}
+	max := 1
+	for _, o := range s.ops {
+		if !o.returned {
+			continue
+		}
+		i := int((o.start + o.latency).Seconds())
+		if i >= seconds {
+			i = seconds - 1
+		}
+		counts[o.outcome][i]++
+		if n := counts[o.outcome][i]; n > max {
+			max = n
+		}
+	}
+
+	p := &plot{
+		w:    bufio.NewWriter(w),
+		xMax: float64(seconds),
+		yMax: float64(max),
+	}
+	p.ticks = niceTicks(0, p.yMax)
+	if last := p.ticks[len(p.ticks)-1]; last > p.yMax {
+		p.yMax = last
+	}
+
+	p.begin(s.title("throughput"), "operations/s", s.Nemeses)
+	for _, c := range outcomeColors {
+		fmt.Fprintf(p.w, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="`, c.color)
+		for i, n := range counts[c.outcome] {
+			fmt.Fprintf(p.w, "%.1f,%.1f ", p.x(float64(i)+0.5), p.y(float64(n)))
+		}
+		fmt.Fprintln(p.w, `"/>`)
+	}
+	return p.end()
+}
+
+func (s *Stats) title(name string) string {
+	if len(s.HistoryFile) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s of %s", name, path.Base(s.HistoryFile))
+}
+
+// WritePlots writes the latency and throughput plots next to the history
+// file, and returns their names.
+func (s *Stats) WritePlots() ([]string, error) {
+	names := []string{s.HistoryFile + ".latency.svg", s.HistoryFile + ".throughput.svg"}
+	for i, write := range []func(io.Writer) error{s.WriteLatencySVG, s.WriteThroughputSVG} {
+		f, err := os.Create(names[i])
+		if err != nil {
+			return nil, err
+		}
+		if err = write(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		if err = f.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+// checkSVG checks the svg is well-formed and counts its elements.
+func checkSVG(t *testing.T, data []byte) map[string]int {
+	counts := make(map[string]int)
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return counts
+		}
+		if err != nil {
+			t.Fatalf("invalid svg %v: %s", err, data)
+		}
+		if e, ok := tok.(xml.StartElement); ok {
+			counts[e.Name.Local]++
+		}
+	}
+}
+
+func TestPlots(t *testing.T) {
+	ops := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 1, Data: history.NoopRequest{}, Time: 0},
+		{Action: core.ReturnOperation, Proc: 1, Data: history.NoopResponse{}, Time: 10 * time.Millisecond},
+		{Action: core.InvokeOperation, Proc: 2, Data: history.NoopRequest{}, Time: time.Second},
+		{Action: core.ReturnOperation, Proc: 2, Data: nil, Time: 3 * time.Second},
+		{Action: core.InvokeOperation, Proc: 3, Data: history.NoopRequest{}, Time: 3 * time.Second},
+	}
+	records := []core.NemesisRecord{
+		{Kind: core.NemesisInvoke, Name: "kill", Node: "n1", Start: time.Second, End: time.Second},
+	}
+	s := New(ops, records, nil, 0)
+
+	var buf bytes.Buffer
+	if err := s.WriteLatencySVG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	counts := checkSVG(t, buf.Bytes())
+	// The operation never returns is not plotted.
+	if counts["circle"] != 2 {
+		t.Fatalf("expect 2 points, but got %d", counts["circle"])
+	}
+	if !strings.Contains(buf.String(), "kill on n1") {
+		t.Fatal("nemesis windows must be shaded")
+	}
+
+	buf.Reset()
+	if err := s.WriteThroughputSVG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if counts = checkSVG(t, buf.Bytes()); counts["polyline"] != 3 {
+		t.Fatalf("expect a line for every outcome, but got %d", counts["polyline"])
+	}
+
+	// An empty history still has the axes.
+	buf.Reset()
+	if err := New(nil, nil, nil, 0).WriteLatencySVG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkSVG(t, buf.Bytes())
+}
+
+func TestNiceTicks(t *testing.T) {
+	ticks := niceTicks(0, 95)
+	if len(ticks) != 10 || ticks[1] != 10 || ticks[9] != 90 {
+		t.Fatalf("unexpected ticks %v", ticks)
+	}
+	if ticks = niceTicks(0, 3); len(ticks) != 7 || ticks[6] != 3 {
+		t.Fatalf("unexpected ticks %v", ticks)
+	}
+}
@@ -0,0 +1,308 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+// DefaultWindow is the default length of a window of the time series.
+const DefaultWindow = 10 * time.Second
+
+// Latency is the latency percentiles of the operations which return ok or fail.
+type Latency struct {
+	P50 time.Duration `json:"p50"`
+	P95 time.Duration `json:"p95"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+// Summary counts the operations by outcome. An operation without a response
+// is unknown, and has no latency.
+type Summary struct {
+	Count       int     `json:"count"`
+	Ok          int     `json:"ok"`
+	Fail        int     `json:"fail"`
+	Unknown     int     `json:"unknown"`
+	OkRate      float64 `json:"ok_rate"`
+	FailRate    float64 `json:"fail_rate"`
+	UnknownRate float64 `json:"unknown_rate"`
+	Latency     Latency `json:"latency"`
+
+	latencies []time.Duration
+}
+
+// Bucket is the summary of the operations invoked in a window.
+type Bucket struct {
+	Start time.Duration `json:"start"`
+	Summary
+	// Throughput is the ok operations per second.
+	Throughput float64 `json:"throughput"`
+	// Nemeses are the nemeses active in the window, like kill@n1.
+	Nemeses []string `json:"nemeses,omitempty"`
+}
+
+// NemesisStats is the summary of the operations invoked in a nemesis window.
+type NemesisStats struct {
+	history.NemesisWindow
+	Summary Summary `json:"summary"`
+}
+
+// Stats is how a history performs.
+type Stats struct {
+	HistoryFile string        `json:"history,omitempty"`
+	Duration    time.Duration `json:"duration"`
+	Window      time.Duration `json:"window"`
+	Total       Summary       `json:"total"`
+	// Ops are the summaries by the name of the operation, like read, see
+	// history.OpName.
+	Ops     map[string]*Summary `json:"ops"`
+	Nodes   map[string]*Summary `json:"nodes"`
+	Series  []Bucket            `json:"series"`
+	Nemeses []NemesisStats      `json:"nemeses"`
+
+	// ops are kept for the plots.
+	ops []op
+}
+
+// op is an operation from its invoke to its return.
+type op struct {
+	name    string
+	node    string
+	start   time.Duration
+	latency time.Duration
+	outcome history.Keyword
+	// returned is false if the operation never returns.
+	returned bool
+}
+
+func (s *Summary) add(o op) {
+	s.Count++
+	switch o.outcome {
+	case history.EDNOk:
+		s.Ok++
+	case history.EDNFail:
+		s.Fail++
+	default:
+		s.Unknown++
+		return
+	}
+	s.latencies = append(s.latencies, o.latency)
+}
+
+func (s *Summary) finish() {
+	if s.Count != 0 {
+		s.OkRate = float64(s.Ok) / float64(s.Count)
+		s.FailRate = float64(s.Fail) / float64(s.Count)
+		s.UnknownRate = float64(s.Unknown) / float64(s.Count)
+	}
+
+	l := s.latencies
+	if len(l) == 0 {
+		return
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+	s.Latency = Latency{
+		P50: percentile(l, 0.5),
+		P95: percentile(l, 0.95),
+		P99: percentile(l, 0.99),
+		Max: l[len(l)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile of the sorted latencies.
+func percentile(l []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p*float64(len(l)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return l[i]
+}
+
+// New computes the stats of the operations, which are not completed, and
+// the nemesis records. The codec names the operations and tells the failed
+// ones, without it the operations are named by their types and every
+// known response is ok. DefaultWindow is used if window is not positive.
+func New(ops []core.Operation, records []core.NemesisRecord, codec history.EDNCodec, window time.Duration) *Stats {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	var end time.Duration
+	for _, o := range ops {
+		if o.Time > end {
+			end = o.Time
+		}
+	}
+
+	var all []op
+	pending := make(map[int64]int)
+	reqs := make(map[int64]interface{})
+	for _, o := range ops {
+		if o.Action == core.InvokeOperation {
+			pending[o.Proc] = len(all)
+			reqs[o.Proc] = o.Data
+			all = append(all, op{
+				name:    history.OpName(o.Data, codec),
+				node:    o.Node,
+				start:   o.Time,
+				outcome: history.EDNInfo,
+			})
+			continue
+		}
+
+		i, ok := pending[o.Proc]
+		if !ok {
+			continue
+		}
+		delete(pending, o.Proc)
+		all[i].outcome = history.OutcomeOf(reqs[o.Proc], o.Data, codec)
+		all[i].latency = o.Time - all[i].start
+		all[i].returned = true
+	}
+
+	s := &Stats{
+		Duration: end,
+		Window:   window,
+		Ops:      make(map[string]*Summary),
+		Nodes:    make(map[string]*Summary),
+		ops:      all,
+	}
+	for _, w := range history.NemesisWindows(records, end) {
+		s.Nemeses = append(s.Nemeses, NemesisStats{NemesisWindow: w})
+	}
+	if len(all) != 0 {
+		s.Series = make([]Bucket, int(end/window)+1)
+	}
+	for i := range s.Series {
+		b := &s.Series[i]
+		b.Start = time.Duration(i) * window
+		for _, n := range s.Nemeses {
+			if n.Start < b.Start+window && n.End >= b.Start {
+				b.Nemeses = append(b.Nemeses, n.Name+"@"+n.Node)
+			}
+		}
+	}
+
+	for _, o := range all {
+		s.Total.add(o)
+		summaryOf(s.Ops, o.name).add(o)
+		summaryOf(s.Nodes, o.node).add(o)
+		s.Series[int(o.start/window)].add(o)
+		for i := range s.Nemeses {
+			if n := &s.Nemeses[i]; o.start >= n.Start && o.start <= n.End {
+				n.Summary.add(o)
+			}
+		}
+	}
+
+	s.Total.finish()
+	for _, sum := range s.Ops {
+		sum.finish()
+	}
+	for _, sum := range s.Nodes {
+		sum.finish()
+	}
+	for i := range s.Series {
+		b := &s.Series[i]
+		b.finish()
+		b.Throughput = float64(b.Ok) / window.Seconds()
+	}
+	for i := range s.Nemeses {
+		s.Nemeses[i].Summary.finish()
+	}
+	return s
+}
+
+func summaryOf(m map[string]*Summary, key string) *Summary {
+	s, ok := m[key]
+	if !ok {
+		s = new(Summary)
+		m[key] = s
+	}
+	return s
+}
+
+// Read computes the stats of the history file, see New.
+func Read(historyFile string, p history.RecordParser, codec history.EDNCodec, window time.Duration) (*Stats, error) {
+	ops, _, err := history.ReadHistory(historyFile, p)
+	if err != nil {
+		return nil, err
+	}
+	records, err := history.ReadNemesisHistory(historyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	s := New(ops, records, codec, window)
+	s.HistoryFile = historyFile
+	return s, nil
+}
+
+// WriteJSON writes the stats in JSON.
+func (s *Stats) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteText writes the stats in tables.
+func (s *Stats) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if len(s.HistoryFile) != 0 {
+		fmt.Fprintf(tw, "history: %s\n", s.HistoryFile)
+	}
+	fmt.Fprintf(tw, "duration: %s\n\n", s.Duration.Round(time.Millisecond))
+
+	fmt.Fprintln(tw, "OP\tCOUNT\tOK\tFAIL\tUNKNOWN\tP50\tP95\tP99\tMAX")
+	writeSummary(tw, "all", &s.Total)
+	for _, name := range sortedKeys(s.Ops) {
+		writeSummary(tw, name, s.Ops[name])
+	}
+
+	fmt.Fprintln(tw, "\nNODE\tCOUNT\tOK\tFAIL\tUNKNOWN\tP50\tP95\tP99\tMAX")
+	for _, node := range sortedKeys(s.Nodes) {
+		writeSummary(tw, node, s.Nodes[node])
+	}
+
+	fmt.Fprintln(tw, "\nNEMESIS\tCOUNT\tOK\tFAIL\tUNKNOWN\tP50\tP95\tP99\tMAX")
+	for i := range s.Nemeses {
+		n := &s.Nemeses[i]
+		name := fmt.Sprintf("%s@%s %s-%s", n.Name, n.Node, n.Start.Round(time.Second), n.End.Round(time.Second))
+		writeSummary(tw, name, &n.Summary)
+	}
+
+	fmt.Fprintf(tw, "\nWINDOW(%s)\tCOUNT\tOK\tFAIL\tUNKNOWN\tOPS/S\tP50\tP99\tNEMESES\n", s.Window)
+	for _, b := range s.Series {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%.1f\t%s\t%s\t%s\n", b.Start, b.Count, b.Ok, b.Fail, b.Unknown,
+			b.Throughput, b.Latency.P50.Round(time.Microsecond), b.Latency.P99.Round(time.Microsecond), strings.Join(b.Nemeses, ","))
+	}
+	return tw.Flush()
+}
+
+func writeSummary(w io.Writer, name string, s *Summary) {
+	fmt.Fprintf(w, "%s\t%d\t%d (%.1f%%)\t%d (%.1f%%)\t%d (%.1f%%)\t%s\t%s\t%s\t%s\n", name, s.Count,
+		s.Ok, s.OkRate*100, s.Fail, s.FailRate*100, s.Unknown, s.UnknownRate*100,
+		s.Latency.P50.Round(time.Microsecond), s.Latency.P95.Round(time.Microsecond),
+		s.Latency.P99.Round(time.Microsecond), s.Latency.Max.Round(time.Microsecond))
+}
+
+func sortedKeys(m map[string]*Summary) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+func TestStats(t *testing.T) {
+	var ops []core.Operation
+	for i := 0; i < 100; i++ {
+		proc := int64(i)
+		node := "n1"
+		if i%2 == 1 {
+			node = "n2"
+		}
+		start := time.Duration(i) * 100 * time.Millisecond
+		ops = append(ops, core.Operation{Action: core.InvokeOperation, Proc: proc, Data: history.NoopRequest{}, Time: start, Node: node})
+		switch {
+		case i == 99:
+			// Never returns.
+		case i%10 == 0:
+			ops = append(ops, core.Operation{Action: core.ReturnOperation, Proc: proc, Data: nil, Time: start + time.Second, Node: node})
+		default:
+			latency := time.Duration(i+1) * time.Millisecond
+			ops = append(ops, core.Operation{Action: core.ReturnOperation, Proc: proc, Data: history.NoopResponse{}, Time: start + latency, Node: node})
+		}
+	}
+	records := []core.NemesisRecord{
+		{Kind: core.NemesisInvoke, Name: "kill", Node: "n1", Start: 2 * time.Second, End: 2 * time.Second},
+		{Kind: core.NemesisRecover, Name: "kill", Node: "n1", Start: 3 * time.Second, End: 3 * time.Second},
+	}
+
+	s := New(ops, records, nil, time.Second)
+	if s.Total.Count != 100 || s.Total.Ok != 89 || s.Total.Unknown != 11 || s.Total.Fail != 0 {
+		t.Fatalf("unexpected total %+v", s.Total)
+	}
+	if s.Total.Latency.P50 != 50*time.Millisecond || s.Total.Latency.Max != 99*time.Millisecond {
+		t.Fatalf("unexpected latency %+v", s.Total.Latency)
+	}
+	if len(s.Ops) != 1 || s.Ops["history.NoopRequest"].Count != 100 {
+		t.Fatalf("unexpected ops %v", s.Ops)
+	}
+	if s.Nodes["n1"].Count != 50 || s.Nodes["n2"].Count != 50 {
+		t.Fatalf("unexpected nodes %v", s.Nodes)
+	}
+
+	if len(s.Series) != 11 {
+		t.Fatalf("expect 11 windows, but got %d", len(s.Series))
+	}
+	b := s.Series[2]
+	if b.Start != 2*time.Second || b.Count != 10 || b.Ok != 9 || b.Throughput != 9 || len(b.Nemeses) != 1 || b.Nemeses[0] != "kill@n1" {
+		t.Fatalf("unexpected window %+v", b)
+	}
+	if len(s.Series[5].Nemeses) != 0 {
+		t.Fatalf("unexpected window %+v", s.Series[5])
+	}
+	if len(s.Nemeses) != 1 || s.Nemeses[0].Summary.Count != 11 {
+		t.Fatalf("unexpected nemesis stats %+v", s.Nemeses)
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "kill@n1") {
+		t.Fatalf("nemesis windows must be annotated, got %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Stats
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded.Total.Ok != 89 || len(decoded.Series) != 11 {
+		t.Fatalf("unexpected json %s, err %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,191 @@
+package timeline
+
+import (
+	"fmt"
+	"html/template"
+	"time"
+)
+
+// page is the data of the HTML template, positions are in percent of the
+// history length.
+type page struct {
+	*Timeline
+	Lanes   []lane
+	Bands   []band
+	Ticks   []tick
+	Blocked *Op
+	Prefix  int
+}
+
+type lane struct {
+	Label string
+	Bars  []bar
+}
+
+type bar struct {
+	Left  float64
+	Width float64
+	Class string
+	Title string
+	Op    Op
+}
+
+type band struct {
+	Left  float64
+	Width float64
+	Title string
+}
+
+type tick struct {
+	Left  float64
+	Label string
+}
+
+// minWidth keeps an instant operation visible.
+const minWidth = 0.05
+
+func newPage(t *Timeline) *page {
+	p := &page{Timeline: t}
+	pos := func(d time.Duration) float64 {
+		if t.End <= 0 {
+			return 0
+		}
+		return float64(d) * 100 / float64(t.End)
+	}
+
+	lanes := make(map[int64]int)
+	for _, proc := range t.Procs() {
+		lanes[proc] = len(p.Lanes)
+		p.Lanes = append(p.Lanes, lane{Label: fmt.Sprintf("proc %d", proc)})
+	}
+	for _, op := range t.Ops {
+		l := &p.Lanes[lanes[op.Proc]]
+		if len(op.Node) != 0 {
+			l.Label = fmt.Sprintf("proc %d  %s/%d", op.Proc, op.Node, op.Client)
+		}
+
+		class := op.Outcome
+		if op.Order > 0 {
+			class += " linearized"
+		}
+		if op.Blocked {
+			class += " blocked"
+			blocked := op
+			p.Blocked = &blocked
+		}
+		width := pos(op.End) - pos(op.Start)
+		if width < minWidth {
+			width = minWidth
+		}
+		l.Bars = append(l.Bars, bar{
+			Left:  pos(op.Start),
+			Width: width,
+			Class: class,
+			Title: fmt.Sprintf("%s -> %s (%s)", op.Request, op.Response, op.Outcome),
+			Op:    op,
+		})
+		if op.Order > p.Prefix {
+			p.Prefix = op.Order
+		}
+	}
+
+	for _, w := range t.Nemeses {
+		start := w.Start
+		if start < 0 {
+			start = 0
+		}
+		p.Bands = append(p.Bands, band{
+			Left:  pos(start),
+			Width: pos(w.End) - pos(start),
+			Title: fmt.Sprintf("%s on %s, %v - %v %s", w.Name, w.Node, w.Start, w.End, w.Err),
+		})
+	}
+
+	const tickCount = 10
+	for i := 0; i <= tickCount; i++ {
+		d := t.End * time.Duration(i) / tickCount
+		p.Ticks = append(p.Ticks, tick{Left: pos(d), Label: d.Round(time.Millisecond).String()})
+	}
+	return p
+}
+
+var pageTemplate = template.Must(template.New("timeline").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; margin: 16px; }
+.legend span { display: inline-block; margin-right: 16px; }
+.legend i { display: inline-block; width: 14px; height: 10px; margin-right: 4px; vertical-align: middle; }
+.chart { overflow-x: auto; border: 1px solid #ccc; }
+.rows { position: relative; min-width: 100%; }
+.row { display: flex; height: 22px; border-bottom: 1px solid #eee; }
+.label { flex: none; width: 160px; padding: 3px 6px; white-space: nowrap; overflow: hidden; background: #fafafa; position: sticky; left: 0; z-index: 3; }
+.track { position: relative; flex: auto; }
+.axis .track { border-bottom: 1px solid #999; }
+.tick { position: absolute; top: 4px; font-size: 11px; color: #666; white-space: nowrap; }
+.bands { position: absolute; top: 0; bottom: 0; left: 172px; right: 0; pointer-events: none; }
+.band { position: absolute; top: 0; bottom: 0; background: rgba(255, 160, 0, 0.18); border-left: 1px dashed #e90; border-right: 1px dashed #e90; pointer-events: auto; }
+.op { position: absolute; top: 4px; height: 14px; border-radius: 2px; cursor: pointer; z-index: 2; box-sizing: border-box; }
+.ok { background: #4caf50; }
+.fail { background: #e53935; }
+.unknown { background: #9e9e9e; }
+.legend .linearized, .op.linearized { border: 2px solid #1565c0; }
+.legend .blocked, .op.blocked { border: 3px solid #000; background: #ff1744; z-index: 4; }
+.op.dim { opacity: 0.35; }
+#detail { white-space: pre-wrap; font-family: monospace; margin-top: 12px; padding: 8px; background: #f5f5f5; min-height: 40px; }
+</style>
+</head>
+<body>
+<h2>{{.Title}}</h2>
+<div class="legend">
+<span><i class="ok"></i>ok</span>
+<span><i class="fail"></i>fail</span>
+<span><i class="unknown"></i>unknown</span>
+<span><i style="background: rgba(255, 160, 0, 0.4)"></i>nemesis</span>
+{{if .Explained}}<span><i class="linearized"></i>in linearizable prefix</span>
+<span><i class="blocked"></i>could not be placed</span>{{end}}
+<span>zoom <button onclick="zoom(2)">+</button> <button onclick="zoom(0.5)">-</button></span>
+<span><label><input type="checkbox" onchange="dim(this.checked)"> dim operations out of the prefix</label></span>
+</div>
+<p>
+{{len .Ops}} operations, {{len .Lanes}} processes, {{len .Nemeses}} nemesis windows, {{.End}}.
+{{if .Explained}}{{if .Linearizable}}The history is linearizable.
+{{else}}The longest linearizable prefix found has {{.Prefix}} operations{{if .Exhausted}}, the search stopped early so a longer one may exist{{end}}.
+{{with .Blocked}}Operation {{.Request}} of proc {{.Proc}} at {{.Start}} could not be placed after it.{{end}}{{end}}{{end}}
+</p>
+<div class="chart" id="chart">
+<div class="rows" id="rows">
+<div class="row axis"><div class="label">time</div><div class="track">{{range .Ticks}}<span class="tick" style="left: {{printf "%.4f" .Left}}%">{{.Label}}</span>{{end}}</div></div>
+{{range .Lanes}}<div class="row"><div class="label" title="{{.Label}}">{{.Label}}</div><div class="track">{{range .Bars}}<div class="op {{.Class}}" style="left: {{printf "%.4f" .Left}}%; width: {{printf "%.4f" .Width}}%" title="{{.Title}}" data-index="{{.Op.Index}}" data-proc="{{.Op.Proc}}" data-node="{{.Op.Node}}" data-start="{{.Op.Start}}" data-end="{{.Op.End}}" data-outcome="{{.Op.Outcome}}" data-order="{{.Op.Order}}" data-request="{{.Op.Request}}" data-response="{{.Op.Response}}"></div>{{end}}</div></div>
+{{end}}<div class="bands">{{range .Bands}}<div class="band" style="left: {{printf "%.4f" .Left}}%; width: {{printf "%.4f" .Width}}%" title="{{.Title}}"></div>{{end}}</div>
+</div>
+</div>
+<div id="detail">Click an operation to show it.</div>
+<script>
+var scale = 1;
+function zoom(f) {
+  scale = Math.max(1, scale * f);
+  document.getElementById("rows").style.width = (scale * 100) + "%";
+}
+function dim(on) {
+  document.querySelectorAll(".op").forEach(function (e) {
+    e.classList.toggle("dim", on && e.dataset.order === "0" && !e.classList.contains("blocked"));
+  });
+}
+document.getElementById("rows").addEventListener("click", function (ev) {
+  var d = ev.target.dataset;
+  if (!ev.target.classList.contains("op")) {
+    return;
+  }
+  document.getElementById("detail").textContent =
+    "index: " + d.index + "\nproc: " + d.proc + "  node: " + d.node +
+    "\ninvoke: " + d.start + "  return: " + d.end + "  outcome: " + d.outcome +
+    (d.order !== "0" ? "\nlinearization order: " + d.order : "") +
+    "\nrequest: " + d.request + "\nresponse: " + d.response;
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,200 @@
+package timeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/check/porcupine"
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+// Outcomes of an operation.
+const (
+	Ok      = "ok"
+	Fail    = "fail"
+	Unknown = "unknown"
+)
+
+// Op is an operation from its invoke to its return.
+type Op struct {
+	// Index is the index of the invoke operation in the history.
+	Index  int
+	Proc   int64
+	Node   string
+	Client int
+	Start  time.Duration
+	// End is the end of the history if the operation never returns.
+	End      time.Duration
+	Outcome  string
+	Request  string
+	Response string
+	// Order is the position of the operation in the longest linearizable
+	// prefix, or 0 if it is not in the prefix.
+	Order int
+	// Blocked is true if the operation could not be placed after the
+	// longest linearizable prefix.
+	Blocked bool
+}
+
+// Timeline is the operations of every process and the nemesis windows of
+// a history.
+type Timeline struct {
+	Title   string
+	Ops     []Op
+	Nemeses []history.NemesisWindow
+	// End is when the last operation or nemesis ends.
+	End time.Duration
+
+	// Explained is true if a linearizability explanation is added.
+	Explained bool
+	// Linearizable is true if the whole history is linearizable.
+	Linearizable bool
+	// Exhausted is true if the explanation search stops early.
+	Exhausted bool
+}
+
+// New creates the timeline of the operations and the nemesis records. The
+// codec tells a failed response from an ok one, without it every known
+// response is ok.
+func New(title string, ops []core.Operation, records []core.NemesisRecord, codec history.EDNCodec) *Timeline {
+	t := &Timeline{Title: title}
+
+	var end time.Duration
+	for _, op := range ops {
+		if op.Time > end {
+			end = op.Time
+		}
+	}
+	for _, r := range records {
+		if r.End > end {
+			end = r.End
+		}
+	}
+	t.End = end
+
+	pending := make(map[int64]int)
+	for i, op := range ops {
+		if op.Action == core.InvokeOperation {
+			pending[op.Proc] = len(t.Ops)
+			t.Ops = append(t.Ops, Op{
+				Index:   i,
+				Proc:    op.Proc,
+				Node:    op.Node,
+				Client:  op.Client,
+				Start:   op.Time,
+				End:     end,
+				Outcome: Unknown,
+				Request: fmt.Sprintf("%+v", op.Data),
+			})
+			continue
+		}
+
+		j, ok := pending[op.Proc]
+		if !ok {
+			continue
+		}
+		delete(pending, op.Proc)
+		o := &t.Ops[j]
+		o.End = op.Time
+		switch history.OutcomeOf(ops[o.Index].Data, op.Data, codec) {
+		case history.EDNOk:
+			o.Outcome = Ok
+		case history.EDNFail:
+			o.Outcome = Fail
+		}
+		if op.Data != nil {
+			o.Response = fmt.Sprintf("%+v", op.Data)
+		}
+	}
+
+	t.Nemeses = history.NemesisWindows(records, end)
+	return t
+}
+
+// Explain marks the longest linearizable prefix and the operation which
+// could not be placed after it.
+func (t *Timeline) Explain(e porcupine.Explanation) {
+	t.Explained = true
+	t.Linearizable = e.Complete
+	t.Exhausted = e.Exhausted
+
+	order := make(map[int]int, len(e.Linearized))
+	for i, index := range e.Linearized {
+		order[index] = i + 1
+	}
+	for i := range t.Ops {
+		op := &t.Ops[i]
+		op.Order = order[op.Index]
+		op.Blocked = op.Index == e.Blocked
+	}
+}
+
+// Procs returns the processes in increasing order.
+func (t *Timeline) Procs() []int64 {
+	seen := make(map[int64]bool)
+	var procs []int64
+	for _, op := range t.Ops {
+		if !seen[op.Proc] {
+			seen[op.Proc] = true
+			procs = append(procs, op.Proc)
+		}
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i] < procs[j] })
+	return procs
+}
+
+// WriteFile writes the timeline to the file in HTML.
+func (t *Timeline) WriteFile(name string) error {
+	os.MkdirAll(path.Dir(name), 0755)
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err = t.WriteHTML(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteHTML writes the timeline as a self-contained HTML page.
+func (t *Timeline) WriteHTML(w io.Writer) error {
+	return pageTemplate.Execute(w, newPage(t))
+}
+
+// Build creates the timeline of the completed operations of the history
+// file, with the nemesis windows and the title from the history. If the model
+// is not nil, the linearizability of the operations is explained with it, so
+// the model must be prepared with the state of the history, and the
+// explanation stops early when the context is done.
+func Build(ctx context.Context, historyFile string, ops []core.Operation, codec history.EDNCodec, m core.Model) (*Timeline, error) {
+	h, err := history.ReadHeader(historyFile)
+	if err != nil {
+		return nil, err
+	}
+	records, err := history.ReadNemesisHistory(historyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	title := historyFile
+	if len(h.Workload) != 0 {
+		title = fmt.Sprintf("%s %s round %d: %s", h.DB, h.Workload, h.Round, historyFile)
+	}
+	t := New(title, ops, records, codec)
+	if m != nil {
+		e, err := porcupine.Explain(ctx, m, ops, 0)
+		if err != nil {
+			return nil, err
+		}
+		t.Explain(e)
+	}
+	return t, nil
+}
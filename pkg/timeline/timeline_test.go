@@ -0,0 +1,63 @@
+package timeline
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/check/porcupine"
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+func TestTimeline(t *testing.T) {
+	ops := []core.Operation{
+		{Action: core.InvokeOperation, Proc: 1, Data: history.NoopRequest{Op: 1, Value: 1}, Time: 0, Node: "n1"},
+		{Action: core.InvokeOperation, Proc: 2, Data: history.NoopRequest{Op: 0}, Time: time.Second, Node: "n2"},
+		{Action: core.ReturnOperation, Proc: 1, Data: history.NoopResponse{Ok: true}, Time: 2 * time.Second, Node: "n1"},
+		{Action: core.ReturnOperation, Proc: 2, Data: nil, Time: 3 * time.Second, Node: "n2"},
+		{Action: core.InvokeOperation, Proc: 3, Data: history.NoopRequest{Op: 0}, Time: 4 * time.Second, Node: "n1"},
+	}
+	records := []core.NemesisRecord{
+		{Kind: core.NemesisInvoke, Name: "kill", Node: "n1", Start: time.Second, End: time.Second},
+		{Kind: core.NemesisRecover, Name: "kill", Node: "n1", Start: 2 * time.Second, End: 3 * time.Second},
+		{Kind: core.NemesisInvoke, Name: "drop", Node: "n2", Start: 4 * time.Second, End: 4 * time.Second},
+	}
+
+	tl := New("test", ops, records, nil)
+	if len(tl.Ops) != 3 || tl.End != 4*time.Second {
+		t.Fatalf("unexpected timeline %+v", tl)
+	}
+	for i, outcome := range []string{Ok, Unknown, Unknown} {
+		if tl.Ops[i].Outcome != outcome {
+			t.Fatalf("expect op %d is %s, but got %+v", i, outcome, tl.Ops[i])
+		}
+	}
+	if tl.Ops[2].End != tl.End {
+		t.Fatalf("a pending operation must end at the end, but got %+v", tl.Ops[2])
+	}
+	expected := []history.NemesisWindow{
+		{Name: "kill", Node: "n1", Start: time.Second, End: 3 * time.Second},
+		{Name: "drop", Node: "n2", Start: 4 * time.Second, End: 4 * time.Second},
+	}
+	if len(tl.Nemeses) != 2 || tl.Nemeses[0] != expected[0] || tl.Nemeses[1] != expected[1] {
+		t.Fatalf("expect nemesis windows %v, but got %v", expected, tl.Nemeses)
+	}
+
+	tl.Explain(porcupine.Explanation{Linearized: []int{0}, Blocked: 4})
+	if tl.Ops[0].Order != 1 || tl.Ops[1].Order != 0 || !tl.Ops[2].Blocked {
+		t.Fatalf("unexpected explained operations %+v", tl.Ops)
+	}
+
+	var buf bytes.Buffer
+	if err := tl.WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	html := buf.String()
+	for _, s := range []string{`class="op ok linearized"`, `class="op unknown blocked"`, `class="band"`, "proc 3  n1/0", "could not be placed after it"} {
+		if !strings.Contains(html, s) {
+			t.Fatalf("expect %q in the timeline", s)
+		}
+	}
+}
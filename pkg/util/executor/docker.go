@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// defaultContainerPrefix matches the container names in docker/docker-compose.yml.
+const defaultContainerPrefix = "chaos-"
+
+// Docker runs commands in the container of the node through `docker exec`.
+// The container name is ContainerPrefix followed by the node name.
+type Docker struct {
+	ContainerPrefix string
+}
+
+func (d Docker) container(node string) string {
+	return d.ContainerPrefix + node
+}
+
+// Exec implements Executor interface.
+func (d Docker) Exec(ctx context.Context, node string, cmd string, args ...string) error {
+	_, err := d.CombinedOutput(ctx, node, cmd, args...)
+	return err
+}
+
+// CombinedOutput implements Executor interface.
+func (d Docker) CombinedOutput(ctx context.Context, node string, cmd string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, "docker", "exec", d.container(node), "sh", "-c", shellCommand(cmd, args...)).CombinedOutput()
+}
+
+// Upload implements Executor interface.
+func (d Docker) Upload(ctx context.Context, localPath string, node string, remotePath string) error {
+	return exec.CommandContext(ctx, "docker", "cp", localPath, fmt.Sprintf("%s:%s", d.container(node), remotePath)).Run()
+}
+
+// Download implements Executor interface.
+func (d Docker) Download(ctx context.Context, localPath string, node string, remotePath string) error {
+	return exec.CommandContext(ctx, "docker", "cp", fmt.Sprintf("%s:%s", d.container(node), remotePath), localPath).Run()
+}
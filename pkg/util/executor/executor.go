@@ -0,0 +1,91 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/chaos/pkg/util/ssh"
+)
+
+// Executor runs commands and transfers files on a node.
+type Executor interface {
+	// Exec executes the cmd on the node.
+	Exec(ctx context.Context, node string, cmd string, args ...string) error
+	// CombinedOutput executes the cmd on the node and returns its combined standard
+	// output and standard error.
+	CombinedOutput(ctx context.Context, node string, cmd string, args ...string) ([]byte, error)
+	// Upload uploads files from local path to the node path.
+	Upload(ctx context.Context, localPath string, node string, remotePath string) error
+	// Download downloads files from the node path to local path.
+	Download(ctx context.Context, localPath string, node string, remotePath string) error
+}
+
+// New creates an executor by name.
+// Name is ssh, native-ssh, native-ssh:<inventory file>, local, docker
+// or docker:<container prefix>.
+func New(name string) (Executor, error) {
+	switch {
+	case name == "" || name == "ssh":
+		return SSH{}, nil
+	case name == "native-ssh":
+		return ssh.NewClient(new(ssh.Inventory)), nil
+	case strings.HasPrefix(name, "native-ssh:"):
+		inv, err := ssh.LoadInventory(name[len("native-ssh:"):])
+		if err != nil {
+			return nil, err
+		}
+		return ssh.NewClient(inv), nil
+	case name == "local":
+		return Local{}, nil
+	case name == "docker":
+		return Docker{ContainerPrefix: defaultContainerPrefix}, nil
+	case strings.HasPrefix(name, "docker:"):
+		return Docker{ContainerPrefix: name[len("docker:"):]}, nil
+	default:
+		return nil, fmt.Errorf("invalid executor %s", name)
+	}
+}
+
+type executorKey struct{}
+
+// WithExecutor returns a copy of ctx in which all node operations use e.
+func WithExecutor(ctx context.Context, e Executor) context.Context {
+	return context.WithValue(ctx, executorKey{}, e)
+}
+
+// FromContext returns the executor bound to ctx, or SSH if there is none.
+func FromContext(ctx context.Context) Executor {
+	if e, ok := ctx.Value(executorKey{}).(Executor); ok {
+		return e
+	}
+	return SSH{}
+}
+
+// Exec executes the cmd on the node with the executor bound to ctx.
+func Exec(ctx context.Context, node string, cmd string, args ...string) error {
+	return FromContext(ctx).Exec(ctx, node, cmd, args...)
+}
+
+// CombinedOutput executes the cmd on the node with the executor bound to ctx
+// and returns its combined standard output and standard error.
+func CombinedOutput(ctx context.Context, node string, cmd string, args ...string) ([]byte, error) {
+	return FromContext(ctx).CombinedOutput(ctx, node, cmd, args...)
+}
+
+// Upload uploads files from local path to the node path with the executor bound to ctx.
+func Upload(ctx context.Context, localPath string, node string, remotePath string) error {
+	return FromContext(ctx).Upload(ctx, localPath, node, remotePath)
+}
+
+// Download downloads files from the node path to local path with the executor bound to ctx.
+func Download(ctx context.Context, localPath string, node string, remotePath string) error {
+	return FromContext(ctx).Download(ctx, localPath, node, remotePath)
+}
+
+// shellCommand joins the cmd and args into one shell command line,
+// the same way `ssh node cmd args...` does on the remote side.
+func shellCommand(cmd string, args ...string) string {
+	v := append([]string{cmd}, args...)
+	return strings.Join(v, " ")
+}
@@ -0,0 +1,99 @@
+package executor
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/chaos/pkg/util/ssh"
+)
+
+func TestNew(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		e    Executor
+	}{
+		{"", SSH{}},
+		{"ssh", SSH{}},
+		{"local", Local{}},
+		{"docker", Docker{ContainerPrefix: "chaos-"}},
+		{"docker:test-", Docker{ContainerPrefix: "test-"}},
+	} {
+		e, err := New(c.name)
+		if err != nil {
+			t.Fatalf("create executor %s failed %v", c.name, err)
+		}
+		if e != c.e {
+			t.Fatalf("expect executor %#v, got %#v", c.e, e)
+		}
+	}
+
+	e, err := New("native-ssh")
+	if err != nil {
+		t.Fatalf("create native ssh executor failed %v", err)
+	}
+	if _, ok := e.(*ssh.Client); !ok {
+		t.Fatalf("expect native ssh client, got %#v", e)
+	}
+
+	if _, err := New("native-ssh:non_exist_file"); err == nil {
+		t.Fatal("native ssh executor without inventory must fail")
+	}
+
+	if _, err := New("telnet"); err == nil {
+		t.Fatal("telnet executor must fail")
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := FromContext(ctx).(SSH); !ok {
+		t.Fatal("default executor must be ssh")
+	}
+
+	ctx = WithExecutor(ctx, Local{})
+	if _, ok := FromContext(ctx).(Local); !ok {
+		t.Fatal("executor must be local")
+	}
+}
+
+func TestLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "executor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := WithExecutor(context.Background(), Local{})
+	name := path.Join(dir, "a.log")
+	if err = Exec(ctx, "n1", "echo", "hello", ">", name); err != nil {
+		t.Fatalf("exec failed %v", err)
+	}
+
+	data, err := CombinedOutput(ctx, "n1", "cat", name)
+	if err != nil {
+		t.Fatalf("exec failed %v", err)
+	}
+	if strings.TrimSpace(string(data)) != "hello" {
+		t.Fatalf("invalid output %q", data)
+	}
+
+	if err = Exec(ctx, "n1", "cat", path.Join(dir, "non_exist_file")); err == nil {
+		t.Fatal("exec must fail")
+	}
+
+	if err = Upload(ctx, name, "n1", path.Join(dir, "b.log")); err != nil {
+		t.Fatalf("upload file failed %v", err)
+	}
+
+	if err = Download(ctx, path.Join(dir, "c.log"), "n1", path.Join(dir, "b.log")); err != nil {
+		t.Fatalf("download file failed %v", err)
+	}
+
+	if _, err = os.Stat(path.Join(dir, "c.log")); err != nil {
+		t.Fatalf("stat file failed %v", err)
+	}
+}
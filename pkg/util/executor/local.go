@@ -0,0 +1,31 @@
+package executor
+
+import (
+	"context"
+	"os/exec"
+)
+
+// Local runs commands on the control host, ignoring the node.
+// It is useful when all the nodes are processes on the same machine.
+type Local struct{}
+
+// Exec implements Executor interface.
+func (l Local) Exec(ctx context.Context, node string, cmd string, args ...string) error {
+	_, err := l.CombinedOutput(ctx, node, cmd, args...)
+	return err
+}
+
+// CombinedOutput implements Executor interface.
+func (Local) CombinedOutput(ctx context.Context, node string, cmd string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, "sh", "-c", shellCommand(cmd, args...)).CombinedOutput()
+}
+
+// Upload implements Executor interface.
+func (Local) Upload(ctx context.Context, localPath string, node string, remotePath string) error {
+	return exec.CommandContext(ctx, "cp", "-r", localPath, remotePath).Run()
+}
+
+// Download implements Executor interface.
+func (Local) Download(ctx context.Context, localPath string, node string, remotePath string) error {
+	return exec.CommandContext(ctx, "cp", "-r", remotePath, localPath).Run()
+}
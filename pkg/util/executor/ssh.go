@@ -0,0 +1,31 @@
+package executor
+
+import (
+	"context"
+
+	"github.com/pingcap/chaos/pkg/util/ssh"
+)
+
+// SSH runs commands on the node through the `ssh` and `scp` commands.
+// Here we assume we can run with `ssh node cmd` directly.
+type SSH struct{}
+
+// Exec implements Executor interface.
+func (SSH) Exec(ctx context.Context, node string, cmd string, args ...string) error {
+	return ssh.Exec(ctx, node, cmd, args...)
+}
+
+// CombinedOutput implements Executor interface.
+func (SSH) CombinedOutput(ctx context.Context, node string, cmd string, args ...string) ([]byte, error) {
+	return ssh.CombinedOutput(ctx, node, cmd, args...)
+}
+
+// Upload implements Executor interface.
+func (SSH) Upload(ctx context.Context, localPath string, node string, remotePath string) error {
+	return ssh.Upload(ctx, localPath, node, remotePath)
+}
+
+// Download implements Executor interface.
+func (SSH) Download(ctx context.Context, localPath string, node string, remotePath string) error {
+	return ssh.Download(ctx, localPath, node, remotePath)
+}
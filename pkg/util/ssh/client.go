@@ -0,0 +1,324 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const handshakeTimeout = 30 * time.Second
+
+// ExitError is returned when the command runs on the node but does not exit successfully.
+type ExitError struct {
+	Node string
+	Cmd  string
+	// ExitStatus is the exit code of the command, -1 if the command
+	// exits without a status, e.g, it is killed by a signal.
+	ExitStatus int
+	// Stderr is the standard error of the command.
+	Stderr []byte
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("run %q on node %s failed with exit status %d: %s",
+		e.Cmd, e.Node, e.ExitStatus, bytes.TrimSpace(e.Stderr))
+}
+
+// Client is a native SSH client. It keeps one multiplexed connection per node
+// and runs every command in a new session of the connection.
+// Client implements the executor.Executor interface.
+type Client struct {
+	inv *Inventory
+
+	mu    sync.Mutex
+	conns map[string]*gossh.Client
+}
+
+// NewClient creates a client with the inventory.
+func NewClient(inv *Inventory) *Client {
+	return &Client{
+		inv:   inv,
+		conns: make(map[string]*gossh.Client),
+	}
+}
+
+// Close closes all the connections.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for node, conn := range c.conns {
+		if e := conn.Close(); e != nil {
+			err = e
+		}
+		delete(c.conns, node)
+	}
+	return err
+}
+
+func (c *Client) config(node string) (*gossh.ClientConfig, string, error) {
+	n := c.inv.Node(node)
+
+	var auths []gossh.AuthMethod
+	if n.KeyFile != "" {
+		key, err := ioutil.ReadFile(n.KeyFile)
+		if err != nil && n.Password == "" {
+			return nil, "", err
+		}
+		if err == nil {
+			signer, err := gossh.ParsePrivateKey(key)
+			if err != nil {
+				return nil, "", fmt.Errorf("parse key %s failed %v", n.KeyFile, err)
+			}
+			auths = append(auths, gossh.PublicKeys(signer))
+		}
+	}
+	if n.Password != "" {
+		auths = append(auths, gossh.Password(n.Password))
+	}
+
+	hostKeyCallback := gossh.InsecureIgnoreHostKey()
+	if !c.inv.IgnoreHostKey {
+		var err error
+		if hostKeyCallback, err = knownhosts.New(c.inv.knownHosts()); err != nil {
+			return nil, "", err
+		}
+	}
+
+	cfg := &gossh.ClientConfig{
+		User:            n.User,
+		Auth:            auths,
+		HostKeyCallback: hostKeyCallback,
+	}
+	return cfg, net.JoinHostPort(n.Host, strconv.Itoa(n.Port)), nil
+}
+
+func (c *Client) dial(ctx context.Context, node string) (*gossh.Client, error) {
+	cfg, addr, err := c.config(node)
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// The handshake is not context aware, so we bound it with a deadline.
+	deadline := time.Now().Add(handshakeTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	sc, chans, reqs, err := gossh.NewClientConn(conn, addr, cfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	return gossh.NewClient(sc, chans, reqs), nil
+}
+
+func (c *Client) conn(ctx context.Context, node string) (*gossh.Client, error) {
+	c.mu.Lock()
+	conn, ok := c.conns[node]
+	c.mu.Unlock()
+	if ok {
+		return conn, nil
+	}
+
+	// Dial without holding the lock, so connecting to a slow node does not block others.
+	conn, err := c.dial(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.conns[node]; ok {
+		// Another goroutine has connected to the node.
+		conn.Close()
+		return old, nil
+	}
+	c.conns[node] = conn
+	return conn, nil
+}
+
+func (c *Client) drop(node string, conn *gossh.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conns[node] == conn {
+		delete(c.conns, node)
+	}
+	conn.Close()
+}
+
+func (c *Client) newSession(ctx context.Context, node string) (*gossh.Session, error) {
+	conn, err := c.conn(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := conn.NewSession()
+	if err == nil {
+		return session, nil
+	}
+
+	// The connection may be broken, e.g, the node is restarted, so reconnect once.
+	c.drop(node, conn)
+	if conn, err = c.conn(ctx, node); err != nil {
+		return nil, err
+	}
+	return conn.NewSession()
+}
+
+// Run runs the cmd on the node, reads the standard input from stdin, and writes
+// the standard output and error to stdout and stderr. Any of stdin, stdout and
+// stderr can be nil.
+// If the command does not exit successfully, the error is an *ExitError.
+// If ctx is done, the remote command is killed and ctx.Err() is returned.
+func (c *Client) Run(ctx context.Context, node string, stdin io.Reader, stdout io.Writer, stderr io.Writer, cmd string, args ...string) error {
+	line := strings.Join(append([]string{cmd}, args...), " ")
+	if *verbose {
+		log.Printf("run %s on node %s", line, node)
+	}
+
+	session, err := c.newSession(ctx, node)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	var errBuf bytes.Buffer
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = &errBuf
+	if stderr != nil {
+		session.Stderr = io.MultiWriter(&errBuf, stderr)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// Kill the remote command and tear down the session.
+			session.Signal(gossh.SIGKILL)
+			session.Close()
+		case <-done:
+		}
+	}()
+
+	err = session.Run(line)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *gossh.ExitError:
+		err = &ExitError{Node: node, Cmd: line, ExitStatus: e.ExitStatus(), Stderr: errBuf.Bytes()}
+	case *gossh.ExitMissingError:
+		err = &ExitError{Node: node, Cmd: line, ExitStatus: -1, Stderr: errBuf.Bytes()}
+	}
+
+	if *verbose {
+		log.Printf("fail to run %s on node %s %v", line, node, err)
+	}
+	return err
+}
+
+// Output runs the cmd on the node and returns its standard output.
+func (c *Client) Output(ctx context.Context, node string, cmd string, args ...string) ([]byte, error) {
+	var out bytes.Buffer
+	err := c.Run(ctx, node, nil, &out, nil, cmd, args...)
+	return out.Bytes(), err
+}
+
+// Exec implements executor.Executor interface.
+func (c *Client) Exec(ctx context.Context, node string, cmd string, args ...string) error {
+	return c.Run(ctx, node, nil, nil, nil, cmd, args...)
+}
+
+// lockedBuffer is a buffer which can be written by stdout and stderr concurrently.
+type lockedBuffer struct {
+	sync.Mutex
+	bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.Buffer.Write(p)
+}
+
+// CombinedOutput implements executor.Executor interface.
+func (c *Client) CombinedOutput(ctx context.Context, node string, cmd string, args ...string) ([]byte, error) {
+	var out lockedBuffer
+	err := c.Run(ctx, node, nil, &out, &out, cmd, args...)
+	return out.Bytes(), err
+}
+
+// Upload implements executor.Executor interface. Like `scp -r`, if remotePath
+// is a directory, localPath is copied into it.
+func (c *Client) Upload(ctx context.Context, localPath string, node string, remotePath string) error {
+	destDir, name := path.Dir(remotePath), path.Base(remotePath)
+	if c.Exec(ctx, node, "test", "-d", remotePath) == nil {
+		destDir, name = remotePath, filepath.Base(localPath)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTar(pw, localPath, name))
+	}()
+
+	err := c.Run(ctx, node, pr, nil, nil, "mkdir", "-p", destDir, "&&", "tar", "-xf", "-", "-C", destDir)
+	// Unblock the writer if the command exits early.
+	pr.Close()
+	return err
+}
+
+// Download implements executor.Executor interface. Like `scp -r`, if localPath
+// is a directory, remotePath is copied into it.
+func (c *Client) Download(ctx context.Context, localPath string, node string, remotePath string) error {
+	srcName := path.Base(remotePath)
+	destDir, name := filepath.Dir(localPath), filepath.Base(localPath)
+	if fi, err := os.Stat(localPath); err == nil && fi.IsDir() {
+		destDir, name = localPath, srcName
+	}
+
+	pr, pw := io.Pipe()
+	errCh := make(chan error, 1)
+	go func() {
+		err := readTar(pr, destDir, srcName, name)
+		// Drain the output, e.g, the padding after the end of the archive,
+		// so the remote command can exit.
+		io.Copy(ioutil.Discard, pr)
+		errCh <- err
+	}()
+
+	err := c.Run(ctx, node, nil, pw, nil, "tar", "-cf", "-", "-C", path.Dir(remotePath), srcName)
+	pw.CloseWithError(err)
+	if rerr := <-errCh; err == nil {
+		err = rerr
+	}
+	return err
+}
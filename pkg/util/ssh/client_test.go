@@ -0,0 +1,348 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// testServer is an SSH server which runs the commands locally with `sh -c`.
+type testServer struct {
+	l     net.Listener
+	cfg   *gossh.ServerConfig
+	conns int32
+}
+
+func newTestServer(t *testing.T, clientKey gossh.PublicKey) *testServer {
+	hostKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := gossh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &gossh.ServerConfig{
+		PublicKeyCallback: func(_ gossh.ConnMetadata, key gossh.PublicKey) (*gossh.Permissions, error) {
+			if !bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, syscall.EPERM
+			}
+			return nil, nil
+		},
+	}
+	cfg.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{l: l, cfg: cfg}
+	go s.serve()
+	return s
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		atomic.AddInt32(&s.conns, 1)
+		go func() {
+			_, chans, reqs, err := gossh.NewServerConn(conn, s.cfg)
+			if err != nil {
+				return
+			}
+			go gossh.DiscardRequests(reqs)
+			for ch := range chans {
+				go s.handleSession(ch)
+			}
+		}()
+	}
+}
+
+func (s *testServer) handleSession(newCh gossh.NewChannel) {
+	ch, reqs, err := newCh.Accept()
+	if err != nil {
+		return
+	}
+	defer ch.Close()
+
+	var cmd *exec.Cmd
+	exited := make(chan struct{})
+	for req := range reqs {
+		switch req.Type {
+		case "exec":
+			line := string(req.Payload[4:])
+			cmd = exec.Command("sh", "-c", line)
+			cmd.Stdin, cmd.Stdout, cmd.Stderr = ch, ch, ch.Stderr()
+			if err := cmd.Start(); err != nil {
+				req.Reply(false, nil)
+				return
+			}
+			req.Reply(true, nil)
+			go func() {
+				defer close(exited)
+				status := make([]byte, 4)
+				if err := cmd.Wait(); err != nil {
+					if e, ok := err.(*exec.ExitError); ok {
+						ws := e.Sys().(syscall.WaitStatus)
+						if ws.Signaled() {
+							return
+						}
+						binary.BigEndian.PutUint32(status, uint32(ws.ExitStatus()))
+					}
+				}
+				ch.SendRequest("exit-status", false, status)
+				ch.Close()
+			}()
+		case "signal":
+			if cmd != nil && cmd.Process != nil {
+				cmd.Process.Kill()
+			}
+		default:
+			req.Reply(false, nil)
+		}
+	}
+	if cmd != nil {
+		cmd.Process.Kill()
+		<-exited
+	}
+}
+
+func (s *testServer) Close() {
+	s.l.Close()
+}
+
+func newTestClient(t *testing.T, dir string) (*Client, *testServer) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := path.Join(dir, "id_ecdsa")
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	if err = ioutil.WriteFile(keyFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	pub, err := gossh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer(t, pub)
+	port := s.l.Addr().(*net.TCPAddr).Port
+	inv := &Inventory{
+		Default:       Node{User: "chaos", KeyFile: keyFile},
+		Nodes:         map[string]Node{"n1": {Host: "127.0.0.1", Port: port}},
+		IgnoreHostKey: true,
+	}
+	return NewClient(inv), s
+}
+
+func TestClientExec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, s := newTestClient(t, dir)
+	defer s.Close()
+	defer c.Close()
+
+	ctx := context.Background()
+	out, err := c.CombinedOutput(ctx, "n1", "echo", "hello")
+	if err != nil {
+		t.Fatalf("exec failed %v", err)
+	}
+	if string(out) != "hello\n" {
+		t.Fatalf("invalid output %q", out)
+	}
+
+	err = c.Exec(ctx, "n1", "echo", "oops", ">&2", "&&", "exit", "3")
+	e, ok := err.(*ExitError)
+	if !ok {
+		t.Fatalf("expect exit error, got %v", err)
+	}
+	if e.ExitStatus != 3 || string(e.Stderr) != "oops\n" {
+		t.Fatalf("invalid exit error %#v", e)
+	}
+
+	out, err = c.Output(ctx, "n1", "echo", "out", "&&", "echo", "err", ">&2")
+	if err != nil || string(out) != "out\n" {
+		t.Fatalf("invalid output %q %v", out, err)
+	}
+
+	// All the commands share one connection.
+	if n := atomic.LoadInt32(&s.conns); n != 1 {
+		t.Fatalf("expect 1 connection, got %d", n)
+	}
+}
+
+func TestClientCancel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, s := newTestClient(t, dir)
+	defer s.Close()
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err = c.Exec(ctx, "n1", "sleep", "10"); err != context.DeadlineExceeded {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("the command must be killed")
+	}
+
+	// The connection can still be used.
+	if err = c.Exec(context.Background(), "n1", "true"); err != nil {
+		t.Fatalf("exec failed %v", err)
+	}
+}
+
+func TestClientUploadDownload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if dir, err = filepath.Abs(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c, s := newTestClient(t, dir)
+	defer s.Close()
+	defer c.Close()
+
+	ctx := context.Background()
+	src := path.Join(dir, "src")
+	os.MkdirAll(path.Join(src, "sub"), 0755)
+	ioutil.WriteFile(path.Join(src, "a.log"), []byte("hello"), 0644)
+	ioutil.WriteFile(path.Join(src, "sub", "b.log"), []byte("world"), 0644)
+
+	// Upload a file to a new name.
+	if err = c.Upload(ctx, path.Join(src, "a.log"), "n1", path.Join(dir, "c.log")); err != nil {
+		t.Fatalf("upload file failed %v", err)
+	}
+	testFileContent(t, path.Join(dir, "c.log"), "hello")
+
+	// Upload a folder into an existing folder.
+	os.MkdirAll(path.Join(dir, "remote"), 0755)
+	if err = c.Upload(ctx, src, "n1", path.Join(dir, "remote")); err != nil {
+		t.Fatalf("upload folder failed %v", err)
+	}
+	testFileContent(t, path.Join(dir, "remote", "src", "sub", "b.log"), "world")
+
+	// Download a folder to a new name.
+	if err = c.Download(ctx, path.Join(dir, "local"), "n1", path.Join(dir, "remote", "src")); err != nil {
+		t.Fatalf("download folder failed %v", err)
+	}
+	testFileContent(t, path.Join(dir, "local", "a.log"), "hello")
+	testFileContent(t, path.Join(dir, "local", "sub", "b.log"), "world")
+
+	// Download a file into an existing folder.
+	if err = c.Download(ctx, path.Join(dir, "local", "sub"), "n1", path.Join(dir, "c.log")); err != nil {
+		t.Fatalf("download file failed %v", err)
+	}
+	testFileContent(t, path.Join(dir, "local", "sub", "c.log"), "hello")
+
+	if err = c.Download(ctx, path.Join(dir, "x"), "n1", path.Join(dir, "non_exist_file")); err == nil {
+		t.Fatal("download must fail")
+	}
+}
+
+func testFileContent(t *testing.T, name string, expect string) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf("read %s failed %v", name, err)
+	}
+	if string(data) != expect {
+		t.Fatalf("expect %s to be %q, got %q", name, expect, data)
+	}
+}
+
+func TestInventory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := path.Join(dir, "inventory.toml")
+	data := `
+known_hosts = "/tmp/known_hosts"
+
+[default]
+user = "root"
+key = "/root/.ssh/id_rsa"
+
+[nodes.n1]
+host = "172.16.5.1"
+port = 2222
+
+[nodes.n2]
+user = "chaos"
+password = "secret"
+`
+	if err = ioutil.WriteFile(name, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inv, err := LoadInventory(name)
+	if err != nil {
+		t.Fatalf("load inventory failed %v", err)
+	}
+
+	if inv.knownHosts() != "/tmp/known_hosts" {
+		t.Fatalf("invalid known hosts %s", inv.knownHosts())
+	}
+
+	for _, c := range []struct {
+		name string
+		node Node
+	}{
+		{"n1", Node{Host: "172.16.5.1", Port: 2222, User: "root", KeyFile: "/root/.ssh/id_rsa"}},
+		{"n2", Node{Host: "n2", Port: 22, User: "chaos", KeyFile: "/root/.ssh/id_rsa", Password: "secret"}},
+		{"n3", Node{Host: "n3", Port: 22, User: "root", KeyFile: "/root/.ssh/id_rsa"}},
+	} {
+		if n := inv.Node(c.name); n != c.node {
+			t.Fatalf("expect node %#v, got %#v", c.node, n)
+		}
+	}
+
+	// The default password is used instead of the default key.
+	inv = &Inventory{Default: Node{User: "root", Password: "secret"}}
+	if n := inv.Node("n1"); n.KeyFile != "" || n.Password != "secret" {
+		t.Fatalf("expect the password only, got %#v", n)
+	}
+}
@@ -0,0 +1,115 @@
+package ssh
+
+import (
+	"os"
+	"os/user"
+	"path"
+
+	"github.com/BurntSushi/toml"
+)
+
+const defaultPort = 22
+
+// Node is the SSH configuration of a node.
+type Node struct {
+	// Host is the address of the node, default is the node name.
+	Host string `toml:"host"`
+	// Port is the SSH port, default is 22.
+	Port int `toml:"port"`
+	// User is the login user, default is the current user.
+	User string `toml:"user"`
+	// KeyFile is the private key file, default is ~/.ssh/id_rsa.
+	KeyFile string `toml:"key"`
+	// Password is used if no key file can be loaded.
+	Password string `toml:"password"`
+}
+
+// Inventory is the SSH configuration of all the nodes.
+//
+// An inventory file looks like:
+//
+//	known_hosts = "/root/.ssh/known_hosts"
+//
+//	[default]
+//	user = "root"
+//	key = "/root/.ssh/id_rsa"
+//
+//	[nodes.n1]
+//	host = "172.16.5.1"
+//	port = 2222
+type Inventory struct {
+	// Default is used for the nodes, or the fields, not in Nodes.
+	Default Node `toml:"default"`
+	// Nodes are the per-node configurations.
+	Nodes map[string]Node `toml:"nodes"`
+	// KnownHosts is the known_hosts file to verify the host keys,
+	// default is ~/.ssh/known_hosts.
+	KnownHosts string `toml:"known_hosts"`
+	// IgnoreHostKey skips verifying the host keys.
+	IgnoreHostKey bool `toml:"ignore_host_key"`
+}
+
+// LoadInventory loads the inventory from a toml file.
+func LoadInventory(name string) (*Inventory, error) {
+	inv := new(Inventory)
+	if _, err := toml.DecodeFile(name, inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// Node returns the configuration of the node, the missing fields are filled
+// with the default ones.
+func (inv *Inventory) Node(name string) Node {
+	n := inv.Nodes[name]
+	if n.Host == "" {
+		n.Host = inv.Default.Host
+	}
+	if n.Host == "" {
+		n.Host = name
+	}
+	if n.Port == 0 {
+		n.Port = inv.Default.Port
+	}
+	if n.Port == 0 {
+		n.Port = defaultPort
+	}
+	if n.User == "" {
+		n.User = inv.Default.User
+	}
+	if n.User == "" {
+		n.User = currentUser()
+	}
+	if n.KeyFile == "" {
+		n.KeyFile = inv.Default.KeyFile
+	}
+	if n.Password == "" {
+		n.Password = inv.Default.Password
+	}
+	// Only try the default key if neither a key nor a password is given.
+	if n.KeyFile == "" && n.Password == "" {
+		n.KeyFile = path.Join(homeDir(), ".ssh", "id_rsa")
+	}
+	return n
+}
+
+func (inv *Inventory) knownHosts() string {
+	if inv.KnownHosts != "" {
+		return inv.KnownHosts
+	}
+	return path.Join(homeDir(), ".ssh", "known_hosts")
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func homeDir() string {
+	if u, err := user.Current(); err == nil {
+		return u.HomeDir
+	}
+	return os.Getenv("HOME")
+}
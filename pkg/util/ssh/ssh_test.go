@@ -24,11 +24,11 @@ func TestExec(t *testing.T) {
 }
 
 func TestScp(t *testing.T) {
-	dir, err := ioutil.TempDir(".", "var")
+	dir, err := ioutil.TempDir("", "ssh")
 	if err != nil {
 		t.Fatal(err)
 	}
-	//defer os.RemoveAll(dir)
+	defer os.RemoveAll(dir)
 
 	f, err := os.Create(path.Join(dir, "a.log"))
 	if err != nil {
@@ -9,18 +9,18 @@ import (
 	"strings"
 	"time"
 
-	"github.com/pingcap/chaos/pkg/util/ssh"
+	"github.com/pingcap/chaos/pkg/util/executor"
 )
 
 // IsFileExist runs on node and returns true if the file exists.
 func IsFileExist(ctx context.Context, node string, name string) bool {
-	err := ssh.Exec(ctx, node, "stat", name)
+	err := executor.Exec(ctx, node, "stat", name)
 	return err == nil
 }
 
 // IsProcessExist runs on node and returns true if the porcess still exists.
 func IsProcessExist(ctx context.Context, node string, pid int) bool {
-	err := ssh.Exec(ctx, node, "kill", fmt.Sprintf("-s 0 %d", pid))
+	err := executor.Exec(ctx, node, "kill", fmt.Sprintf("-s 0 %d", pid))
 	return err == nil
 }
 
@@ -40,7 +40,7 @@ func Wget(ctx context.Context, node string, rawURL string, dest string) (string,
 	filePath := path.Join(dest, fileName)
 
 	Mkdir(ctx, node, dest)
-	err = ssh.Exec(ctx, node, "wget", "--tries", "20", "--waitretry", "60",
+	err = executor.Exec(ctx, node, "wget", "--tries", "20", "--waitretry", "60",
 		"--retry-connrefused", "--dns-timeout", "60", "--connect-timeout", "60",
 		"--read-timeout", "60", "--no-clobber", "--no-verbose", "--directory-prefix", dest, rawURL)
 	return filePath, err
@@ -49,7 +49,7 @@ func Wget(ctx context.Context, node string, rawURL string, dest string) (string,
 // InstallArchive runs on node, downloads the URL and extracts the archive to the dest diretory.
 // Supports zip, and tarball.
 func InstallArchive(ctx context.Context, node string, rawURL string, dest string) error {
-	err := ssh.Exec(ctx, node, "mkdir", "-p", "/tmp/chaos")
+	err := executor.Exec(ctx, node, "mkdir", "-p", "/tmp/chaos")
 	if err != nil {
 		return err
 	}
@@ -68,11 +68,11 @@ func InstallArchive(ctx context.Context, node string, rawURL string, dest string
 	}
 
 	if strings.HasSuffix(name, ".zip") {
-		err = ssh.Exec(ctx, node, "unzip", "-d", tmpDir, name)
+		err = executor.Exec(ctx, node, "unzip", "-d", tmpDir, name)
 	} else if strings.HasSuffix(name, ".tar.gz") {
-		err = ssh.Exec(ctx, node, "tar", "-xzf", name, "-C", tmpDir)
+		err = executor.Exec(ctx, node, "tar", "-xzf", name, "-C", tmpDir)
 	} else {
-		err = ssh.Exec(ctx, node, "tar", "-xf", name, "-C", tmpDir)
+		err = executor.Exec(ctx, node, "tar", "-xf", name, "-C", tmpDir)
 	}
 
 	if err != nil {
@@ -90,15 +90,15 @@ func InstallArchive(ctx context.Context, node string, rawURL string, dest string
 	if files, err = ReadDir(ctx, node, tmpDir); err != nil {
 		return err
 	} else if len(files) == 1 && IsDir(ctx, node, path.Join(tmpDir, files[0])) {
-		return ssh.Exec(ctx, node, "mv", path.Join(tmpDir, files[0]), dest)
+		return executor.Exec(ctx, node, "mv", path.Join(tmpDir, files[0]), dest)
 	}
 
-	return ssh.Exec(ctx, node, "mv", tmpDir, dest)
+	return executor.Exec(ctx, node, "mv", tmpDir, dest)
 }
 
 // ReadDir runs on node and lists the files of dir.
 func ReadDir(ctx context.Context, node string, dir string) ([]string, error) {
-	output, err := ssh.CombinedOutput(ctx, node, "ls", dir)
+	output, err := executor.CombinedOutput(ctx, node, "ls", dir)
 	if err != nil {
 		return nil, err
 	}
@@ -117,23 +117,23 @@ func ReadDir(ctx context.Context, node string, dir string) ([]string, error) {
 
 // IsDir runs on node and checks path is directory or not.
 func IsDir(ctx context.Context, node string, path string) bool {
-	err := ssh.Exec(ctx, node, "test", "-d", path)
+	err := executor.Exec(ctx, node, "test", "-d", path)
 	return err == nil
 }
 
 // Mkdir runs on node and makes a directory
 func Mkdir(ctx context.Context, node string, dir string) error {
-	return ssh.Exec(ctx, node, "mkdir", "-p", dir)
+	return executor.Exec(ctx, node, "mkdir", "-p", dir)
 }
 
 // RemoveDir runs on node and removes the diretory
 func RemoveDir(ctx context.Context, node string, dir string) error {
-	return ssh.Exec(ctx, node, "rm", "-rf", dir)
+	return executor.Exec(ctx, node, "rm", "-rf", dir)
 }
 
 // WriteFile runs on node and writes data to file
 func WriteFile(ctx context.Context, node string, file string, data string) error {
-	return ssh.Exec(ctx, node, "echo", "-e", data, ">", file)
+	return executor.Exec(ctx, node, "echo", "-e", data, ">", file)
 }
 
 // DaemonOptions is the options to start a command in daemon mode.
@@ -171,11 +171,11 @@ func StartDaemon(ctx context.Context, node string, opts DaemonOptions, cmd strin
 	args = append(args, "--")
 	args = append(args, cmdArgs...)
 
-	return ssh.Exec(ctx, node, "start-stop-daemon", args...)
+	return executor.Exec(ctx, node, "start-stop-daemon", args...)
 }
 
 func parsePID(ctx context.Context, node string, pidFile string) string {
-	data, err := ssh.CombinedOutput(ctx, node, "cat", pidFile)
+	data, err := executor.CombinedOutput(ctx, node, "cat", pidFile)
 	if err != nil {
 		return ""
 	}
@@ -196,7 +196,7 @@ func KillDaemon(ctx context.Context, node string, cmd string, pidFile string) er
 func stopDaemon(ctx context.Context, node string, cmd string, pidFile string, sig string) error {
 	name := path.Base(cmd)
 
-	return ssh.Exec(ctx, node, "start-stop-daemon", "--stop", "--remove-pidfile",
+	return executor.Exec(ctx, node, "start-stop-daemon", "--stop", "--remove-pidfile",
 		"--pidfile", pidFile, "--oknodo", "--name", name, "--signal", sig)
 }
 
@@ -204,7 +204,7 @@ func stopDaemon(ctx context.Context, node string, cmd string, pidFile string, si
 func IsDaemonRunning(ctx context.Context, node string, cmd string, pidFile string) bool {
 	name := path.Base(cmd)
 
-	err := ssh.Exec(ctx, node, "start-stop-daemon", "--status", "--pidfile", pidFile, "--name", name)
+	err := executor.Exec(ctx, node, "start-stop-daemon", "--status", "--pidfile", pidFile, "--name", name)
 
 	return err == nil
 }
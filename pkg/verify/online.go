@@ -0,0 +1,174 @@
+package verify
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+// OnlineWindow is how many recent operations are kept, they are written to
+// the window file when an online checker finds a violation. The window is
+// an excerpt for diagnosis, the whole history is kept in the history file.
+var OnlineWindow = 1000
+
+// Online runs the online checkers on the operations seen by a Recorder. The
+// first violation aborts the run, and the operations before it are kept.
+type Online struct {
+	historyFile string
+	header      history.Header
+	checkers    []core.OnlineChecker
+	abort       func()
+
+	state   interface{}
+	pending map[int64]interface{}
+	// recent are the recent records of operations, at most 2 * OnlineWindow.
+	recent []core.Operation
+	// trimmed tells whether the earlier operations are dropped from recent.
+	trimmed bool
+
+	failed core.OnlineChecker
+	err    error
+	failAt time.Duration
+	window []core.Operation
+}
+
+// NewOnlineCheckers creates the registered online checkers.
+func NewOnlineCheckers(names []string) ([]core.OnlineChecker, error) {
+	checkers := make([]core.OnlineChecker, 0, len(names))
+	for _, name := range names {
+		c := core.NewOnlineChecker(name)
+		if c == nil {
+			return nil, fmt.Errorf("online checker %s is not registered", name)
+		}
+		checkers = append(checkers, c)
+	}
+	return checkers, nil
+}
+
+// NewOnline creates the online checking of the history, abort is called once
+// when a checker finds a violation. The header is written to the window file.
+func NewOnline(historyFile string, header history.Header, checkers []core.OnlineChecker, abort func()) *Online {
+	return &Online{
+		historyFile: historyFile,
+		header:      header,
+		checkers:    checkers,
+		abort:       abort,
+		pending:     make(map[int64]interface{}),
+	}
+}
+
+// OnState implements history.Observer.
+func (o *Online) OnState(state interface{}) {
+	o.state = state
+	for _, c := range o.checkers {
+		c.Prepare(state)
+	}
+}
+
+// OnOperation implements history.Observer.
+func (o *Online) OnOperation(op core.Operation) {
+	if o.failed != nil {
+		return
+	}
+
+	o.recent = append(o.recent, op)
+	if len(o.recent) > 2*OnlineWindow {
+		o.recent = append(o.recent[:0:0], o.recent[len(o.recent)-OnlineWindow:]...)
+		o.trimmed = true
+	}
+
+	if op.Action == core.InvokeOperation {
+		o.pending[op.Proc] = op.Data
+		return
+	}
+	req, ok := o.pending[op.Proc]
+	if !ok {
+		return
+	}
+	delete(o.pending, op.Proc)
+
+	resp := op.Data
+	if u, ok := resp.(core.UnknownResponse); ok && u.IsUnknown() {
+		resp = nil
+	}
+	for _, c := range o.checkers {
+		if err := c.Step(req, resp); err != nil {
+			o.failed = c
+			o.err = err
+			o.failAt = op.Time
+			o.window = o.recent
+			o.recent = nil
+			log.Printf("online checker %s finds a violation at %s of history %s: %v", c.Name(), op.Time, o.historyFile, err)
+			o.abort()
+			return
+		}
+	}
+}
+
+// Failed tells whether a checker finds a violation.
+func (o *Online) Failed() bool {
+	return o.failed != nil
+}
+
+// Finish returns the results of the online checkers, it must be called after
+// the recorder is closed. The window before the violation is written next to
+// the history, see WindowName. It is only an excerpt for diagnosis: the
+// dumped state is written only if no operation is dropped from the window,
+// otherwise the state at the start of the window is unknown, and the window
+// can't be verified again, verify the history file instead.
+func (o *Online) Finish() []Result {
+	var results []Result
+	for _, c := range o.checkers {
+		r := Result{
+			Outcome:     Valid,
+			Checker:     c.Name(),
+			HistoryFile: o.historyFile,
+			Online:      true,
+		}
+		if c == o.failed {
+			r.Outcome = Invalid
+			r.Err = fmt.Sprintf("violation at %s: %v", o.failAt, o.err)
+			name := WindowName(o.historyFile)
+			var state interface{}
+			if !o.trimmed {
+				state = o.state
+			}
+			if err := history.WriteHistory(name, o.header, state, windowOperations(o.window)); err != nil {
+				log.Printf("write the window of history %s failed %v", o.historyFile, err)
+			} else {
+				r.Window = name
+				log.Printf("window of the violation of history %s is written to %s", o.historyFile, name)
+			}
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// windowOperations drops the returns whose invokes are not in the window.
+func windowOperations(ops []core.Operation) []core.Operation {
+	invoked := make(map[int64]bool)
+	window := make([]core.Operation, 0, len(ops))
+	for _, op := range ops {
+		if op.Action == core.InvokeOperation {
+			invoked[op.Proc] = true
+		} else if !invoked[op.Proc] {
+			continue
+		} else {
+			delete(invoked, op.Proc)
+		}
+		window = append(window, op)
+	}
+	return window
+}
+
+// WindowName returns the name of the window file of the history, e.g,
+// history.log.1.gz is windowed to history.log.1.window.gz.
+func WindowName(historyFile string) string {
+	ext := history.CompressionOf(historyFile).Ext()
+	return strings.TrimSuffix(historyFile, ext) + ".window" + ext
+}
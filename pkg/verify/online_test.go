@@ -0,0 +1,104 @@
+package verify
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+// maxValueChecker fails once a response is larger than the dumped state.
+type maxValueChecker struct {
+	max int
+}
+
+func (c *maxValueChecker) Prepare(state interface{}) {
+	c.max = state.(int)
+}
+
+func (c *maxValueChecker) Step(req interface{}, resp interface{}) error {
+	if resp == nil {
+		return nil
+	}
+	if v := resp.(history.NoopResponse).Value; v > c.max {
+		return fmt.Errorf("value %d is larger than %d", v, c.max)
+	}
+	return nil
+}
+
+func (c *maxValueChecker) Name() string {
+	return "max_value"
+}
+
+func TestOnline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "online")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(window int) { OnlineWindow = window }(OnlineWindow)
+	OnlineWindow = 2
+
+	name := path.Join(dir, "history.log.1.gz")
+	header := history.Header{DB: "noop", Workload: "noop", Round: 1}
+	recorder, err := history.NewRecorder(name, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aborted := 0
+	online := NewOnline(name, header, []core.OnlineChecker{&maxValueChecker{}}, func() { aborted++ })
+	recorder.Observe(online)
+
+	recorder.RecordState(10)
+	for i := 1; i <= 5; i++ {
+		recorder.RecordRequest(int64(i), "n1", 0, history.NoopRequest{})
+		recorder.RecordResponse(int64(i), "n1", 0, history.NoopResponse{Value: i})
+	}
+	// Unknown responses are not checked.
+	recorder.RecordRequest(6, "n1", 0, history.NoopRequest{})
+	recorder.RecordResponse(6, "n1", 0, nil)
+	recorder.RecordRequest(7, "n1", 0, history.NoopRequest{})
+	if online.Failed() || aborted != 0 {
+		t.Fatal("no violation is expected")
+	}
+
+	recorder.RecordRequest(8, "n1", 0, history.NoopRequest{})
+	recorder.RecordResponse(8, "n1", 0, history.NoopResponse{Value: 11})
+	recorder.RecordRequest(9, "n1", 0, history.NoopRequest{})
+	recorder.RecordResponse(9, "n1", 0, history.NoopResponse{Value: 12})
+	recorder.Close()
+	if !online.Failed() || aborted != 1 {
+		t.Fatalf("expect to abort once, but aborted %d", aborted)
+	}
+
+	results := online.Finish()
+	if len(results) != 1 || results[0].Outcome != Invalid || !results[0].Online {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	window := path.Join(dir, "history.log.1.window.gz")
+	if results[0].Window != window {
+		t.Fatalf("expect window %s, got %s", window, results[0].Window)
+	}
+
+	h, err := history.ReadHeader(window)
+	if err != nil || h.Workload != "noop" || h.Round != 1 {
+		t.Fatalf("unexpected header %+v, %v", h, err)
+	}
+	ops, state, err := history.ReadHistory(window, history.NoopParser{State: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The window keeps the recent operations till the violation, without
+	// the initial state, which is not the state at the start of the window.
+	if len(ops) != 3 || ops[0].Proc != 7 || ops[len(ops)-1].Proc != 8 || state != nil {
+		t.Fatalf("unexpected window %+v, state %v", ops, state)
+	}
+	if _, err = history.CompleteOperations(ops, history.NoopParser{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,64 @@
+package verify
+
+import (
+	"fmt"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+// NewSuit creates a suit from the registered model, parser and checker.
+// An empty model name means the checker needs no model.
+func NewSuit(model string, parser string, checker string) (Suit, error) {
+	var s Suit
+	if len(model) != 0 {
+		if s.Model = core.NewModel(model); s.Model == nil {
+			return s, fmt.Errorf("model %s is not registered", model)
+		}
+	}
+	if s.Parser = history.GetParser(parser); s.Parser == nil {
+		return s, fmt.Errorf("parser %s is not registered", parser)
+	}
+	if s.Checker = core.NewChecker(checker); s.Checker == nil {
+		return s, fmt.Errorf("checker %s is not registered", checker)
+	}
+	s.Codec = history.GetEDNCodec(parser)
+	return s, nil
+}
+
+// WorkloadSuits creates a suit for every checker with the model and the
+// parser of the workload. The default checkers of the workload are used if
+// checkers is empty.
+func WorkloadSuits(w core.Workload, checkers []string) ([]Suit, error) {
+	if len(checkers) == 0 {
+		checkers = w.Checkers
+	}
+
+	suits := make([]Suit, 0, len(checkers))
+	for _, checker := range checkers {
+		s, err := NewSuit(w.Model, w.Parser, checker)
+		if err != nil {
+			return nil, fmt.Errorf("workload %s of db %s: %v", w.Name, w.DB, err)
+		}
+		suits = append(suits, s)
+	}
+	return suits, nil
+}
+
+// HistorySuits creates the suits for the history file with the workload
+// recorded in its header, see WorkloadSuits.
+func HistorySuits(historyFile string, checkers []string) ([]Suit, error) {
+	h, err := history.ReadHeader(historyFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(h.Workload) == 0 {
+		return nil, fmt.Errorf("history %s has no workload in its header", historyFile)
+	}
+
+	w, ok := core.GetWorkload(h.DB, h.Workload)
+	if !ok {
+		return nil, fmt.Errorf("workload %s of db %s in history %s is not registered", h.Workload, h.DB, historyFile)
+	}
+	return WorkloadSuits(w, checkers)
+}
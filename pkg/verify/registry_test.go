@@ -0,0 +1,78 @@
+package verify
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+func init() {
+	core.RegisterWorkload(core.Workload{
+		DB:               "noop",
+		Name:             "noop",
+		NewClientCreator: func() core.ClientCreator { return core.NoopClientCreator{} },
+		Model:            "noop",
+		Parser:           "noop",
+		Checkers:         []string{"noop"},
+	})
+}
+
+func TestWorkloadSuits(t *testing.T) {
+	w, ok := core.GetWorkload("noop", "noop")
+	if !ok {
+		t.Fatal("workload must be registered")
+	}
+
+	suits, err := WorkloadSuits(w, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suits) != 1 || suits[0].Model == nil || suits[0].Parser == nil || suits[0].Checker == nil {
+		t.Fatalf("unexpected suits %+v", suits)
+	}
+
+	if _, err = WorkloadSuits(w, []string{"noop", "missing"}); err == nil {
+		t.Fatal("unregistered checker must fail")
+	}
+
+	w.Model = ""
+	if suits, err = WorkloadSuits(w, []string{"noop", "noop"}); err != nil || len(suits) != 2 || suits[0].Model != nil {
+		t.Fatalf("unexpected suits %+v, err %v", suits, err)
+	}
+}
+
+func TestHistorySuits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := path.Join(dir, "history.log")
+	r, err := history.NewRecorder(name, history.Header{DB: "noop", Workload: "noop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	suits, err := HistorySuits(name, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suits) != 1 || suits[0].Checker.Name() != (core.NoopChecker{}).Name() {
+		t.Fatalf("unexpected suits %+v", suits)
+	}
+
+	r, err = history.NewRecorder(name, history.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if _, err = HistorySuits(name, nil); err == nil {
+		t.Fatal("history without workload must fail")
+	}
+}
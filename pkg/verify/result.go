@@ -0,0 +1,102 @@
+package verify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+)
+
+// Outcome is the outcome of verifying a history.
+type Outcome string
+
+// Outcomes of verifying a history.
+const (
+	// Valid means the checker accepts the history.
+	Valid Outcome = "valid"
+	// Invalid means the checker finds the history is wrong.
+	Invalid Outcome = "invalid"
+	// Unknown means the history can't be checked, e.g, the history file
+	// is broken, the checker fails or times out.
+	Unknown Outcome = "unknown"
+)
+
+// Result is the result of verifying a history file.
+type Result struct {
+	Outcome     Outcome       `json:"outcome"`
+	Checker     string        `json:"checker"`
+	Model       string        `json:"model,omitempty"`
+	HistoryFile string        `json:"history"`
+	Err         string        `json:"error,omitempty"`
+	Duration    time.Duration `json:"duration"`
+	// TimedOut means the checker can't finish before the timeout, so the
+	// outcome is Unknown.
+	TimedOut bool `json:"timed_out,omitempty"`
+	// Truncated means the history ends with a partial record, which is
+	// skipped, e.g, the controller is killed.
+	Truncated bool `json:"truncated,omitempty"`
+	// Partitions are the keys which are not linearizable, if the history
+	// is checked by key.
+	Partitions []string `json:"partitions,omitempty"`
+	// Timeline is the HTML timeline of an invalid history, if written.
+	Timeline string `json:"timeline,omitempty"`
+	// Shrunk is the smallest history found which still fails the checker.
+	Shrunk string `json:"shrunk,omitempty"`
+	// Online means the checker runs while the test runs, Window is the
+	// excerpt of the history before its violation, for diagnosis only.
+	Online bool   `json:"online,omitempty"`
+	Window string `json:"window,omitempty"`
+}
+
+// Summary summarizes the results of all the verified histories.
+type Summary struct {
+	Outcome Outcome  `json:"outcome"`
+	Results []Result `json:"results"`
+}
+
+// Summarize summarizes the results. The outcome is Invalid if any result is
+// Invalid, otherwise Unknown if any result is Unknown or there is no result,
+// otherwise Valid.
+func Summarize(results []Result) Summary {
+	s := Summary{Outcome: Valid, Results: results}
+	if len(results) == 0 {
+		s.Outcome = Unknown
+	}
+	for _, r := range results {
+		switch r.Outcome {
+		case Invalid:
+			s.Outcome = Invalid
+		case Unknown:
+			if s.Outcome == Valid {
+				s.Outcome = Unknown
+			}
+		}
+	}
+	if s.Results == nil {
+		s.Results = []Result{}
+	}
+	return s
+}
+
+// WriteSummary writes the summary to the file in JSON.
+func WriteSummary(name string, s Summary) error {
+	os.MkdirAll(path.Dir(name), 0755)
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, append(data, '\n'), 0644)
+}
+
+// ReadSummary reads the summary file written by WriteSummary.
+func ReadSummary(name string) (Summary, error) {
+	var s Summary
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
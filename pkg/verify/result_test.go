@@ -0,0 +1,264 @@
+package verify
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/check/porcupine"
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+	"github.com/pingcap/chaos/pkg/model"
+)
+
+func TestSummarize(t *testing.T) {
+	cases := []struct {
+		outcomes []Outcome
+		expected Outcome
+	}{
+		{nil, Unknown},
+		{[]Outcome{Valid, Valid}, Valid},
+		{[]Outcome{Valid, Unknown}, Unknown},
+		{[]Outcome{Unknown, Invalid, Valid}, Invalid},
+	}
+
+	for i, cs := range cases {
+		var results []Result
+		for _, o := range cs.outcomes {
+			results = append(results, Result{Outcome: o})
+		}
+		if s := Summarize(results); s.Outcome != cs.expected {
+			t.Fatalf("case %d: expected %s, got %s", i, cs.expected, s.Outcome)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := Suit{
+		Model:   &core.NoopModel{},
+		Checker: core.NoopChecker{},
+		Parser:  history.NoopParser{},
+	}
+
+	r := s.Verify(path.Join(dir, "missing.log"))
+	if r.Outcome != Unknown || r.Err == "" {
+		t.Fatalf("missing history must be unknown, got %+v", r)
+	}
+
+	name := path.Join(dir, "history.log")
+	recorder, err := history.NewRecorder(name, history.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.RecordRequest(1, "n1", 0, history.NoopRequest{})
+	recorder.RecordResponse(1, "n1", 0, history.NoopResponse{})
+	recorder.Close()
+
+	r = s.Verify(name)
+	if r.Outcome != Valid || r.Checker != s.Checker.Name() || r.Model != s.Model.Name() || r.Truncated {
+		t.Fatalf("unexpected result %+v", r)
+	}
+
+	// The history of a killed controller ends with a partial record.
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"action":"call","proc":2,"da`)
+	f.Close()
+	r = s.Verify(name)
+	if r.Outcome != Valid || !r.Truncated {
+		t.Fatalf("truncated history must be verified, got %+v", r)
+	}
+}
+
+// blockingChecker checks until the context is done.
+type blockingChecker struct{}
+
+func (blockingChecker) Check(ctx context.Context, _ core.Model, _ []core.Operation) (bool, error) {
+	<-ctx.Done()
+	return false, ctx.Err()
+}
+
+func (blockingChecker) Name() string {
+	return "blocking"
+}
+
+func TestVerifyTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := path.Join(dir, "history.log")
+	recorder, err := history.NewRecorder(name, history.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.RecordRequest(1, "n1", 0, history.NoopRequest{})
+	recorder.RecordResponse(1, "n1", 0, history.NoopResponse{})
+	recorder.Close()
+
+	s := Suit{
+		Checker: blockingChecker{},
+		Parser:  history.NoopParser{},
+		Timeout: 10 * time.Millisecond,
+	}
+	r := s.Verify(name)
+	if r.Outcome != Unknown || !r.TimedOut || r.Err == "" || len(r.Shrunk) != 0 {
+		t.Fatalf("timed out history must be unknown, got %+v", r)
+	}
+	if o := Summarize([]Result{{Outcome: Valid}, r}).Outcome; o != Unknown {
+		t.Fatalf("expected unknown summary, got %s", o)
+	}
+}
+
+func TestVerifyInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := Suit{
+		Model:   model.RegisterModel(),
+		Checker: porcupine.Checker{},
+		Parser:  model.RegisterParser(),
+	}
+
+	name := path.Join(dir, "history.log")
+	recorder, err := history.NewRecorder(name, history.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.RecordState(0)
+	recorder.RecordRequest(1, "n1", 0, model.RegisterRequest{Op: model.RegisterWrite, Value: 1})
+	recorder.RecordResponse(1, "n1", 0, model.RegisterResponse{})
+	// Read the old value after the write returns.
+	recorder.RecordRequest(2, "n2", 1, model.RegisterRequest{Op: model.RegisterRead})
+	recorder.RecordResponse(2, "n2", 1, model.RegisterResponse{Value: 0})
+	recorder.Close()
+
+	ShrinkTimeout = time.Minute
+	defer func() { ShrinkTimeout = 0 }()
+	r := s.Verify(name)
+	if r.Outcome != Invalid || r.Timeline != name+timelineExt {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	if _, err = os.Stat(r.Timeline); err != nil {
+		t.Fatalf("timeline must be written, %v", err)
+	}
+
+	// The shrunk history has only the write and the stale read.
+	ops, _, err := history.ReadHistory(r.Shrunk, s.Parser)
+	if err != nil || len(ops) != 4 {
+		t.Fatalf("unexpected shrunk history %v, err %v", ops, err)
+	}
+}
+
+func TestVerifyStream(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := Suit{
+		Model:   model.CounterModel(),
+		Checker: model.CounterChecker(),
+		Parser:  model.CounterParser(),
+	}
+
+	name := path.Join(dir, "history.log")
+	recorder, err := history.NewRecorder(name, history.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.RecordState(5)
+	recorder.RecordRequest(1, "n1", 0, model.CounterRequest{Op: model.CounterAdd, Value: 2})
+	recorder.RecordResponse(1, "n1", 0, model.CounterResponse{Unknown: true})
+	recorder.RecordRequest(2, "n2", 1, model.CounterRequest{Op: model.CounterRead})
+	recorder.RecordResponse(2, "n2", 1, model.CounterResponse{Value: 7})
+	// The add is pending at the end of the history.
+	recorder.RecordRequest(3, "n2", 1, model.CounterRequest{Op: model.CounterAdd, Value: 1})
+	recorder.Close()
+
+	if r := s.Verify(name); r.Outcome != Valid {
+		t.Fatalf("unexpected result %+v", r)
+	}
+
+	// The read is more than the initial state and all the adds.
+	recorder, err = history.NewRecorder(name, history.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.RecordState(5)
+	recorder.RecordRequest(1, "n1", 0, model.CounterRequest{Op: model.CounterRead})
+	recorder.RecordResponse(1, "n1", 0, model.CounterResponse{Value: 6})
+	recorder.Close()
+
+	if r := s.Verify(name); r.Outcome != Invalid {
+		t.Fatalf("unexpected result %+v", r)
+	}
+
+	// A broken history is unknown.
+	recorder, err = history.NewRecorder(name, history.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.RecordResponse(1, "n1", 0, model.CounterResponse{Value: 6})
+	recorder.Close()
+
+	if r := s.Verify(name); r.Outcome != Unknown || r.Err == "" {
+		t.Fatalf("unexpected result %+v", r)
+	}
+}
+
+func TestVerifyPartitions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := Suit{
+		Model:   model.MultiRegisterModel(),
+		Checker: porcupine.Checker{},
+		Parser:  model.MultiRegisterParser(),
+	}
+
+	name := path.Join(dir, "history.log")
+	recorder, err := history.NewRecorder(name, history.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.RecordState(map[string]int{})
+	for i, key := range []string{"a", "b", "c"} {
+		proc := int64(i * 2)
+		recorder.RecordRequest(proc, "n1", 0, model.MultiRegisterRequest{Op: model.RegisterWrite, Key: key, Value: 1})
+		recorder.RecordResponse(proc, "n1", 0, model.MultiRegisterResponse{})
+		// Only b reads the old value after the write returns.
+		value := 1
+		if key == "b" {
+			value = 0
+		}
+		recorder.RecordRequest(proc+1, "n2", 1, model.MultiRegisterRequest{Op: model.RegisterRead, Key: key})
+		recorder.RecordResponse(proc+1, "n2", 1, model.MultiRegisterResponse{Value: value})
+	}
+	recorder.Close()
+
+	r := s.Verify(name)
+	if r.Outcome != Invalid || len(r.Partitions) != 1 || r.Partitions[0] != "b" || len(r.Timeline) == 0 {
+		t.Fatalf("unexpected result %+v", r)
+	}
+}
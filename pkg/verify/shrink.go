@@ -0,0 +1,69 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pingcap/chaos/pkg/core"
+	"github.com/pingcap/chaos/pkg/history"
+)
+
+// ShrinkTimeout limits how long an invalid history is shrunk by Verify,
+// 0 disables shrinking, which is the default, so a run never shrinks its
+// histories between rounds. Set it to shrink when verifying after a run.
+var ShrinkTimeout time.Duration
+
+// ShrunkName returns the name of the shrunk history next to the history,
+// history.log.1.gz is shrunk to history.log.1.shrunk.gz.
+func ShrunkName(historyFile string) string {
+	ext := history.CompressionOf(historyFile).Ext()
+	return strings.TrimSuffix(historyFile, ext) + ".shrunk" + ext
+}
+
+// Shrink writes the smallest history found which still fails the checker
+// to out, and returns the count of its operations. Only the read-only
+// operations of the model are removed, so the shrunk history fails only if
+// the history does. It stops and writes the smallest one found when the
+// context is done.
+func (s Suit) Shrink(ctx context.Context, historyFile string, out string) (int, error) {
+	m, ok := s.Model.(core.ReadOnlyModel)
+	if !ok {
+		return 0, fmt.Errorf("model of %s doesn't tell the read-only operations", s.Checker.Name())
+	}
+	fails := func(ops []core.Operation, state interface{}) bool {
+		return s.fails(ctx, ops, state)
+	}
+	return history.Shrink(ctx, historyFile, out, s.Parser, m.ReadOnly, fails)
+}
+
+// fails tells whether the operations are not valid, a check which is
+// stopped by the context is not a failure.
+func (s Suit) fails(ctx context.Context, ops []core.Operation, state interface{}) bool {
+	ops, err := history.CompleteOperations(ops, s.Parser)
+	if err != nil {
+		return false
+	}
+	if s.Model != nil {
+		s.Model.Prepare(state)
+	}
+	ok, err := s.Checker.Check(ctx, s.Model, ops)
+	return err == nil && !ok
+}
+
+// shrink shrinks the invalid history for triage.
+func (s Suit) shrink(historyFile string, r *Result) {
+	ctx, cancel := context.WithTimeout(context.Background(), ShrinkTimeout)
+	defer cancel()
+
+	name := ShrunkName(historyFile)
+	n, err := s.Shrink(ctx, historyFile, name)
+	if err != nil {
+		log.Printf("shrink history %s failed %v", historyFile, err)
+		return
+	}
+	r.Shrunk = name
+	log.Printf("history %s is shrunk to %d operations in %s", historyFile, n, name)
+}
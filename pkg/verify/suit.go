@@ -1,47 +1,247 @@
 package verify
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"time"
 
+	"github.com/pingcap/chaos/pkg/check/porcupine"
 	"github.com/pingcap/chaos/pkg/core"
 	"github.com/pingcap/chaos/pkg/history"
+	"github.com/pingcap/chaos/pkg/timeline"
 )
 
+// timelineExt is the extension of the timeline written next to a history
+// which is not linearizable.
+const timelineExt = ".timeline.html"
+
+// CheckTimeout limits how long a checker checks a history by default, the
+// history is Unknown if the checker can't finish in time. 0 means no limit.
+var CheckTimeout = time.Hour
+
 // Suit collects a checker, a model and a parser.
 type Suit struct {
 	Checker core.Checker
 	Model   core.Model
 	Parser  history.RecordParser
+	// Codec is optional, it tells the failed operations in the timeline.
+	Codec history.EDNCodec
+	// Timeout limits how long the checker runs, CheckTimeout is used if
+	// it is 0, and a negative one means no limit.
+	Timeout time.Duration
 }
 
-// Verify creates the verifier from model name and verfies the history file.
-func (s Suit) Verify(historyFile string) {
+// Verify verfies the history file with the checker and the model.
+// It never exits the process, a history which can't be checked is reported
+// as Unknown with the error in the result.
+func (s Suit) Verify(historyFile string) Result {
+	start := time.Now()
+	r := Result{
+		Checker:     s.Checker.Name(),
+		HistoryFile: historyFile,
+	}
 	if s.Model == nil {
 		log.Printf("begin to check %s", s.Checker.Name())
 	} else {
+		r.Model = s.Model.Name()
 		log.Printf("begin to check %s with %s", s.Model.Name(), s.Checker.Name())
 	}
-	ops, state, err := history.ReadHistory(historyFile, s.Parser)
+
+	s.verify(historyFile, &r)
+	r.Duration = time.Since(start)
+	if r.Outcome == Invalid && ShrinkTimeout > 0 {
+		s.shrink(historyFile, &r)
+	}
+
+	switch r.Outcome {
+	case Valid:
+		log.Printf("history %s is valid", historyFile)
+	case Invalid:
+		log.Printf("history %s is not valid", historyFile)
+	default:
+		if r.TimedOut {
+			log.Printf("history %s is unknown, %s", historyFile, r.Err)
+			break
+		}
+		log.Printf("verify history %s failed %v", historyFile, r.Err)
+	}
+	return r
+}
+
+func (s Suit) verify(historyFile string, r *Result) {
+	r.Outcome = Unknown
+	if sc, ok := s.Checker.(core.StreamChecker); ok {
+		s.verifyStream(historyFile, sc, r)
+		return
+	}
+	ops, state, err := s.readHistory(historyFile, r)
 	if err != nil {
-		log.Fatalf("verify failed: %v", err)
+		r.Err = err.Error()
+		return
 	}
 
 	ops, err = history.CompleteOperations(ops, s.Parser)
 	if err != nil {
-		log.Fatalf("verify failed: %v", err)
+		r.Err = err.Error()
+		return
 	}
 
 	if s.Model != nil {
 		s.Model.Prepare(state)
 	}
-	ok, err := s.Checker.Check(s.Model, ops)
+	ctx, cancel := s.checkContext()
+	defer cancel()
+	ok, invalid, err := s.check(ctx, ops)
+	if err != nil {
+		s.setError(r, err)
+		return
+	}
+
+	if ok {
+		r.Outcome = Valid
+		return
+	}
+	r.Outcome = Invalid
+	for _, p := range invalid {
+		r.Partitions = append(r.Partitions, p.Key)
+	}
+	// The timeline of a partitioned history shows the first invalid key.
+	if len(invalid) != 0 {
+		ops = invalid[0].Ops
+	}
+
+	// Show where the history is not linearizable.
+	if _, ok := s.Checker.(porcupine.Checker); ok && s.Model != nil {
+		name := historyFile + timelineExt
+		// The explanation shares the check timeout, so it can't stall the
+		// run after the checker answers.
+		if err = s.writeTimeline(ctx, historyFile, ops, name); err != nil {
+			log.Printf("write timeline of %s failed %v", historyFile, err)
+			return
+		}
+		r.Timeline = name
+		log.Printf("timeline of history %s is written to %s", historyFile, name)
+	}
+}
+
+// check checks the operations with the checker, the history of a partition
+// model is checked by key if the checker is porcupine, and the invalid
+// partitions are returned.
+func (s Suit) check(ctx context.Context, ops []core.Operation) (bool, []porcupine.Partition, error) {
+	if _, ok := s.Checker.(porcupine.Checker); ok {
+		if pm, ok := s.Model.(core.PartitionModel); ok {
+			invalid, err := porcupine.CheckPartitions(ctx, pm, ops)
+			return len(invalid) == 0 && err == nil, invalid, err
+		}
+	}
+	ok, err := s.Checker.Check(ctx, s.Model, ops)
+	return ok, nil, err
+}
+
+// verifyStream checks the history with the stream checker while the history
+// is read, so the history is never held in memory.
+func (s Suit) verifyStream(historyFile string, sc core.StreamChecker, r *Result) {
+	it, err := history.NewIterator(historyFile, s.Parser)
 	if err != nil {
-		log.Fatalf("verify history failed %v", err)
+		r.Err = err.Error()
+		return
+	}
+	defer it.Close()
+
+	next := history.CompleteIterator(it)
+	// The initial state is dumped before the first operation, so the model
+	// is prepared after the first operation is read.
+	first, hasFirst := next()
+	if err = it.Err(); err != nil {
+		r.Err = err.Error()
+		return
+	}
+	if s.Model != nil {
+		s.Model.Prepare(it.State())
 	}
 
-	if !ok {
-		log.Fatalf("history %s is not valid", historyFile)
+	ctx, cancel := s.checkContext()
+	defer cancel()
+	ok, err := sc.CheckStream(ctx, s.Model, func() (core.Operation, bool) {
+		if hasFirst {
+			hasFirst = false
+			return first, true
+		}
+		return next()
+	})
+	if err == nil {
+		err = it.Err()
+	}
+	if err != nil {
+		s.setError(r, err)
+		return
+	}
+	if it.Truncated() {
+		r.Truncated = true
+		log.Printf("history %s is truncated, the partial record at line %d is skipped", historyFile, it.Line())
+	}
+
+	if ok {
+		r.Outcome = Valid
 	} else {
-		log.Printf("history %s is valid", historyFile)
+		r.Outcome = Invalid
+	}
+}
+
+// checkContext returns the context which limits how long the checker runs.
+func (s Suit) checkContext() (context.Context, context.CancelFunc) {
+	if timeout := s.timeout(); timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// setError marks the result unknown with the error of the checker.
+func (s Suit) setError(r *Result, err error) {
+	if err == context.DeadlineExceeded {
+		r.TimedOut = true
+		r.Err = fmt.Sprintf("check timed out after %s", s.timeout())
+		return
+	}
+	r.Err = err.Error()
+}
+
+func (s Suit) timeout() time.Duration {
+	if s.Timeout != 0 {
+		return s.Timeout
+	}
+	return CheckTimeout
+}
+
+// readHistory reads the history like history.ReadHistory, and marks the
+// result if the history is truncated, so the history of an aborted run can
+// still be checked.
+func (s Suit) readHistory(historyFile string, r *Result) ([]core.Operation, interface{}, error) {
+	it, err := history.NewIterator(historyFile, s.Parser)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer it.Close()
+
+	var ops []core.Operation
+	for it.Next() {
+		ops = append(ops, it.Operation())
+	}
+	if err = it.Err(); err != nil {
+		return nil, nil, err
+	}
+	if it.Truncated() {
+		r.Truncated = true
+		log.Printf("history %s is truncated, the partial record at line %d is skipped", historyFile, it.Line())
+	}
+	return ops, it.State(), nil
+}
+
+func (s Suit) writeTimeline(ctx context.Context, historyFile string, ops []core.Operation, name string) error {
+	t, err := timeline.Build(ctx, historyFile, ops, s.Codec, s.Model)
+	if err != nil {
+		return err
 	}
+	return t.WriteFile(name)
 }
@@ -3,18 +3,18 @@
 for bin in $@; do
     case $bin in
     'tidb' )
-        suit=chaos-tidb
+        db=tidb
         cases=( bank multi_bank )
         nemeses=( random_kill random_drop )
         ;;
     'rawkv' )
-        suit=chaos-rawkv
+        db=rawkv
         cases=( register )
         # TODO: add random_drop, chaos can not heal drop nemesis sometime.
         nemeses=( random_kill  )
         ;;
     'txnkv' )
-        suit=chaos-txnkv
+        db=txnkv
         cases=( register )
         nemeses=( random_kill  )
         ;;
@@ -44,12 +44,13 @@ for i in "${cases[@]}"
 do
     for j in "${nemeses[@]}"
     do
-        history_log=./var/history_"$suit"_"$i"_"$j".log
+        output_dir=./var/"$db"_"$i"_"$j"
         echo "run $i with nemeses $j"
-        ./bin/$suit \
+        ./bin/chaos run \
+            --db $db \
             --case $i \
             --nemesis $j \
-            --history $history_log \
+            --output-dir $output_dir \
             --request-count 200 \
             --round 10
     done